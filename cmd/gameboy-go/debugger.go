@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/briancain/gameboy-go/internal/core"
+	"github.com/briancain/gameboy-go/internal/debugger"
+)
+
+// runDebugger runs the emulator under the interactive terminal debugger
+func runDebugger(gb *core.GameBoyCore) error {
+	dbg := debugger.New(gb, os.Stdin, os.Stdout)
+	dbg.SetSymbols(gb.Symbols)
+
+	// Ctrl-C stops a running command instead of exiting
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+	go func() {
+		for range sigChan {
+			dbg.Interrupt()
+		}
+	}()
+
+	err := dbg.Run()
+	gb.Exit()
+	return err
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/briancain/gameboy-go/internal/cdl"
+	"github.com/briancain/gameboy-go/internal/disasm"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// runDisasm implements the disasm subcommand and returns the exit code
+func runDisasm(args []string) int {
+	fs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	bank := fs.Int("bank", -1, "Only disassemble this ROM bank, or the bank mapped at 4000-7FFF for a start address (default: all banks, or bank 1)")
+	count := fs.Int("count", 0, "Instructions to disassemble from the start address (default: to the end of the bank)")
+	symPath := fs.String("sym", "", "A symbol file with labels for the listing (default: the .sym file next to the ROM, if any)")
+	cdlPath := fs.String("cdl", "", "A code/data log from -cdl, bytes it only saw used as data are listed as DB (default: the .cdl file next to the ROM, if any)")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s disasm [flags] game.gb [addr|bank:addr|label]\n\n", os.Args[0])
+		fmt.Fprintln(out, "Disassembles a ROM, every bank by default. Addresses are hexadecimal.")
+		fmt.Fprintln(out)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 1
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %v\n", err)
+		return 1
+	}
+	var table *symbols.Table
+	if *symPath != "" {
+		table, err = symbols.Load(*symPath)
+	} else {
+		table, err = symbols.LoadForROM(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %v\n", err)
+		return 1
+	}
+	d := disasm.New()
+	d.SetSymbols(table)
+
+	codeData, err := loadCodeDataLog(*cdlPath, fs.Arg(0), len(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %v\n", err)
+		return 1
+	}
+
+	rom := disasm.NewBankedROM(data, 1)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if fs.NArg() == 2 {
+		start, startBank, err := parseDisasmAddress(fs.Arg(1), table)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "disasm: %v\n", err)
+			return 1
+		}
+		if startBank < 0 {
+			startBank = *bank
+		}
+		if startBank >= 0 {
+			rom.SetBank(startBank)
+		}
+		disasmRange(out, d, rom, codeData, start, *count)
+		return 0
+	}
+
+	for b := 0; b < rom.Banks(); b++ {
+		if *bank >= 0 && b != *bank {
+			continue
+		}
+		start := uint16(disasm.ROM_BANK_SIZE)
+		if b == 0 {
+			start = 0
+		} else {
+			rom.SetBank(b)
+		}
+		fmt.Fprintf(out, "; Bank %02X\n", b)
+		disasmRange(out, d, rom, codeData, start, 0)
+	}
+	return 0
+}
+
+// loadCodeDataLog loads the code/data log at path, or the one next to the
+// ROM if path is empty. It returns nil if there is no log next to the ROM.
+func loadCodeDataLog(path, romPath string, size int) (*cdl.Log, error) {
+	if path == "" {
+		path = cdl.PathForROM(romPath)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return cdl.Load(path, size)
+}
+
+// disasmRange writes count instructions from start, or every instruction up
+// to the end of the bank at start when count is 0. Bytes codeData only saw
+// used as data are written as DB directives.
+func disasmRange(out *bufio.Writer, d *disasm.Disassembler, rom *disasm.BankedROM, codeData *cdl.Log, start uint16, count int) {
+	end := (int(start)/disasm.ROM_BANK_SIZE + 1) * disasm.ROM_BANK_SIZE
+
+	addr := int(start)
+	for i := 0; ; i++ {
+		if count == 0 && addr >= end || count > 0 && i == count || addr > 0xFFFF {
+			return
+		}
+
+		inst := d.Decode(rom, uint16(addr))
+		if n := dataLength(d, rom, codeData, addr, end); n > 0 {
+			inst = dataDirective(rom, inst, n)
+		}
+		if inst.Label != "" {
+			fmt.Fprintf(out, "%s:\n", inst.Label)
+		}
+
+		var hex strings.Builder
+		for _, b := range inst.Bytes {
+			fmt.Fprintf(&hex, "%02X ", b)
+		}
+		location := fmt.Sprintf("%04X", inst.Addr)
+		if b := rom.BankAt(inst.Addr); b >= 0 {
+			location = fmt.Sprintf("%02X:%04X", b, inst.Addr)
+		}
+		fmt.Fprintf(out, "%s  %-9s %s\n", location, hex.String(), inst.Text())
+
+		addr += inst.Len()
+	}
+}
+
+// DATA_PER_LINE is the most bytes listed by one DB directive, as many as
+// the longest instruction so the columns line up
+const DATA_PER_LINE = 3
+
+// dataLength returns how many bytes from addr, up to DATA_PER_LINE, codeData
+// only saw used as data. A run stops at end and at the next label.
+func dataLength(d *disasm.Disassembler, rom *disasm.BankedROM, codeData *cdl.Log, addr, end int) int {
+	n := 0
+	for ; n < DATA_PER_LINE && addr+n < end; n++ {
+		flags := codeData.Flags(cdl.Offset(uint16(addr+n), rom.Bank()))
+		if flags == 0 || flags&(cdl.CODE|cdl.OPERAND) != 0 {
+			break
+		}
+		if n > 0 && d.Decode(rom, uint16(addr+n)).Label != "" {
+			break
+		}
+	}
+	return n
+}
+
+// dataDirective replaces inst with a DB directive for n bytes, keeping its
+// label
+func dataDirective(rom *disasm.BankedROM, inst disasm.Instruction, n int) disasm.Instruction {
+	data := disasm.Instruction{Addr: inst.Addr, Mnemonic: "DB", Label: inst.Label}
+	for i := 0; i < n; i++ {
+		b := rom.ReadMemory(inst.Addr + uint16(i))
+		data.Bytes = append(data.Bytes, b)
+		data.Operands = append(data.Operands, fmt.Sprintf("$%02X", b))
+	}
+	return data
+}
+
+// parseDisasmAddress parses a label, addr or bank:addr, returning -1 for
+// the bank when there is none
+func parseDisasmAddress(s string, table *symbols.Table) (uint16, int, error) {
+	if sym, ok := table.Resolve(s); ok {
+		return sym.Addr, sym.ROMBank(), nil
+	}
+
+	bank := -1
+	if bankText, addrText, ok := strings.Cut(s, ":"); ok {
+		b, err := strconv.ParseUint(strings.TrimPrefix(bankText, "$"), 16, 16)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid bank %q", bankText)
+		}
+		bank = int(b)
+		s = addrText
+	}
+
+	addr, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0x"), "$"), 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(addr), bank, nil
+}
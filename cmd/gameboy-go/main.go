@@ -1,14 +1,21 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/briancain/gameboy-go/internal/cdl"
 	"github.com/briancain/gameboy-go/internal/core"
+	"github.com/briancain/gameboy-go/internal/dap"
 	"github.com/briancain/gameboy-go/internal/display"
+	"github.com/briancain/gameboy-go/internal/gdbstub"
+	"github.com/briancain/gameboy-go/internal/link"
+	"github.com/briancain/gameboy-go/internal/printer"
 	"github.com/briancain/gameboy-go/version"
 )
 
@@ -19,6 +26,25 @@ var (
 	Scale          int
 	Headless       bool
 	BatterySaveDir string
+	LoadStatePath  string
+	BootROMPath    string
+	RewindInterval int
+	RewindSeconds  int
+	SampleRate     int
+	AudioBufferMs  int
+	RecordAudio    string
+	LinkListen     string
+	LinkConnect    string
+	PrinterDir     string
+	TraceLog       string
+	TraceStubLY    bool
+	TraceLabels    bool
+	ProfilePath    string
+	CodeDataLog    bool
+	CrashDir       string
+	DebuggerMode   bool
+	GDBAddr        string
+	DAPAddr        string
 )
 
 func init() {
@@ -33,6 +59,25 @@ func init() {
 		currentDir = "."
 	}
 	flag.StringVar(&BatterySaveDir, "battery-save-dir", currentDir, "Directory to store battery-backed save files from cartridges (e.g., game progress)")
+	flag.StringVar(&LoadStatePath, "load-state", "", "A path to a save state file to restore after loading the ROM")
+	flag.StringVar(&BootROMPath, "boot-rom", "", "A path to a DMG or CGB boot ROM to run before the game, instead of starting in the state it leaves")
+	flag.IntVar(&RewindInterval, "rewind-interval", 2, "Frames between rewind captures")
+	flag.IntVar(&RewindSeconds, "rewind-seconds", 10, "Seconds of rewind history to keep (0 disables rewind)")
+	flag.IntVar(&SampleRate, "audio-sample-rate", 44100, "Audio output sample rate in Hz (0 disables audio)")
+	flag.IntVar(&AudioBufferMs, "audio-buffer", 50, "Audio output buffer size in milliseconds")
+	flag.StringVar(&RecordAudio, "record-audio", "", "A path to a WAV file to record the emulator audio to")
+	flag.StringVar(&LinkListen, "link-listen", "", "Wait for another emulator to connect a link cable on this address (e.g. :5000)")
+	flag.StringVar(&LinkConnect, "link-connect", "", "Connect a link cable to another emulator at this address (e.g. localhost:5000)")
+	flag.StringVar(&TraceLog, "trace-log", "", "A path to a file to log the CPU state before every instruction to, in the Gameboy Doctor format")
+	flag.BoolVar(&TraceStubLY, "trace-stub-ly", false, "Make LY always read 0x90 while tracing, as Gameboy Doctor expects")
+	flag.BoolVar(&TraceLabels, "trace-labels", false, "Name PC on each trace line by the labels in the ROM's .sym file, in a comment Gameboy Doctor does not accept")
+	flag.StringVar(&ProfilePath, "profile", "", "A path to write a pprof profile of the cycles spent in the ROM's code to on exit, for go tool pprof")
+	flag.BoolVar(&CodeDataLog, "cdl", false, "Log which ROM bytes run as code or are read as data to a .cdl file next to the ROM, adding to the file if it exists")
+	flag.StringVar(&CrashDir, "crash-dir", "", "Directory to write crash reports to when the game crashes (default: the battery save directory)")
+	flag.BoolVar(&DebuggerMode, "debugger", false, "Start in the interactive terminal debugger instead of running the game (no display)")
+	flag.StringVar(&GDBAddr, "gdb", "", "Wait for GDB to connect on this address (e.g. :2345) and let it control execution (no display)")
+	flag.StringVar(&DAPAddr, "dap", "", "Wait for a Debug Adapter Protocol client such as VS Code to connect on this address (e.g. :4711) (no display)")
+	flag.StringVar(&PrinterDir, "printer-dir", "", "Attach a Game Boy Printer to the link port and save its prints as PNG files in this directory")
 }
 
 func startEmulator() error {
@@ -45,12 +90,138 @@ func startEmulator() error {
 
 	// Set the save directory
 	gb.SetSaveDirectory(BatterySaveDir)
+	gb.SetCrashDirectory(CrashDir)
+	gb.SetBootROM(BootROMPath)
 
 	if err := gb.Init(CartridgePath); err != nil {
 		log.Print("[ERROR] Failed to initialize new core!\n", err)
 		return err
 	}
 
+	// Restore a save state if requested
+	if LoadStatePath != "" {
+		if err := gb.LoadStateFile(LoadStatePath); err != nil {
+			log.Print("[ERROR] Failed to load save state!\n", err)
+			return err
+		}
+	}
+
+	// Keep a rolling rewind history for the rewind hotkey
+	if !Headless && !debugging() && RewindSeconds > 0 && RewindInterval > 0 {
+		gb.EnableRewind(RewindInterval, RewindSeconds*gb.FPS/RewindInterval)
+	}
+
+	if SampleRate > 0 {
+		gb.SetSampleRate(SampleRate)
+	}
+
+	// Record the audio output if requested
+	if RecordAudio != "" {
+		if err := gb.StartAudioRecording(RecordAudio); err != nil {
+			log.Print("[ERROR] Failed to start audio recording!\n", err)
+			return err
+		}
+		defer func() {
+			if err := gb.StopAudioRecording(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+	}
+
+	// Trace instructions if requested
+	if TraceLog != "" {
+		if err := gb.StartTrace(TraceLog, TraceStubLY, TraceLabels); err != nil {
+			log.Print("[ERROR] Failed to start trace!\n", err)
+			return err
+		}
+		defer func() {
+			if err := gb.StopTrace(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+	}
+
+	// Profile the ROM's code if requested
+	if ProfilePath != "" {
+		if err := gb.StartProfile(ProfilePath); err != nil {
+			log.Print("[ERROR] Failed to start profile!\n", err)
+			return err
+		}
+		defer func() {
+			if err := gb.StopProfile(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+	}
+
+	// Log the ROM's code and data if requested
+	if CodeDataLog {
+		if err := gb.StartCodeDataLog(cdl.PathForROM(CartridgePath)); err != nil {
+			log.Print("[ERROR] Failed to start code/data log!\n", err)
+			return err
+		}
+		defer func() {
+			if err := gb.StopCodeDataLog(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+	}
+
+	// Connect the link cable if requested
+	if PrinterDir != "" && (LinkListen != "" || LinkConnect != "") {
+		err := errors.New("-printer-dir cannot be used with a link cable")
+		log.Print("[ERROR] Failed to attach printer!\n", err)
+		return err
+	}
+	if LinkListen != "" || LinkConnect != "" {
+		cable, err := connectLinkCable()
+		if err != nil {
+			log.Print("[ERROR] Failed to connect link cable!\n", err)
+			return err
+		}
+		defer cable.Close()
+		gb.Serial.SetPeer(cable)
+	}
+
+	// Attach the printer if requested
+	if PrinterDir != "" {
+		gbPrinter := printer.NewPrinter(PrinterDir)
+		defer func() {
+			if err := gbPrinter.Close(); err != nil {
+				log.Printf("[ERROR] Failed to save print: %v", err)
+			}
+		}()
+		gb.Serial.SetPeer(gbPrinter)
+	}
+
+	// Control execution from a debugger instead of running freely
+	modes := 0
+	for _, enabled := range []bool{DebuggerMode, GDBAddr != "", DAPAddr != ""} {
+		if enabled {
+			modes++
+		}
+	}
+	if modes > 1 {
+		err := errors.New("use only one of -debugger, -gdb and -dap")
+		log.Print("[ERROR] Failed to start debugger!\n", err)
+		return err
+	}
+	if DebuggerMode {
+		return runDebugger(gb)
+	}
+	if GDBAddr != "" {
+		err := gdbstub.NewServer(gb).ListenAndServe(GDBAddr)
+		gb.Exit()
+		return err
+	}
+	if DAPAddr != "" {
+		server := dap.NewServer(gb)
+		server.SetSymbols(gb.Symbols)
+		err := server.ListenAndServe(DAPAddr)
+		gb.Exit()
+		return err
+	}
+
 	// Check if running in headless mode
 	if Headless {
 		log.Println("Running in headless mode...")
@@ -77,11 +248,43 @@ func startEmulator() error {
 		// Create and run the ebiten display
 		log.Println("Starting visual display...")
 		ebitenDisplay := display.NewEbitenDisplay(gb, gb, Scale, DebugOutput)
+		if SampleRate > 0 {
+			bufferSize := time.Duration(AudioBufferMs) * time.Millisecond
+			if err := ebitenDisplay.EnableAudio(SampleRate, bufferSize); err != nil {
+				log.Printf("[ERROR] Failed to start audio output: %v", err)
+			}
+		}
 		return ebitenDisplay.Run()
 	}
 }
 
+// debugging reports whether a debugger controls execution
+func debugging() bool {
+	return DebuggerMode || GDBAddr != "" || DAPAddr != ""
+}
+
+// connectLinkCable connects to or waits for the other emulator
+func connectLinkCable() (*link.Cable, error) {
+	if LinkListen != "" && LinkConnect != "" {
+		return nil, errors.New("use only one of -link-listen and -link-connect")
+	}
+	if LinkListen != "" {
+		return link.Listen(LinkListen)
+	}
+	return link.Dial(LinkConnect)
+}
+
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "trace-diff":
+			os.Exit(runTraceDiff(os.Args[2:]))
+		case "disasm":
+			os.Exit(runDisasm(os.Args[2:]))
+		}
+	}
+
 	log.Print("Starting gameboy-go ... ")
 	versionInfo := version.Get()
 	log.Print("Version: ", versionInfo)
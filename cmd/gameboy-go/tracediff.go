@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/core"
+	"github.com/briancain/gameboy-go/internal/trace"
+)
+
+// Exit codes for trace-diff
+const (
+	traceDiffMatch     = 0
+	traceDiffDiverged  = 1
+	traceDiffErrorCode = 2
+)
+
+// runTraceDiff implements the trace-diff subcommand and returns the exit code
+func runTraceDiff(args []string) int {
+	fs := flag.NewFlagSet("trace-diff", flag.ExitOnError)
+	context := fs.Int("context", trace.DefaultContext, "Lines to show before and after the divergence")
+	romFile := fs.String("rom-file", "", "Run this ROM and compare its execution live instead of reading a trace file")
+	stubLY := fs.Bool("stub-ly", true, "Make LY always read 0x90 when running a ROM, as Gameboy Doctor expects")
+	maxFrames := fs.Int("max-frames", 60*60, "Frames to run a ROM for before giving up")
+	verbose := fs.Bool("v", false, "Show emulator log output when running a ROM")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s trace-diff [flags] trace.log reference.log\n", os.Args[0])
+		fmt.Fprintf(out, "       %s trace-diff [flags] -rom-file game.gb reference.log\n\n", os.Args[0])
+		fmt.Fprintln(out, "Compares a Gameboy Doctor trace against a reference and reports the first line that differs.")
+		fmt.Fprintln(out, "Exits 0 if the traces match, 1 if they diverge and 2 on errors.")
+		fmt.Fprintln(out)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var (
+		d     *trace.Divergence
+		lines int
+		err   error
+	)
+	switch {
+	case *romFile != "" && fs.NArg() == 1:
+		if !*verbose {
+			log.SetOutput(io.Discard)
+		}
+		d, lines, err = traceDiffROM(*romFile, fs.Arg(0), *context, *stubLY, *maxFrames)
+	case *romFile == "" && fs.NArg() == 2:
+		d, lines, err = traceDiffFiles(fs.Arg(0), fs.Arg(1), *context)
+	default:
+		fs.Usage()
+		return traceDiffErrorCode
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trace-diff: %v\n", err)
+		return traceDiffErrorCode
+	}
+	if d != nil {
+		fmt.Print(d)
+		return traceDiffDiverged
+	}
+
+	fmt.Printf("No divergence in %d lines\n", lines)
+	return traceDiffMatch
+}
+
+// traceDiffFiles compares two trace files
+func traceDiffFiles(actualPath, referencePath string, context int) (*trace.Divergence, int, error) {
+	actual, err := os.Open(actualPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer actual.Close()
+
+	reference, err := os.Open(referencePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reference.Close()
+
+	return trace.Diff(actual, reference, context)
+}
+
+// traceDiffROM runs a ROM headlessly and compares every instruction against
+// the reference trace until it diverges or the reference ends
+func traceDiffROM(romPath, referencePath string, context int, stubLY bool, maxFrames int) (*trace.Divergence, int, error) {
+	reference, err := os.Open(referencePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reference.Close()
+
+	// Keep battery saves from a previous run out of the comparison
+	saveDir, err := os.MkdirTemp("", "gameboy-go-trace-diff")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.RemoveAll(saveDir)
+
+	gb, err := core.NewGameBoyCore(false)
+	if err != nil {
+		return nil, 0, err
+	}
+	gb.SetSaveDirectory(saveDir)
+	if err := gb.Init(romPath); err != nil {
+		return nil, 0, err
+	}
+
+	live := trace.NewLiveDiff(reference, gb.Mmu, context)
+	live.SetLabels(gb.LabelAt)
+	gb.Cpu.AddObserver(live)
+	gb.Mmu.SetLYStub(stubLY)
+
+	for frame := 0; frame < maxFrames && !live.Done(); frame++ {
+		if err := gb.Step(); err != nil {
+			return nil, live.Lines(), err
+		}
+	}
+	if !live.Done() {
+		return nil, live.Lines(), fmt.Errorf("no divergence after %d frames (%d lines) and the reference has not ended", maxFrames, live.Lines())
+	}
+
+	d, err := live.Result()
+	return d, live.Lines(), err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/testrom"
+)
+
+// Exit codes
+const (
+	exitPassed  = 0
+	exitFailed  = 1
+	exitTimeout = 2
+	exitError   = 3
+)
+
+func main() {
+	timeout := flag.Duration("timeout", testrom.DefaultBlarggTimeout, "Emulated time to wait for a result")
+	verbose := flag.Bool("v", false, "Show emulator log output")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] rom.gb [rom.gb ...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Runs Blargg test ROMs headlessly and prints their serial output.")
+		fmt.Fprintln(os.Stderr, "Exits 0 if every ROM passed, 1 if any failed, 2 if any timed out and 3 on errors.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(exitError)
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	exitCode := exitPassed
+	for _, rom := range flag.Args() {
+		fmt.Printf("=== %s\n", rom)
+
+		result, err := testrom.RunBlargg(rom, *timeout)
+		if err != nil {
+			fmt.Printf("Error: %v\n\n", err)
+			exitCode = max(exitCode, exitError)
+			continue
+		}
+
+		fmt.Print(result.Output)
+		fmt.Printf("\n--- %s after %d frames\n\n", result.Status, result.Frames)
+
+		switch result.Status {
+		case testrom.StatusFailed:
+			exitCode = max(exitCode, exitFailed)
+		case testrom.StatusTimeout:
+			exitCode = max(exitCode, exitTimeout)
+		}
+	}
+
+	os.Exit(exitCode)
+}
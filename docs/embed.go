@@ -0,0 +1,9 @@
+// Package docs embeds reference data used by the emulator's tools
+package docs
+
+import _ "embed"
+
+// OpcodesJSON is the gbdev opcode table, see README.md
+//
+//go:embed Opcodes.json
+var OpcodesJSON []byte
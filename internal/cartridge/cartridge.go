@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // Cartridge types
@@ -232,12 +234,56 @@ func (c *Cartridge) GetMBC() MBC {
 	return c.mbc
 }
 
+// GetTitle returns the game title from the cartridge header
+func (c *Cartridge) GetTitle() string {
+	return c.title
+}
+
+// GetROMSize returns the size of the ROM in bytes
+func (c *Cartridge) GetROMSize() int {
+	return len(c.rom)
+}
+
+// GetHeaderChecksum returns the global checksum stored at 0x14E-0x14F
+func (c *Cartridge) GetHeaderChecksum() uint16 {
+	if len(c.rom) < 0x150 {
+		return 0
+	}
+	return uint16(c.rom[0x14E])<<8 | uint16(c.rom[0x14F])
+}
+
+// SaveState writes the banking registers and RAM of the active MBC
+func (c *Cartridge) SaveState(w *snapshot.StateWriter) {
+	w.Section("CART")
+	w.Uint8(c.cartType)
+	c.mbc.SaveState(w)
+}
+
+// LoadState restores the MBC state written by SaveState
+func (c *Cartridge) LoadState(r *snapshot.StateReader) {
+	r.Section("CART")
+	cartType := r.Uint8()
+	if r.Err() != nil {
+		return
+	}
+	if cartType != c.cartType {
+		r.Fail(fmt.Errorf("save state is for cartridge type %02X, loaded cartridge is %02X", cartType, c.cartType))
+		return
+	}
+	c.mbc.LoadState(r)
+}
+
 // A generic Memory Bank Controller interface.
 type MBC interface {
 	ReadByte(addr uint16) byte
 	WriteByte(addr uint16, value byte)
-	SaveBatteryRAM()  // Save battery-backed RAM to file (if supported)
-	IsRumbling() bool // Returns true if the cartridge has rumble and it's active
+	SaveBatteryRAM()     // Save battery-backed RAM to file (if supported)
+	IsRumbling() bool    // Returns true if the cartridge has rumble and it's active
+	CurrentROMBank() int // ROM bank mapped at 0x4000-0x7FFF
+
+	// Save states
+	SaveState(w *snapshot.StateWriter) // Write banking registers, RAM and RTC
+	LoadState(r *snapshot.StateReader) // Restore state written by SaveState
 }
 
 // ROM Only (no MBC) implementation
@@ -290,6 +336,21 @@ func (r *ROMOnly) IsRumbling() bool {
 	return false
 }
 
+// CurrentROMBank always returns 1, ROM-only cartridges have no banking
+func (r *ROMOnly) CurrentROMBank() int {
+	return 1
+}
+
+// SaveState writes the cartridge RAM (if any)
+func (r *ROMOnly) SaveState(w *snapshot.StateWriter) {
+	w.Bytes(r.ram)
+}
+
+// LoadState restores the cartridge RAM written by SaveState
+func (r *ROMOnly) LoadState(sr *snapshot.StateReader) {
+	sr.BytesInto(r.ram)
+}
+
 // https://gbdev.io/pandocs/The_Cartridge_Header.html#0147---cartridge-type
 var cartridgeTypeMap = map[byte]string{
 	byte(0x00): "ROM ONLY",
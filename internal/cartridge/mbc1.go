@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // MBC1 implementation
@@ -281,3 +283,29 @@ func (mbc *MBC1) SaveBatteryRAM() {
 func (mbc *MBC1) IsRumbling() bool {
 	return false
 }
+
+// CurrentROMBank returns the ROM bank mapped at 0x4000-0x7FFF
+func (mbc *MBC1) CurrentROMBank() int {
+	if mbc.romBank == 0 {
+		return 1
+	}
+	return int(mbc.romBank)
+}
+
+// SaveState writes the MBC1 banking registers and RAM
+func (mbc *MBC1) SaveState(w *snapshot.StateWriter) {
+	w.Uint8(mbc.romBank)
+	w.Uint8(mbc.ramBank)
+	w.Bool(mbc.ramEnabled)
+	w.Uint8(mbc.bankingMode)
+	w.Bytes(mbc.ram)
+}
+
+// LoadState restores the MBC1 state written by SaveState
+func (mbc *MBC1) LoadState(r *snapshot.StateReader) {
+	mbc.romBank = r.Uint8()
+	mbc.ramBank = r.Uint8()
+	mbc.ramEnabled = r.Bool()
+	mbc.bankingMode = r.Uint8()
+	r.BytesInto(mbc.ram)
+}
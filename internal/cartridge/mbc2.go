@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // MBC2 implementation
@@ -184,3 +186,25 @@ func (mbc *MBC2) SaveBatteryRAM() {
 func (mbc *MBC2) IsRumbling() bool {
 	return false
 }
+
+// CurrentROMBank returns the ROM bank mapped at 0x4000-0x7FFF
+func (mbc *MBC2) CurrentROMBank() int {
+	if mbc.romBank == 0 {
+		return 1
+	}
+	return int(mbc.romBank)
+}
+
+// SaveState writes the MBC2 banking registers and built-in RAM
+func (mbc *MBC2) SaveState(w *snapshot.StateWriter) {
+	w.Uint8(mbc.romBank)
+	w.Bool(mbc.ramEnabled)
+	w.Bytes(mbc.ram[:])
+}
+
+// LoadState restores the MBC2 state written by SaveState
+func (mbc *MBC2) LoadState(r *snapshot.StateReader) {
+	mbc.romBank = r.Uint8()
+	mbc.ramEnabled = r.Bool()
+	r.BytesInto(mbc.ram[:])
+}
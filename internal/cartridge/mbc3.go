@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // MBC3 implementation
@@ -409,3 +411,39 @@ func (mbc *MBC3) SaveBatteryRAM() {
 func (mbc *MBC3) IsRumbling() bool {
 	return false
 }
+
+// CurrentROMBank returns the ROM bank mapped at 0x4000-0x7FFF
+func (mbc *MBC3) CurrentROMBank() int {
+	if mbc.romBank == 0 {
+		return 1
+	}
+	return int(mbc.romBank)
+}
+
+// SaveState writes the MBC3 banking registers, RAM and RTC
+func (mbc *MBC3) SaveState(w *snapshot.StateWriter) {
+	w.Uint8(mbc.romBank)
+	w.Uint8(mbc.ramBank)
+	w.Bool(mbc.ramEnabled)
+	w.Bytes(mbc.ram)
+
+	w.Bytes(mbc.rtcRegisters[:])
+	w.Bool(mbc.rtcLatch)
+	w.Bytes(mbc.rtcLatched[:])
+	w.Int64(mbc.rtcBaseTime)
+	w.Int64(mbc.rtcLastTime)
+}
+
+// LoadState restores the MBC3 state written by SaveState
+func (mbc *MBC3) LoadState(r *snapshot.StateReader) {
+	mbc.romBank = r.Uint8()
+	mbc.ramBank = r.Uint8()
+	mbc.ramEnabled = r.Bool()
+	r.BytesInto(mbc.ram)
+
+	r.BytesInto(mbc.rtcRegisters[:])
+	mbc.rtcLatch = r.Bool()
+	r.BytesInto(mbc.rtcLatched[:])
+	mbc.rtcBaseTime = r.Int64()
+	mbc.rtcLastTime = r.Int64()
+}
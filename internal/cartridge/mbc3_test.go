@@ -1,8 +1,11 @@
 package cartridge
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // TestMBC3ROMBanking tests the ROM banking functionality of MBC3
@@ -262,3 +265,61 @@ func TestMBC3RTCSave(t *testing.T) {
 		t.Errorf("Expected RTC halt bit to be set after loading")
 	}
 }
+
+// TestMBC3SaveState tests that banking registers, RAM and RTC survive a save state round trip
+func TestMBC3SaveState(t *testing.T) {
+	rom := make([]byte, 256*1024)
+	rom[0x8000+0x1000] = 0x12 // Bank 2
+
+	// Create a temporary directory for save files
+	tmpDir, err := os.MkdirTemp("", "gameboy_test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mbc := NewMBC3(rom, 32*1024, CART_MBC3_TIMER_RAM_BAT, "TESTROM", tmpDir)
+
+	// Select ROM bank 2, RAM bank 1 and write to RAM and the RTC
+	mbc.WriteByte(0x0000, 0x0A)
+	mbc.WriteByte(0x2000, 0x02)
+	mbc.WriteByte(0x4000, 0x01)
+	mbc.WriteByte(0xA010, 0x99)
+	mbc.WriteByte(0x4000, 0x08)
+	mbc.WriteByte(0xA000, 0x2A) // RTC seconds
+	mbc.WriteByte(0x4000, 0x01)
+
+	var buf bytes.Buffer
+	w := snapshot.NewStateWriter(&buf)
+	mbc.SaveState(w)
+	if err := w.Err(); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	restored := NewMBC3(rom, 32*1024, CART_MBC3_TIMER_RAM_BAT, "OTHERROM", tmpDir)
+	r := snapshot.NewStateReader(&buf)
+	restored.LoadState(r)
+	if err := r.Err(); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+
+	if restored.ReadByte(0x5000) != 0x12 {
+		t.Errorf("Expected ROM bank 2 to be restored, read %02X", restored.ReadByte(0x5000))
+	}
+	if restored.ReadByte(0xA010) != 0x99 {
+		t.Errorf("Expected RAM bank 1 data to be restored, read %02X", restored.ReadByte(0xA010))
+	}
+	if restored.rtcRegisters[RTC_S] != 0x2A {
+		t.Errorf("Expected RTC seconds 0x2A, got %02X", restored.rtcRegisters[RTC_S])
+	}
+
+	// A state from a cartridge with a different RAM size must be rejected
+	buf.Reset()
+	mbc.SaveState(snapshot.NewStateWriter(&buf))
+	small := NewMBC3(rom, 8*1024, CART_MBC3_TIMER_RAM_BAT, "SMALLROM", tmpDir)
+	r = snapshot.NewStateReader(&buf)
+	small.LoadState(r)
+	if r.Err() == nil {
+		t.Error("Expected error loading a state with a different RAM size")
+	}
+}
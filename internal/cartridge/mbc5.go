@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // MBC5 implementation
@@ -209,3 +211,26 @@ func (mbc *MBC5) SaveBatteryRAM() {
 func (mbc *MBC5) IsRumbling() bool {
 	return mbc.hasRumble && mbc.rumble
 }
+
+// CurrentROMBank returns the ROM bank mapped at 0x4000-0x7FFF
+func (mbc *MBC5) CurrentROMBank() int {
+	return int(mbc.romBank)
+}
+
+// SaveState writes the MBC5 banking registers, rumble state and RAM
+func (mbc *MBC5) SaveState(w *snapshot.StateWriter) {
+	w.Uint16(mbc.romBank)
+	w.Uint8(mbc.ramBank)
+	w.Bool(mbc.ramEnabled)
+	w.Bool(mbc.rumble)
+	w.Bytes(mbc.ram)
+}
+
+// LoadState restores the MBC5 state written by SaveState
+func (mbc *MBC5) LoadState(r *snapshot.StateReader) {
+	mbc.romBank = r.Uint16()
+	mbc.ramBank = r.Uint8()
+	mbc.ramEnabled = r.Bool()
+	mbc.rumble = r.Bool()
+	r.BytesInto(mbc.ram)
+}
@@ -0,0 +1,121 @@
+// Package cdl keeps a code/data log of a ROM, recording how each byte was
+// used while the game ran: executed, read as an instruction's operand, read
+// as data or copied to OAM or VRAM. Disassemblers use it to tell code from
+// data, and it shows which parts of a ROM a run exercised.
+package cdl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ROM_BANK_SIZE is the size of a switchable ROM bank
+const ROM_BANK_SIZE = 0x4000
+
+// Flag records one way a ROM byte was used. A CDL file has one byte of
+// flags for every byte of the ROM, in the same order.
+type Flag byte
+
+const (
+	CODE    Flag = 1 << iota // The first byte of an executed instruction
+	OPERAND                  // Another byte of an executed instruction
+	DATA                     // Read by the CPU, other than as an instruction
+	DMA                      // Copied to OAM by OAM DMA
+	TILE                     // Read by the CPU and written to VRAM tile data
+)
+
+// Log holds the flags of every byte of a ROM
+type Log struct {
+	flags []Flag
+}
+
+// New creates an empty log for a ROM of size bytes
+func New(size int) *Log {
+	return &Log{flags: make([]Flag, size)}
+}
+
+// PathForROM returns the path of the CDL file next to a ROM, the ROM's path
+// with a .cdl extension
+func PathForROM(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".cdl"
+}
+
+// Load reads the CDL file at path for a ROM of size bytes, or returns an
+// empty log if the file does not exist, so runs can add to a log
+func Load(path string, size int) (*Log, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(size), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("%s is for a %d byte ROM, not %d bytes", path, len(data), size)
+	}
+
+	l := New(size)
+	for i, b := range data {
+		l.flags[i] = Flag(b)
+	}
+	return l, nil
+}
+
+// Save writes the log to path
+func (l *Log) Save(path string) error {
+	data := make([]byte, len(l.flags))
+	for i, f := range l.flags {
+		data[i] = byte(f)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Len returns the size of the ROM
+func (l *Log) Len() int {
+	return len(l.flags)
+}
+
+// Mark adds flag to the byte at offset in the ROM. Offsets outside the ROM
+// are ignored, they read open bus.
+func (l *Log) Mark(offset int, flag Flag) {
+	if offset >= 0 && offset < len(l.flags) {
+		l.flags[offset] |= flag
+	}
+}
+
+// Flags returns the flags of the byte at offset in the ROM. A nil log has
+// no flags.
+func (l *Log) Flags(offset int) Flag {
+	if l == nil || offset < 0 || offset >= len(l.flags) {
+		return 0
+	}
+	return l.flags[offset]
+}
+
+// Count returns the number of bytes with any of the flags in mask
+func (l *Log) Count(mask Flag) int {
+	n := 0
+	for _, f := range l.flags {
+		if f&mask != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Offset returns the offset in the ROM of addr when bank is mapped at
+// 0x4000-0x7FFF, or -1 outside ROM
+func Offset(addr uint16, bank int) int {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return int(addr)
+	case addr < 2*ROM_BANK_SIZE:
+		return bank*ROM_BANK_SIZE + int(addr) - ROM_BANK_SIZE
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,159 @@
+package cdl
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/mmu"
+)
+
+// program copies a byte from the ROM to VRAM and starts OAM DMA from it
+//
+//	0100: LD HL, $0200
+//	0103: LD A, [HL+]
+//	0104: LD [$8000], A
+//	0107: LD A, $02
+//	0109: LDH [$FF46], A
+//	010B: CALL $4000
+//	4000: LD A, [$4010]     in bank 2
+var program = map[int][]byte{
+	0x0100: {0x21, 0x00, 0x02, 0x2A, 0xEA, 0x00, 0x80, 0x3E, 0x02, 0xE0, 0x46, 0xCD, 0x00, 0x40},
+	0x0200: {0x5A},
+	0x8000: {0xFA, 0x10, 0x40},
+}
+
+// TestLogger tests marking bytes by how the CPU uses them
+func TestLogger(t *testing.T) {
+	cart := &MockCartridge{rom: make([]byte, 4*ROM_BANK_SIZE), bank: 2}
+	for offset, code := range program {
+		copy(cart.rom[offset:], code)
+	}
+	m := mmu.NewMMU()
+	m.DisableBIOS()
+	m.SetCartridge(cart)
+	c, _ := cpu.NewCPU(m)
+
+	log := New(len(cart.rom))
+	logger := NewLogger(log, cart)
+	for _, w := range logger.Watchpoints() {
+		m.AddWatchpoint(w)
+	}
+	for i := 0; i < 7; i++ {
+		c.Step()
+	}
+
+	tests := []struct {
+		offset int
+		flags  Flag
+	}{
+		{0x0100, CODE},
+		{0x0101, OPERAND},
+		{0x0103, CODE},
+		{0x0200, DATA | TILE | DMA},
+		{0x0201, DMA},
+		{0x029F, DMA},
+		{0x02A0, 0},
+		{0x8000, CODE},
+		{0x8002, OPERAND},
+		{0x8010, DATA},
+		{0x4010, 0},
+	}
+	for _, test := range tests {
+		if flags := log.Flags(test.offset); flags != test.flags {
+			t.Errorf("Expected flags %05b at %05X, got %05b", test.flags, test.offset, flags)
+		}
+	}
+	if n := log.Count(CODE); n != 7 {
+		t.Errorf("Expected 7 instructions, got %d", n)
+	}
+}
+
+// TestLoggerBootROM tests that the boot ROM is not logged as the ROM it is
+// mapped over
+func TestLoggerBootROM(t *testing.T) {
+	cart := &MockCartridge{rom: make([]byte, 2*ROM_BANK_SIZE), bank: 1}
+	m := mmu.NewMMU()
+	m.SetCartridge(cart)
+
+	// LD A, $01; LDH [$FF50], A
+	bios := make([]byte, mmu.DMG_BOOT_ROM_SIZE)
+	copy(bios[0xFC:], []byte{0x3E, 0x01, 0xE0, 0x50})
+	if err := m.LoadBIOS(bios); err != nil {
+		t.Fatalf("LoadBIOS failed: %v", err)
+	}
+	c, _ := cpu.NewCPU(m)
+	c.SetRegisters(cpu.Registers{PC: 0x00FC})
+
+	log := New(len(cart.rom))
+	for _, w := range NewLogger(log, cart).Watchpoints() {
+		m.AddWatchpoint(w)
+	}
+	for i := 0; i < 3; i++ {
+		c.Step()
+	}
+
+	if log.Flags(0x00FC) != 0 || log.Flags(0x00FE) != 0 {
+		t.Errorf("Expected the boot ROM not to be logged")
+	}
+	if log.Flags(0x0100) != CODE {
+		t.Errorf("Expected the instruction after the boot ROM is unmapped to be logged, got %05b", log.Flags(0x0100))
+	}
+}
+
+// TestSaveLoad tests that logs are saved and added to by later runs
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.cdl")
+
+	log, err := Load(path, 16)
+	if err != nil || log.Count(0xFF) != 0 {
+		t.Fatalf("Expected an empty log without a file, got %v", err)
+	}
+	log.Mark(1, CODE)
+	log.Mark(2, DATA)
+	log.Mark(16, CODE)
+	if err := log.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	log, err = Load(path, 16)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if log.Flags(1) != CODE || log.Flags(2) != DATA || log.Count(0xFF) != 2 {
+		t.Errorf("Expected the saved flags, got %v", log.flags)
+	}
+
+	if _, err := Load(path, 32); err == nil {
+		t.Error("Expected an error loading a log for a different size ROM")
+	}
+}
+
+// TestPathForROM tests finding the CDL file next to a ROM
+func TestPathForROM(t *testing.T) {
+	if path := PathForROM(filepath.Join("roms", "game.gb")); path != filepath.Join("roms", "game.cdl") {
+		t.Errorf("Expected roms/game.cdl, got %s", path)
+	}
+	if path := PathForROM("game"); path != "game.cdl" {
+		t.Errorf("Expected game.cdl, got %s", path)
+	}
+}
+
+// MockCartridge maps one of its ROM banks at 0x4000
+type MockCartridge struct {
+	rom  []byte
+	bank int
+}
+
+func (c *MockCartridge) ReadByte(addr uint16) byte {
+	if offset := Offset(addr, c.bank); offset >= 0 {
+		return c.rom[offset]
+	}
+	return 0xFF
+}
+
+func (c *MockCartridge) WriteByte(addr uint16, value byte) {}
+
+func (c *MockCartridge) CurrentROMBank() int {
+	return c.bank
+}
@@ -0,0 +1,85 @@
+package cdl
+
+import (
+	"github.com/briancain/gameboy-go/internal/disasm"
+	"github.com/briancain/gameboy-go/internal/mmu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// Logger marks a Log from the memory accesses reported by the watchpoints
+// it returns from Watchpoints. Addresses are mapped to the ROM through the
+// bank banker has switched in at the time of the access.
+type Logger struct {
+	log    *Log
+	banker symbols.ROMBanker
+
+	// The instruction being executed, whose bytes are read as it is
+	// fetched rather than as data
+	fetchAddr uint16
+	fetchLen  uint16
+
+	// The last byte read as data. Games copy tiles by reading them into a
+	// register and writing them to VRAM, so a VRAM write of the same value
+	// marks it as a tile.
+	lastRead      int
+	lastReadValue byte
+}
+
+// NewLogger creates a logger marking log. banker may be nil for ROMs
+// without banking.
+func NewLogger(log *Log, banker symbols.ROMBanker) *Logger {
+	return &Logger{log: log, banker: banker, lastRead: -1}
+}
+
+// Log returns the log being marked
+func (l *Logger) Log() *Log {
+	return l.log
+}
+
+// Watchpoints returns the watchpoints to install on the MMU, which report
+// instructions executed and data read from ROM, and writes to VRAM tile
+// data
+func (l *Logger) Watchpoints() []mmu.Watchpoint {
+	return []mmu.Watchpoint{
+		{Start: 0x0000, End: 0x7FFF, Kinds: mmu.AccessRead | mmu.AccessExecute, Callback: l.romAccess},
+		{Start: 0x8000, End: 0x97FF, Kinds: mmu.AccessWrite, Callback: l.tileWrite},
+	}
+}
+
+// offset returns the offset in the ROM of addr
+func (l *Logger) offset(addr uint16) int {
+	bank := 1
+	if l.banker != nil {
+		bank = l.banker.CurrentROMBank()
+	}
+	return Offset(addr, bank)
+}
+
+func (l *Logger) romAccess(a mmu.Access) {
+	switch {
+	case a.BIOS:
+		// The boot ROM is not part of the ROM
+	case a.Kind == mmu.AccessExecute:
+		l.fetchAddr = a.Addr
+		l.fetchLen = uint16(disasm.Length(a.Value))
+		l.log.Mark(l.offset(a.Addr), CODE)
+		for i := uint16(1); i < l.fetchLen; i++ {
+			l.log.Mark(l.offset(a.Addr+i), OPERAND)
+		}
+	case a.DMA:
+		l.log.Mark(l.offset(a.Addr), DMA)
+	case a.Addr-l.fetchAddr < l.fetchLen:
+		// Fetching the instruction, marked when it started
+	default:
+		l.lastRead = l.offset(a.Addr)
+		l.lastReadValue = a.Value
+		l.log.Mark(l.lastRead, DATA)
+	}
+}
+
+func (l *Logger) tileWrite(a mmu.Access) {
+	if l.lastRead >= 0 && a.Value == l.lastReadValue {
+		l.log.Mark(l.lastRead, TILE)
+	}
+	l.lastRead = -1
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAudioRecording verifies that a recording produces a WAV file with the emulated audio length
+func TestAudioRecording(t *testing.T) {
+	// JR -2 (loop forever)
+	gb := newTestCore(t, []byte{0x18, 0xFE})
+	gb.SetSampleRate(8000)
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if err := gb.StartAudioRecording(path); err != nil {
+		t.Fatalf("StartAudioRecording failed: %v", err)
+	}
+
+	// Ten frames, 1/6 of a second
+	for i := 0; i < 10; i++ {
+		gb.Step()
+	}
+
+	if err := gb.StopAudioRecording(); err != nil {
+		t.Fatalf("StopAudioRecording failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read recording: %v", err)
+	}
+
+	dataSize := binary.LittleEndian.Uint32(data[40:])
+	if int(dataSize) != len(data)-44 {
+		t.Errorf("Expected data size %d, got %d", len(data)-44, dataSize)
+	}
+
+	// 10 frames of 70224 cycles at 8000 Hz, 4 bytes per stereo sample
+	expected := 10 * 70224 * 8000 / 4194304
+	if frames := int(dataSize) / 4; frames < expected-1 || frames > expected+1 {
+		t.Errorf("Expected about %d samples, got %d", expected, frames)
+	}
+
+	// Stopping again is a no-op
+	if err := gb.StopAudioRecording(); err != nil {
+		t.Errorf("Expected second stop to succeed, got %v", err)
+	}
+}
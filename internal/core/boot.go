@@ -0,0 +1,34 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// SetBootROM sets a DMG or CGB boot ROM for Init to start the game with,
+// scrolling in the logo and checking the cartridge header as the hardware
+// does. Without one the game starts in the state the boot ROM leaves, see
+// Initialize.
+func (gb *GameBoyCore) SetBootROM(path string) {
+	gb.bootROMPath = path
+}
+
+// loadBootROM maps the boot ROM and starts the CPU at its first
+// instruction. The boot ROM sets up the rest of the hardware and unmaps
+// itself by writing FF50 before jumping to the cartridge at 0x0100.
+func (gb *GameBoyCore) loadBootROM(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := gb.Mmu.LoadBIOS(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	gb.Cpu.SetRegisters(cpu.Registers{})
+	log.Printf("[Core] Running boot ROM %s", path)
+	return nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/mmu"
+)
+
+// TestBootROM verifies that the boot ROM runs from 0x0000 and hands over to
+// the cartridge once it writes FF50
+func TestBootROM(t *testing.T) {
+	// JP $00FC; ...; LD A, $01; LDH [$FF50], A
+	bios := make([]byte, mmu.DMG_BOOT_ROM_SIZE)
+	copy(bios, []byte{0xC3, 0xFC, 0x00})
+	copy(bios[0xFC:], []byte{0x3E, 0x01, 0xE0, 0x50})
+	path := filepath.Join(t.TempDir(), "dmg_boot.bin")
+	if err := os.WriteFile(path, bios, 0644); err != nil {
+		t.Fatalf("Failed to write boot ROM: %v", err)
+	}
+
+	gb, _ := NewGameBoyCore(false)
+	gb.SetSaveDirectory(t.TempDir())
+	gb.SetBootROM(path)
+	// INC A
+	if err := gb.Init(writeTestROM(t, "BOOTTEST", []byte{0x3C})); err != nil {
+		t.Fatalf("Failed to initialize core: %v", err)
+	}
+
+	if pc := gb.Cpu.GetRegisters().PC; pc != 0x0000 || gb.ReadMemory(0x0000) != 0xC3 {
+		t.Fatalf("Expected to start in the boot ROM at 0000, got PC %04X", pc)
+	}
+
+	for i := 0; i < 3; i++ {
+		gb.StepInstruction()
+	}
+	if pc := gb.Cpu.GetRegisters().PC; pc != 0x0100 || gb.Mmu.BIOSActive() {
+		t.Fatalf("Expected the boot ROM to be unmapped at 0100, got PC %04X", pc)
+	}
+	if gb.ReadMemory(0x0000) != 0x00 {
+		t.Errorf("Expected the cartridge at 0000, got %02X", gb.ReadMemory(0x0000))
+	}
+
+	gb.StepInstruction()
+	if a := gb.Cpu.GetRegisters().A; a != 0x02 {
+		t.Errorf("Expected the cartridge to run after the boot ROM, got A=%02X", a)
+	}
+}
+
+// TestBootROMInvalid verifies that a boot ROM of the wrong size is rejected
+func TestBootROMInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "boot.bin")
+	if err := os.WriteFile(path, make([]byte, 0x200), 0644); err != nil {
+		t.Fatalf("Failed to write boot ROM: %v", err)
+	}
+
+	gb, _ := NewGameBoyCore(false)
+	gb.SetSaveDirectory(t.TempDir())
+	gb.SetBootROM(path)
+	if err := gb.Init(writeTestROM(t, "BOOTTEST", nil)); err == nil {
+		t.Error("Expected an error for a boot ROM of the wrong size")
+	}
+}
+
+// TestSkipBoot verifies the state the game starts in without a boot ROM
+func TestSkipBoot(t *testing.T) {
+	gb := newTestCore(t, []byte{0x00})
+
+	if gb.Mmu.BIOSActive() {
+		t.Error("Expected no boot ROM to be mapped")
+	}
+
+	reg := gb.Cpu.GetRegisters()
+	if reg.GetAF() != 0x01B0 || reg.GetBC() != 0x0013 || reg.GetDE() != 0x00D8 ||
+		reg.GetHL() != 0x014D || reg.SP != 0xFFFE || reg.PC != 0x0100 {
+		t.Errorf("Expected the DMG registers after boot, got %s", formatRegisters(reg))
+	}
+
+	tests := []struct {
+		addr  uint16
+		value byte
+	}{
+		{0xFF04, 0xAB}, // DIV
+		{0xFF0F, 0xE1}, // IF
+		{0xFF40, 0x91}, // LCDC
+		{0xFF46, 0xFF}, // DMA
+		{0xFF47, 0xFC}, // BGP
+		{0xFFFF, 0x00}, // IE
+	}
+	for _, test := range tests {
+		if value := gb.ReadMemory(test.addr); value != test.value {
+			t.Errorf("Expected %04X to be %02X, got %02X", test.addr, test.value, value)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/briancain/gameboy-go/internal/cdl"
+)
+
+// codeDataLog is a code/data log being recorded to a file
+type codeDataLog struct {
+	path        string
+	logger      *cdl.Logger
+	watchpoints []int
+}
+
+// StartCodeDataLog records how each byte of the ROM is used, as code or
+// data, until StopCodeDataLog saves it to path. An existing log at path is
+// added to, so it covers every run.
+func (gb *GameBoyCore) StartCodeDataLog(path string) error {
+	if gb.codeDataLog != nil {
+		return errors.New("code/data log already in progress")
+	}
+
+	l, err := cdl.Load(path, gb.Cartridge.GetROMSize())
+	if err != nil {
+		return err
+	}
+
+	run := &codeDataLog{path: path, logger: cdl.NewLogger(l, gb)}
+	for _, w := range run.logger.Watchpoints() {
+		run.watchpoints = append(run.watchpoints, gb.Mmu.AddWatchpoint(w))
+	}
+	gb.codeDataLog = run
+
+	log.Printf("[Core] Logging code and data to %s", path)
+	return nil
+}
+
+// StopCodeDataLog saves the code/data log. It does nothing if no log is in
+// progress.
+func (gb *GameBoyCore) StopCodeDataLog() error {
+	run := gb.codeDataLog
+	if run == nil {
+		return nil
+	}
+	gb.codeDataLog = nil
+	for _, id := range run.watchpoints {
+		gb.Mmu.RemoveWatchpoint(id)
+	}
+
+	l := run.logger.Log()
+	if err := l.Save(run.path); err != nil {
+		return fmt.Errorf("writing code/data log %s: %w", run.path, err)
+	}
+
+	code := l.Count(cdl.CODE | cdl.OPERAND)
+	data := l.Count(cdl.DATA | cdl.DMA | cdl.TILE)
+	used := l.Count(cdl.CODE | cdl.OPERAND | cdl.DATA | cdl.DMA | cdl.TILE)
+	log.Printf("[Core] Logged %d bytes of code and %d bytes of data, %.1f%% of the ROM, to %s",
+		code, data, 100*float64(used)/float64(max(l.Len(), 1)), run.path)
+	return nil
+}
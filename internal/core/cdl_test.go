@@ -0,0 +1,81 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cdl"
+)
+
+// TestCodeDataLog verifies that executed code is saved to the log
+func TestCodeDataLog(t *testing.T) {
+	// INC A; LD (C000),A; JR -6
+	gb := newTestCore(t, []byte{0x3C, 0xEA, 0x00, 0xC0, 0x18, 0xFA})
+
+	path := filepath.Join(t.TempDir(), "game.cdl")
+	if err := gb.StartCodeDataLog(path); err != nil {
+		t.Fatalf("StartCodeDataLog failed: %v", err)
+	}
+	if err := gb.StartCodeDataLog(path); err == nil {
+		t.Error("Expected an error starting a second log")
+	}
+	for i := 0; i < 10; i++ {
+		gb.StepInstruction()
+	}
+	if err := gb.StopCodeDataLog(); err != nil {
+		t.Fatalf("StopCodeDataLog failed: %v", err)
+	}
+
+	l, err := cdl.Load(path, gb.Cartridge.GetROMSize())
+	if err != nil {
+		t.Fatalf("Failed to load the log: %v", err)
+	}
+	if l.Flags(0x0100) != cdl.CODE || l.Flags(0x0102) != cdl.OPERAND || l.Flags(0x0105) != cdl.OPERAND {
+		t.Errorf("Expected the loop to be logged as code, got %05b %05b %05b",
+			l.Flags(0x0100), l.Flags(0x0102), l.Flags(0x0105))
+	}
+	if n := l.Count(cdl.CODE); n != 3 {
+		t.Errorf("Expected 3 instructions, got %d", n)
+	}
+
+	// Stopping again is a no-op
+	if err := gb.StopCodeDataLog(); err != nil {
+		t.Errorf("Expected second stop to succeed, got %v", err)
+	}
+}
+
+// TestCodeDataLogWithTrace verifies that the bytes a trace logs after each
+// instruction are not logged as data
+func TestCodeDataLogWithTrace(t *testing.T) {
+	// INC A; JR -3
+	gb := newTestCore(t, []byte{0x3C, 0x18, 0xFD})
+
+	dir := t.TempDir()
+	if err := gb.StartTrace(filepath.Join(dir, "trace.log"), false, false); err != nil {
+		t.Fatalf("StartTrace failed: %v", err)
+	}
+	path := filepath.Join(dir, "game.cdl")
+	if err := gb.StartCodeDataLog(path); err != nil {
+		t.Fatalf("StartCodeDataLog failed: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		gb.StepInstruction()
+	}
+	if err := gb.StopCodeDataLog(); err != nil {
+		t.Fatalf("StopCodeDataLog failed: %v", err)
+	}
+	if err := gb.StopTrace(); err != nil {
+		t.Fatalf("StopTrace failed: %v", err)
+	}
+
+	l, err := cdl.Load(path, gb.Cartridge.GetROMSize())
+	if err != nil {
+		t.Fatalf("Failed to load the log: %v", err)
+	}
+	if n := l.Count(cdl.DATA); n != 0 {
+		t.Errorf("Expected no data, got %d bytes", n)
+	}
+	if l.Flags(0x0103) != 0 || l.Flags(0x0104) != 0 {
+		t.Errorf("Expected the bytes after the loop to be unused, got %05b %05b", l.Flags(0x0103), l.Flags(0x0104))
+	}
+}
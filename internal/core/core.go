@@ -9,8 +9,10 @@ import (
 	"github.com/briancain/gameboy-go/internal/cpu"
 	"github.com/briancain/gameboy-go/internal/mmu"
 	"github.com/briancain/gameboy-go/internal/ppu"
+	"github.com/briancain/gameboy-go/internal/serial"
 	"github.com/briancain/gameboy-go/internal/snapshot"
 	"github.com/briancain/gameboy-go/internal/sound"
+	"github.com/briancain/gameboy-go/internal/symbols"
 	"github.com/briancain/gameboy-go/internal/timer"
 )
 
@@ -22,6 +24,7 @@ type GameBoyCore struct {
 	Ppu       *ppu.PPU
 	Sound     *sound.Sound
 	Timer     *timer.Timer
+	Serial    *serial.Serial
 	Cartridge *cartridge.Cartridge
 
 	// Speed options
@@ -29,16 +32,54 @@ type GameBoyCore struct {
 
 	Controller controller.Controller
 
-	Snapshots []snapshot.Snapshot
+	// In-memory snapshot tree for exploring alternate play paths
+	Snapshots *snapshot.Manager
+
+	// Labels from the symbol file next to the ROM, nil if there is none
+	Symbols *symbols.Table
 
 	// Private vars
 	exit           bool
 	debug          bool
 	batterySaveDir string
+	bootROMPath    string
 
 	// Timing
 	cyclesPerFrame int
+	frameCycles    int
 	lastFrameTime  time.Time
+
+	// Rewind history, nil when rewind is disabled
+	rewind *rewindState
+
+	// Destinations for mixed APU samples
+	sampleSinks []sound.SampleSink
+
+	// Audio recording in progress, nil when not recording
+	audioRecording *audioRecording
+
+	// Instruction trace in progress, nil when not tracing
+	traceLog *traceLog
+
+	// Profile in progress, nil when not profiling
+	profile *profileRun
+
+	// Set when a debugger needs calls tracked, see TrackCalls
+	debugTracksCalls bool
+
+	// Code/data log in progress, nil when not logging
+	codeDataLog *codeDataLog
+
+	// Crash reports, see WriteCrashReport. pendingFault is reported at the
+	// end of the step it happened in.
+	crashDir      string
+	pendingFault  *cpu.Fault
+	faultReported bool
+
+	// Subscribers to CPU lock-ups, see OnLockup. pendingLockup is announced
+	// at the end of the step it happened in.
+	lockupHandlers []func(cpu.Fault)
+	pendingLockup  *cpu.Fault
 }
 
 func NewGameBoyCore(debug bool) (*GameBoyCore, error) {
@@ -74,27 +115,47 @@ func (gb *GameBoyCore) Init(cartPath string) error {
 	// Set up the cartridge in the MMU
 	gb.Mmu.SetCartridge(crt)
 
+	// Load labels for debugging, a broken symbol file should not stop the
+	// game from running
+	gb.Symbols, err = symbols.LoadForROM(cartPath)
+	if err != nil {
+		log.Printf("[Core] Failed to load symbols: %v", err)
+	} else if gb.Symbols != nil {
+		log.Printf("[Core] Loaded %d symbols", gb.Symbols.Len())
+	}
+
 	// Initialize CPU with reference to MMU
 	gb.Cpu, err = cpu.NewCPU(gb.Mmu)
 	if err != nil {
 		return err
 	}
+	gb.Cpu.SetFaultHandler(gb.onFault)
 
 	// Initialize PPU with reference to MMU
-	gb.Ppu = ppu.NewPPU(gb.Mmu)
+	gb.Ppu = ppu.NewPPU(gb.Mmu.Unwatched())
+	gb.Ppu.SetDebug(gb.debug)
 
 	// Set the PPU in the MMU for register write handling
 	gb.Mmu.SetPPU(gb.Ppu)
 
 	// Initialize Timer with reference to MMU
-	gb.Timer = timer.NewTimer(gb.Mmu)
+	gb.Timer = timer.NewTimer(gb.Mmu.Unwatched())
 
 	// Set the timer in the MMU
 	gb.Mmu.SetTimer(gb.Timer)
 
+	// Initialize the serial controller with reference to MMU
+	gb.Serial = serial.NewSerial(gb.Mmu.Unwatched())
+
+	// Set the serial controller in the MMU
+	gb.Mmu.SetSerial(gb.Serial)
+
 	// Initialize Sound
 	gb.Sound = sound.NewSound()
 
+	// Set the sound system in the MMU
+	gb.Mmu.SetSound(gb.Sound)
+
 	// Initialize hardware controller
 	gb.Controller = controller.NewKeyboard()
 	gb.Controller.Init()
@@ -102,8 +163,17 @@ func (gb *GameBoyCore) Init(cartPath string) error {
 	// Set the controller in the MMU
 	gb.Mmu.SetController(gb.Controller)
 
-	// Initialize to post-boot state (simulate boot ROM completion)
-	gb.Initialize()
+	// Run the boot ROM if there is one, otherwise start in the state it
+	// would have left the Game Boy in
+	if gb.bootROMPath != "" {
+		if err := gb.loadBootROM(gb.bootROMPath); err != nil {
+			return err
+		}
+	} else {
+		gb.Initialize()
+	}
+
+	gb.Snapshots = snapshot.NewManager(gb)
 
 	return nil
 }
@@ -138,30 +208,23 @@ func (gb *GameBoyCore) Run() error {
 	}
 }
 
-// runFrame executes one frame of emulation
-func (gb *GameBoyCore) runFrame() error {
-	cyclesThisFrame := 0
+// runFrame executes one frame of emulation. A panic is returned as an
+// error after writing a crash report.
+func (gb *GameBoyCore) runFrame() (err error) {
+	defer gb.recoverPanic(&err)
 
 	// Run until we've executed enough cycles for one frame
-	for cyclesThisFrame < gb.cyclesPerFrame {
-		// Execute one CPU instruction
-		cycles := gb.Cpu.Step()
-
-		// Update PPU
-		gb.Ppu.Step(cycles)
-
-		// Update Sound
-		gb.Sound.Step(cycles)
-
-		// Update Timer
-		gb.Timer.Step(cycles)
-
-		cyclesThisFrame += cycles
+	for {
+		cycles, frameDone := gb.step()
 
 		// Debug output
 		if gb.debug {
 			log.Printf("[DEBUG] Executed instruction, cycles: %d", cycles)
 		}
+
+		if frameDone {
+			break
+		}
 	}
 
 	// Debug output for frame
@@ -175,6 +238,48 @@ func (gb *GameBoyCore) runFrame() error {
 	return nil
 }
 
+// step executes one CPU instruction and advances the other components by the
+// same number of cycles. It reports whether a frame boundary was crossed.
+func (gb *GameBoyCore) step() (int, bool) {
+	// Execute one CPU instruction
+	cycles := gb.Cpu.Step()
+
+	// Update PPU
+	gb.Ppu.Step(cycles)
+
+	// Update Sound
+	gb.Sound.Step(cycles)
+
+	// Update Timer
+	gb.Timer.Step(cycles)
+
+	// Update Serial
+	gb.Serial.Step(cycles)
+
+	if gb.pendingFault != nil {
+		gb.reportFault()
+	}
+	if gb.pendingLockup != nil {
+		gb.announceLockup()
+	}
+
+	gb.frameCycles += cycles
+	if gb.frameCycles < gb.cyclesPerFrame {
+		return cycles, false
+	}
+	gb.frameCycles -= gb.cyclesPerFrame
+
+	gb.endFrame()
+	return cycles, true
+}
+
+// endFrame runs the per-frame bookkeeping once a full frame has been emulated
+func (gb *GameBoyCore) endFrame() {
+	if gb.rewind != nil {
+		gb.captureRewind()
+	}
+}
+
 // throttleFPS limits the emulation speed to the target FPS
 func (gb *GameBoyCore) throttleFPS() {
 	// Calculate target frame time
@@ -219,25 +324,22 @@ func (gb *GameBoyCore) Step() error {
 	return gb.runFrame()
 }
 
-// StepInstruction executes a single CPU instruction (for more granular control)
-func (gb *GameBoyCore) StepInstruction() (int, error) {
-	// Execute one CPU instruction
-	cycles := gb.Cpu.Step()
-
-	// Update PPU
-	gb.Ppu.Step(cycles)
-
-	// Update Sound
-	gb.Sound.Step(cycles)
-
-	// Update Timer
-	gb.Timer.Step(cycles)
+// StepInstruction executes a single CPU instruction (for more granular
+// control). A panic is returned as an error after writing a crash report.
+func (gb *GameBoyCore) StepInstruction() (cycles int, err error) {
+	defer gb.recoverPanic(&err)
 
+	cycles, _ = gb.step()
 	return cycles, nil
 }
 
-// Initialize sets up the GameBoy to the post-boot state
+// Initialize sets up the GameBoy in the state the DMG boot ROM leaves it
+// in, from the Pan Docs power up sequence. The CPU registers are already
+// set by cpu.ResetCPU, and the sound registers by sound.Reset as writing
+// NRx4 here would trigger the channels.
 func (gb *GameBoyCore) Initialize() {
+	gb.Mmu.WriteByte(0xFF50, 0x01) // Boot ROM disable
+
 	// Most importantly, enable the LCD (what boot ROM would do)
 	gb.Mmu.WriteByte(0xFF40, 0x91) // LCDC - LCD enabled, BG enabled
 	gb.Mmu.WriteByte(0xFF42, 0x00) // SCY - Scroll Y
@@ -249,12 +351,18 @@ func (gb *GameBoyCore) Initialize() {
 	gb.Mmu.WriteByte(0xFF4A, 0x00) // WY - Window Y
 	gb.Mmu.WriteByte(0xFF4B, 0x00) // WX - Window X
 
-	// Initialize sound registers
-	gb.Mmu.WriteByte(0xFF26, 0xF1) // NR52 - Sound on/off
+	// Writing DMA would start a transfer
+	gb.Mmu.WriteIODirect(0xFF46, 0xFF) // DMA - OAM DMA source
 
-	// Initialize timer
+	// Initialize timer, DIV has been counting while the boot ROM ran
+	gb.Timer.SetDivider(0xAB)      // DIV - Divider
 	gb.Mmu.WriteByte(0xFF07, 0x00) // TAC - Timer control
+
+	// The boot ROM leaves a V-Blank interrupt requested
+	gb.Mmu.WriteByte(0xFF0F, 0xE1) // IF - Interrupt flags
+	gb.Mmu.WriteByte(0xFFFF, 0x00) // IE - Interrupt enable
 }
+
 func (gb *GameBoyCore) GetPPUDebugInfo() map[string]interface{} {
 	lcdc := gb.Mmu.ReadByte(0xFF40) // LCDC register
 	stat := gb.Mmu.ReadByte(0xFF41) // STAT register
@@ -289,12 +397,6 @@ func (gb *GameBoyCore) SetButtonState(button string, pressed bool) {
 	}
 }
 
-// Takes a snapshot of the current state of the system that can be loaded later
-func (gb *GameBoyCore) SaveState() error {
-	// TODO: Implement save state functionality
-	return nil
-}
-
 // SetSaveDirectory sets the directory where battery-backed save files will be stored
 func (gb *GameBoyCore) SetSaveDirectory(dir string) {
 	gb.batterySaveDir = dir
@@ -2,6 +2,8 @@ package core
 
 import (
 	"testing"
+
+	"github.com/briancain/gameboy-go/internal/mmu"
 )
 
 // TestGameBoyCoreInitialization verifies that a new GameBoyCore can be created
@@ -48,3 +50,36 @@ func TestGameBoyCoreExit(t *testing.T) {
 		t.Error("Expected exit flag to be true after calling Exit")
 	}
 }
+
+// TestWatchpointsOnlySeeProgram verifies that the PPU, timer and interrupt
+// polling do not trigger watchpoints, only the program's accesses do
+func TestWatchpointsOnlySeeProgram(t *testing.T) {
+	// LD A, $05; LDH [$FF07], A; LDH A, [$FF0F]; JR @
+	gb := newTestCore(t, []byte{0x3E, 0x05, 0xE0, 0x07, 0xF0, 0x0F, 0x18, 0xFE})
+
+	var vram, interrupts []mmu.Access
+	gb.Mmu.AddWatchpoint(mmu.Watchpoint{
+		Start: 0x8000, End: 0x9FFF, Kinds: mmu.AccessRead,
+		Callback: func(a mmu.Access) { vram = append(vram, a) },
+	})
+	gb.Mmu.AddWatchpoint(mmu.Watchpoint{
+		Start: 0xFF0F, End: 0xFF0F, Kinds: mmu.AccessRead | mmu.AccessWrite,
+		Callback: func(a mmu.Access) { interrupts = append(interrupts, a) },
+	})
+
+	// A frame has the PPU fetch tiles and request V-Blank, and the timer
+	// overflow
+	if err := gb.runFrame(); err != nil {
+		t.Fatalf("runFrame failed: %v", err)
+	}
+
+	if len(vram) != 0 {
+		t.Errorf("Expected no VRAM reads by the program, got %d", len(vram))
+	}
+	if len(interrupts) != 1 || interrupts[0].Kind != mmu.AccessRead {
+		t.Errorf("Expected only the program's read of IF, got %v", interrupts)
+	}
+	if gb.Mmu.Peek(0xFF0F)&0x05 != 0x05 {
+		t.Errorf("Expected V-Blank and timer interrupts requested, got IF=%02X", gb.Mmu.Peek(0xFF0F))
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/disasm"
+)
+
+// SetCrashDirectory sets the directory crash reports are written to. By
+// default they go to the battery save directory.
+func (gb *GameBoyCore) SetCrashDirectory(dir string) {
+	gb.crashDir = dir
+}
+
+// onFault is the CPU's fault handler. The report is written once the other
+// components have caught up with the instruction, see step.
+func (gb *GameBoyCore) onFault(f cpu.Fault) {
+	if gb.pendingFault == nil && !gb.faultReported {
+		gb.pendingFault = &f
+	}
+	if f.Kind == cpu.FaultIllegalOpcode {
+		gb.pendingLockup = &f
+	}
+}
+
+// OnLockup adds a function to call when an illegal opcode locks up the CPU,
+// after the crash report is written. Handlers run on the emulation thread.
+func (gb *GameBoyCore) OnLockup(handler func(cpu.Fault)) {
+	gb.lockupHandlers = append(gb.lockupHandlers, handler)
+}
+
+// announceLockup passes the lock-up to every OnLockup handler
+func (gb *GameBoyCore) announceLockup() {
+	f := *gb.pendingLockup
+	gb.pendingLockup = nil
+	for _, handler := range gb.lockupHandlers {
+		handler(f)
+	}
+}
+
+// reportFault writes a crash report for the first fault of the run. Later
+// faults are usually the same crash carrying on, so they are not reported.
+func (gb *GameBoyCore) reportFault() {
+	f := *gb.pendingFault
+	gb.pendingFault = nil
+	gb.faultReported = true
+
+	reason := f.String()
+	if label := gb.LabelAt(f.PC); label != "" {
+		reason += " (" + label + ")"
+	}
+	path, err := gb.WriteCrashReport("CPU crashed: "+reason, nil)
+	if err != nil {
+		log.Printf("[Core] CPU crashed: %s, failed to write crash report: %v", reason, err)
+		return
+	}
+	log.Printf("[Core] CPU crashed: %s, wrote crash report to %s", reason, path)
+}
+
+// recoverPanic turns a panic while emulating into an error, after writing a
+// crash report. It must be deferred.
+func (gb *GameBoyCore) recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	reason := fmt.Sprintf("emulator panic: %v", r)
+	path, reportErr := gb.WriteCrashReport(reason, debug.Stack())
+	if reportErr != nil {
+		*err = fmt.Errorf("%s (failed to write crash report: %v)", reason, reportErr)
+		return
+	}
+	*err = fmt.Errorf("%s, wrote crash report to %s", reason, path)
+}
+
+// WriteCrashReport writes the instruction history, registers and memory to
+// a text file in the crash directory, with a save state next to it, and
+// returns the report's path. goStack is included if it is not nil.
+func (gb *GameBoyCore) WriteCrashReport(reason string, goStack []byte) (string, error) {
+	dir := gb.crashDir
+	if dir == "" {
+		dir = gb.batterySaveDir
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := "gameboy"
+	if gb.Cartridge != nil {
+		if title := sanitizeTitle(gb.Cartridge.GetTitle()); title != "" {
+			name = title
+		}
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%s-crash-%s", name, time.Now().Format("20060102-150405")))
+	reportPath, statePath := base+".txt", base+".state"
+
+	// The emulator may be in a bad way after a panic, a failed save state
+	// should not lose the rest of the report
+	stateErr := gb.SaveStateFile(statePath)
+
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	gb.writeCrashReport(w, reason, statePath, stateErr, goStack)
+	err = w.Flush()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return reportPath, nil
+}
+
+// writeCrashReport writes the body of a crash report
+func (gb *GameBoyCore) writeCrashReport(w io.Writer, reason, statePath string, stateErr error, goStack []byte) {
+	fmt.Fprintf(w, "%s\n\n", reason)
+	if gb.Cartridge != nil {
+		fmt.Fprintf(w, "ROM: %s\n", strings.TrimRight(gb.Cartridge.GetTitle(), "\x00 "))
+	}
+	fmt.Fprintf(w, "Time: %s\n", time.Now().Format(time.RFC3339))
+	if stateErr != nil {
+		fmt.Fprintf(w, "Save state: failed: %v\n", stateErr)
+	} else {
+		fmt.Fprintf(w, "Save state: %s\n", statePath)
+	}
+
+	reg := gb.Cpu.GetRegisters()
+	fmt.Fprintf(w, "\nRegisters:\n%s  ROM bank %d\n", formatRegisters(reg), gb.CurrentROMBank())
+
+	if stack := gb.Cpu.CallStack(); len(stack) > 0 {
+		fmt.Fprintln(w, "\nCall stack, innermost first:")
+		for i := len(stack) - 1; i >= 0; i-- {
+			frame := stack[i]
+			fmt.Fprintf(w, "  %04X  called from %04X%s\n", frame.Target, frame.CallSite, gb.crashLabel(frame.CallSite))
+		}
+	}
+
+	history := gb.Cpu.History()
+	fmt.Fprintf(w, "\nLast %d instructions, oldest first, with the registers before each:\n", len(history))
+	d := disasm.New()
+	d.SetSymbols(gb.Symbols)
+	for _, entry := range history {
+		inst := d.Decode(historyMemory{gb, entry}, entry.PC)
+		fmt.Fprintf(w, "  %04X  %-20s %s%s\n", entry.PC, inst.Text(), formatRegisters(entry.Registers), gb.crashLabel(entry.PC))
+	}
+
+	if goStack != nil {
+		fmt.Fprintf(w, "\nGo stack:\n%s", goStack)
+	}
+
+	fmt.Fprintln(w, "\nMemory:")
+	writeMemoryDump(w, gb.ReadMemory)
+}
+
+// crashLabel names addr for a crash report, with a leading space
+func (gb *GameBoyCore) crashLabel(addr uint16) string {
+	if label := gb.LabelAt(addr); label != "" {
+		return "  ; " + label
+	}
+	return ""
+}
+
+// historyMemory reads memory for disassembling a history entry, with the
+// opcode that was executed, as the code may have changed since
+type historyMemory struct {
+	gb    *GameBoyCore
+	entry cpu.HistoryEntry
+}
+
+func (m historyMemory) ReadMemory(addr uint16) byte {
+	if addr == m.entry.PC {
+		return m.entry.Opcode
+	}
+	return m.gb.ReadMemory(addr)
+}
+
+func (m historyMemory) CurrentROMBank() int {
+	return m.gb.CurrentROMBank()
+}
+
+// formatRegisters formats the registers on one line
+func formatRegisters(reg cpu.Registers) string {
+	return fmt.Sprintf("AF=%04X BC=%04X DE=%04X HL=%04X SP=%04X PC=%04X",
+		reg.GetAF(), reg.GetBC(), reg.GetDE(), reg.GetHL(), reg.SP, reg.PC)
+}
+
+// writeMemoryDump writes the whole address space as hex, 16 bytes a line.
+// Runs of lines repeating the line before them are written as one "*", as
+// hexdump does.
+func writeMemoryDump(w io.Writer, read func(uint16) byte) {
+	var line, previous [16]byte
+	repeating := false
+	for addr := 0; addr < 0x10000; addr += 16 {
+		for i := range line {
+			line[i] = read(uint16(addr + i))
+		}
+		if addr > 0 && line == previous {
+			if !repeating {
+				fmt.Fprintln(w, "*")
+				repeating = true
+			}
+			continue
+		}
+		previous = line
+		repeating = false
+
+		var hex strings.Builder
+		for _, b := range line {
+			fmt.Fprintf(&hex, "%02X ", b)
+		}
+		fmt.Fprintf(w, "%04X: %s\n", addr, strings.TrimSpace(hex.String()))
+	}
+}
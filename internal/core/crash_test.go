@@ -0,0 +1,129 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// TestCrashReportOnFault verifies that an illegal opcode writes one crash
+// report with a save state
+func TestCrashReportOnFault(t *testing.T) {
+	// INC A; illegal $D3; illegal $DB
+	gb := newTestCore(t, []byte{0x3C, 0xD3, 0xDB})
+	dir := t.TempDir()
+	gb.SetCrashDirectory(dir)
+
+	for i := 0; i < 3; i++ {
+		gb.StepInstruction()
+	}
+
+	report := readCrashReport(t, dir)
+	for _, want := range []string{
+		"CPU crashed: illegal opcode $D3 at $0101",
+		"Last 2 instructions",
+		"0100  INC A",
+		"0101  DB $D3",
+		"Registers:\nAF=",
+		"Memory:\n0000: ",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+
+	states, _ := filepath.Glob(filepath.Join(dir, "*.state"))
+	if len(states) != 1 {
+		t.Fatalf("Expected one save state, got %v", states)
+	}
+	if err := gb.LoadStateFile(states[0]); err != nil {
+		t.Errorf("Expected the save state to load, got %v", err)
+	}
+}
+
+// TestOnLockup verifies that every lock-up handler hears about an illegal
+// opcode once, and crash reports are still written
+func TestOnLockup(t *testing.T) {
+	// INC A; illegal $DD
+	gb := newTestCore(t, []byte{0x3C, 0xDD})
+	dir := t.TempDir()
+	gb.SetCrashDirectory(dir)
+
+	var first, second []cpu.Fault
+	gb.OnLockup(func(f cpu.Fault) { first = append(first, f) })
+	gb.OnLockup(func(f cpu.Fault) { second = append(second, f) })
+
+	for i := 0; i < 4; i++ {
+		gb.StepInstruction()
+	}
+
+	for _, faults := range [][]cpu.Fault{first, second} {
+		if len(faults) != 1 || faults[0].PC != 0x0101 || faults[0].Opcode != 0xDD {
+			t.Errorf("Expected one lock-up at $0101, got %v", faults)
+		}
+	}
+	readCrashReport(t, dir)
+}
+
+// TestCrashReportOnPanic verifies that a panic while running a frame is
+// returned as an error with a crash report
+func TestCrashReportOnPanic(t *testing.T) {
+	gb := newTestCore(t, []byte{0x00})
+	dir := t.TempDir()
+	gb.SetCrashDirectory(dir)
+	gb.Cpu.AddObserver(&MockPanicObserver{})
+
+	err := gb.runFrame()
+	if err == nil || !strings.Contains(err.Error(), "emulator panic: broken") {
+		t.Fatalf("Expected the panic as an error, got %v", err)
+	}
+
+	report := readCrashReport(t, dir)
+	if !strings.Contains(report, "Go stack:") {
+		t.Errorf("Expected the report to contain the Go stack, got:\n%s", report)
+	}
+}
+
+// TestCrashReportOnStepPanic verifies that a panic while stepping an
+// instruction, as the display and debuggers do, is returned as an error
+// with a crash report
+func TestCrashReportOnStepPanic(t *testing.T) {
+	gb := newTestCore(t, []byte{0x00})
+	dir := t.TempDir()
+	gb.SetCrashDirectory(dir)
+	gb.Cpu.AddObserver(&MockPanicObserver{})
+
+	_, err := gb.StepInstruction()
+	if err == nil || !strings.Contains(err.Error(), "emulator panic: broken") {
+		t.Fatalf("Expected the panic as an error, got %v", err)
+	}
+
+	report := readCrashReport(t, dir)
+	if !strings.Contains(report, "Go stack:") {
+		t.Errorf("Expected the report to contain the Go stack, got:\n%s", report)
+	}
+}
+
+// readCrashReport reads the only crash report in dir
+func readCrashReport(t *testing.T, dir string) string {
+	t.Helper()
+	reports, _ := filepath.Glob(filepath.Join(dir, "*-crash-*.txt"))
+	if len(reports) != 1 {
+		t.Fatalf("Expected one crash report, got %v", reports)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatalf("Failed to read crash report: %v", err)
+	}
+	return string(data)
+}
+
+// MockPanicObserver panics before every instruction
+type MockPanicObserver struct{}
+
+func (o *MockPanicObserver) BeforeInstruction(c *cpu.Z80) {
+	panic("broken")
+}
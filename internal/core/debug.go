@@ -0,0 +1,69 @@
+package core
+
+import (
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/mmu"
+)
+
+// Registers returns a copy of the CPU registers
+func (gb *GameBoyCore) Registers() cpu.Registers {
+	return gb.Cpu.GetRegisters()
+}
+
+// SetRegisters replaces the CPU registers
+func (gb *GameBoyCore) SetRegisters(reg cpu.Registers) {
+	gb.Cpu.SetRegisters(reg)
+}
+
+// ReadMemory reads a byte through the MMU, as the CPU would see it, without
+// triggering watchpoints
+func (gb *GameBoyCore) ReadMemory(addr uint16) byte {
+	return gb.Mmu.Peek(addr)
+}
+
+// WriteMemory writes a byte through the MMU, as the CPU would
+func (gb *GameBoyCore) WriteMemory(addr uint16, value byte) {
+	gb.Mmu.WriteByte(addr, value)
+}
+
+// AddWatchpoint installs a memory watchpoint, see mmu.AddWatchpoint
+func (gb *GameBoyCore) AddWatchpoint(w mmu.Watchpoint) int {
+	return gb.Mmu.AddWatchpoint(w)
+}
+
+// RemoveWatchpoint removes a memory watchpoint by ID
+func (gb *GameBoyCore) RemoveWatchpoint(id int) bool {
+	return gb.Mmu.RemoveWatchpoint(id)
+}
+
+// LabelAt names addr by the closest preceding symbol in the ROM bank
+// mapped there, or returns "" without one
+func (gb *GameBoyCore) LabelAt(addr uint16) string {
+	return gb.Symbols.LabelAt(addr, gb)
+}
+
+// TrackCalls turns call stack tracking on or off for debugging, see
+// cpu.TrackCalls. Calls stay tracked while profiling.
+func (gb *GameBoyCore) TrackCalls(enabled bool) {
+	gb.debugTracksCalls = enabled
+	gb.Cpu.TrackCalls(enabled || gb.profile != nil)
+}
+
+// CallStack returns the tracked call stack, outermost frame first
+func (gb *GameBoyCore) CallStack() []cpu.CallFrame {
+	return gb.Cpu.CallStack()
+}
+
+// CurrentROMBank returns the ROM bank mapped at 0x4000-0x7FFF
+func (gb *GameBoyCore) CurrentROMBank() int {
+	if gb.Cartridge == nil || gb.Cartridge.GetMBC() == nil {
+		return 1
+	}
+	return gb.Cartridge.GetMBC().CurrentROMBank()
+}
+
+// Locked reports whether an illegal opcode has locked up the CPU, see
+// cpu.Locked
+func (gb *GameBoyCore) Locked() bool {
+	return gb.Cpu.Locked()
+}
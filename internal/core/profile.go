@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/profiler"
+)
+
+// profileRun is a profile being recorded to a file
+type profileRun struct {
+	path     string
+	profiler *profiler.Profiler
+}
+
+// StartProfile attributes the cycles of every instruction to the call
+// stack it ran in, until StopProfile writes them to path as a pprof
+// profile. Functions are named by the ROM's symbols when it has a symbol
+// file.
+func (gb *GameBoyCore) StartProfile(path string) error {
+	if gb.profile != nil {
+		return errors.New("profile already in progress")
+	}
+
+	// Fail early rather than after a long run
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	p := profiler.New(gb.Cartridge.GetTitle(), gb, gb.Symbols)
+	gb.profile = &profileRun{path: path, profiler: p}
+	gb.Cpu.TrackCalls(true)
+	gb.Cpu.AddObserver(p)
+
+	log.Printf("[Core] Profiling to %s", path)
+	return nil
+}
+
+// StopProfile finishes the profile and writes it. It does nothing if no
+// profile is in progress.
+func (gb *GameBoyCore) StopProfile() error {
+	run := gb.profile
+	if run == nil {
+		return nil
+	}
+	gb.profile = nil
+	gb.Cpu.RemoveObserver(run.profiler)
+	gb.Cpu.TrackCalls(gb.debugTracksCalls)
+
+	f, err := os.Create(run.path)
+	if err != nil {
+		return err
+	}
+	err = run.profiler.WriteProfile(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing profile %s: %w", run.path, err)
+	}
+
+	log.Printf("[Core] Profiled %d instructions (%d cycles) to %s", run.profiler.Instructions(), run.profiler.Cycles(), run.path)
+	return nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProfile verifies that a profile is written as gzipped protobuf
+func TestProfile(t *testing.T) {
+	// CALL $0105; JR -5; RET
+	gb := newTestCore(t, []byte{0xCD, 0x05, 0x01, 0x18, 0xFB, 0xC9})
+
+	path := filepath.Join(t.TempDir(), "cpu.pb.gz")
+	if err := gb.StartProfile(path); err != nil {
+		t.Fatalf("StartProfile failed: %v", err)
+	}
+	if err := gb.StartProfile(path); err == nil {
+		t.Error("Expected an error starting a second profile")
+	}
+	for i := 0; i < 10; i++ {
+		gb.StepInstruction()
+	}
+	if err := gb.StopProfile(); err != nil {
+		t.Fatalf("StopProfile failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open profile: %v", err)
+	}
+	defer f.Close()
+	if _, err := gzip.NewReader(f); err != nil {
+		t.Errorf("Expected a gzipped profile, got %v", err)
+	}
+
+	// Stopping again is a no-op
+	if err := gb.StopProfile(); err != nil {
+		t.Errorf("Expected second stop to succeed, got %v", err)
+	}
+}
+
+// TestProfileCallTracking verifies that calls are only tracked after a
+// profile while a debugger needs them
+func TestProfileCallTracking(t *testing.T) {
+	// CALL $0105; JR -5; RET
+	gb := newTestCore(t, []byte{0xCD, 0x05, 0x01, 0x18, 0xFB, 0xC9})
+	dir := t.TempDir()
+
+	if err := gb.StartProfile(filepath.Join(dir, "cpu.pb.gz")); err != nil {
+		t.Fatalf("StartProfile failed: %v", err)
+	}
+	if err := gb.StopProfile(); err != nil {
+		t.Fatalf("StopProfile failed: %v", err)
+	}
+	gb.StepInstruction()
+	if stack := gb.CallStack(); len(stack) != 0 {
+		t.Errorf("Expected calls not to be tracked after the profile, got %v", stack)
+	}
+
+	// RET; JR -5; CALL $0105
+	gb.TrackCalls(true)
+	if err := gb.StartProfile(filepath.Join(dir, "cpu.pb.gz")); err != nil {
+		t.Fatalf("StartProfile failed: %v", err)
+	}
+	if err := gb.StopProfile(); err != nil {
+		t.Fatalf("StopProfile failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		gb.StepInstruction()
+	}
+	if stack := gb.CallStack(); len(stack) != 1 {
+		t.Errorf("Expected calls to stay tracked for the debugger, got %v", stack)
+	}
+}
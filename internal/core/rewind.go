@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"log"
+
+	"github.com/briancain/gameboy-go/internal/rewind"
+)
+
+// rewindState tracks periodic state captures for Rewind
+type rewindState struct {
+	buffer *rewind.Buffer
+
+	// Frames between captures
+	interval int
+
+	// Frames emulated since the last capture
+	frames int
+
+	// Requested frames not yet rewound because they were less than one
+	// capture interval
+	pending int
+
+	// Scratch buffer reused for each capture
+	scratch bytes.Buffer
+}
+
+// EnableRewind starts capturing the emulator state every interval frames,
+// keeping up to capacity captures. Rewinding can go back at most
+// interval*capacity frames.
+func (gb *GameBoyCore) EnableRewind(interval, capacity int) {
+	if interval < 1 {
+		interval = 1
+	}
+
+	gb.rewind = &rewindState{
+		buffer:   rewind.NewBuffer(capacity),
+		interval: interval,
+	}
+
+	log.Printf("[Core] Rewind enabled: capture every %d frames, %d frames of history", interval, interval*capacity)
+}
+
+// DisableRewind stops capturing states and discards the rewind history
+func (gb *GameBoyCore) DisableRewind() {
+	gb.rewind = nil
+}
+
+// RewindEnabled returns whether rewind captures are being recorded
+func (gb *GameBoyCore) RewindEnabled() bool {
+	return gb.rewind != nil
+}
+
+// Rewind steps the emulator back by the given number of frames, rounded to
+// the capture interval. Requests smaller than one interval accumulate, so
+// calling Rewind(1) once per displayed frame rewinds in real time.
+func (gb *GameBoyCore) Rewind(frames int) error {
+	rw := gb.rewind
+	if rw == nil {
+		return errors.New("rewind is not enabled")
+	}
+
+	rw.pending += frames
+	steps := rw.pending / rw.interval
+	rw.pending %= rw.interval
+	if steps == 0 {
+		return nil
+	}
+
+	// If we have run past the newest capture, returning to it is the first step
+	var state []byte
+	if rw.frames > 0 {
+		state = rw.buffer.Latest()
+		steps--
+	}
+
+	for ; steps > 0; steps-- {
+		prev, err := rw.buffer.Pop()
+		if err == rewind.ErrEmpty {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		state = prev
+	}
+
+	if state == nil {
+		return rewind.ErrEmpty
+	}
+
+	if err := gb.LoadState(bytes.NewReader(state)); err != nil {
+		return err
+	}
+	rw.frames = 0
+	gb.frameCycles = 0
+
+	return nil
+}
+
+// captureRewind records the current state if a capture is due
+func (gb *GameBoyCore) captureRewind() {
+	rw := gb.rewind
+
+	rw.frames++
+	if rw.frames < rw.interval && rw.buffer.Latest() != nil {
+		return
+	}
+	rw.frames = 0
+
+	rw.scratch.Reset()
+	if err := gb.SaveState(&rw.scratch); err != nil {
+		log.Printf("[Core] Rewind capture failed: %v", err)
+		return
+	}
+	rw.buffer.Push(rw.scratch.Bytes())
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+)
+
+// TestRewind verifies that rewinding restores an earlier frame
+func TestRewind(t *testing.T) {
+	// INC A; LD (C000),A; JR -6 (loop forever incrementing WRAM)
+	program := []byte{0x3C, 0xEA, 0x00, 0xC0, 0x18, 0xFA}
+	gb := newTestCore(t, program)
+	gb.EnableRewind(2, 10)
+
+	// The first frame captures the starting point
+	gb.Step()
+	start := gb.Mmu.ReadByte(0xC000)
+
+	for i := 0; i < 6; i++ {
+		gb.Step()
+	}
+	if gb.Mmu.ReadByte(0xC000) == start {
+		t.Fatalf("Expected program to change WRAM")
+	}
+
+	// Six frames back lands on the first capture
+	if err := gb.Rewind(6); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if got := gb.Mmu.ReadByte(0xC000); got != start {
+		t.Errorf("Expected WRAM %02X after rewind, got %02X", start, got)
+	}
+
+	if err := gb.Rewind(2); err == nil {
+		t.Errorf("Expected error rewinding past the oldest capture")
+	}
+}
+
+// TestRewindAccumulates verifies that single frame requests add up
+func TestRewindAccumulates(t *testing.T) {
+	program := []byte{0x3C, 0xEA, 0x00, 0xC0, 0x18, 0xFA}
+	gb := newTestCore(t, program)
+	gb.EnableRewind(3, 10)
+
+	gb.Step()
+	start := gb.Mmu.ReadByte(0xC000)
+	for i := 0; i < 3; i++ {
+		gb.Step()
+	}
+
+	gb.Rewind(1)
+	gb.Rewind(1)
+	if gb.Mmu.ReadByte(0xC000) == start {
+		t.Errorf("Expected no rewind before a full interval was requested")
+	}
+	gb.Rewind(1)
+	if got := gb.Mmu.ReadByte(0xC000); got != start {
+		t.Errorf("Expected WRAM %02X after rewind, got %02X", start, got)
+	}
+}
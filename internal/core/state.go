@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
+)
+
+// SaveState writes the complete emulator state to w in the versioned binary
+// state format (see snapshot.StateVersion)
+func (gb *GameBoyCore) SaveState(w io.Writer) error {
+	if gb.Cartridge == nil {
+		return errors.New("no cartridge loaded")
+	}
+
+	sw := snapshot.NewStateWriter(w)
+	sw.WriteHeader()
+
+	// Identify the cartridge so states are not loaded into the wrong game
+	sw.Section("CORE")
+	sw.String(gb.Cartridge.GetTitle())
+	sw.Uint16(gb.Cartridge.GetHeaderChecksum())
+
+	gb.Cpu.SaveState(sw)
+	gb.Mmu.SaveState(sw)
+	gb.Ppu.SaveState(sw)
+	gb.Timer.SaveState(sw)
+	gb.Serial.SaveState(sw)
+	gb.Sound.SaveState(sw)
+	gb.Cartridge.SaveState(sw)
+
+	return sw.Err()
+}
+
+// LoadState restores emulator state previously written by SaveState. If the
+// state is invalid or belongs to a different cartridge, the current state is
+// left untouched and an error is returned.
+func (gb *GameBoyCore) LoadState(r io.Reader) error {
+	if gb.Cartridge == nil {
+		return errors.New("no cartridge loaded")
+	}
+
+	// Keep a copy of the current state so a failed load can be rolled back
+	var backup bytes.Buffer
+	if err := gb.SaveState(&backup); err != nil {
+		return err
+	}
+
+	if err := gb.loadState(r); err != nil {
+		if rollbackErr := gb.loadState(&backup); rollbackErr != nil {
+			return fmt.Errorf("%v (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (gb *GameBoyCore) loadState(r io.Reader) error {
+	sr := snapshot.NewStateReader(r)
+	sr.ReadHeader()
+
+	sr.Section("CORE")
+	title := sr.String()
+	checksum := sr.Uint16()
+	if sr.Err() != nil {
+		return sr.Err()
+	}
+	if title != gb.Cartridge.GetTitle() || checksum != gb.Cartridge.GetHeaderChecksum() {
+		return fmt.Errorf("save state is for %q (checksum %04X), loaded cartridge is %q (checksum %04X)",
+			strings.TrimRight(title, "\x00"), checksum,
+			strings.TrimRight(gb.Cartridge.GetTitle(), "\x00"), gb.Cartridge.GetHeaderChecksum())
+	}
+
+	gb.Cpu.LoadState(sr)
+	gb.Mmu.LoadState(sr)
+	gb.Ppu.LoadState(sr)
+	gb.Timer.LoadState(sr)
+	gb.Serial.LoadState(sr)
+	gb.Sound.LoadState(sr)
+	gb.Cartridge.LoadState(sr)
+
+	return sr.Err()
+}
+
+// SaveStateFile writes the emulator state to the file at path
+func (gb *GameBoyCore) SaveStateFile(path string) error {
+	var buf bytes.Buffer
+	if err := gb.SaveState(&buf); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	log.Printf("[Core] Saved state to %s", path)
+	return nil
+}
+
+// LoadStateFile restores the emulator state from the file at path
+func (gb *GameBoyCore) LoadStateFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := gb.LoadState(f); err != nil {
+		return fmt.Errorf("loading state from %s: %w", path, err)
+	}
+
+	log.Printf("[Core] Loaded state from %s", path)
+	return nil
+}
+
+// QuickStatePath returns the default save state path for the loaded cartridge
+func (gb *GameBoyCore) QuickStatePath() string {
+	dir := gb.batterySaveDir
+	if dir == "" {
+		dir = "."
+	}
+
+	name := "gameboy"
+	if gb.Cartridge != nil {
+		if title := sanitizeTitle(gb.Cartridge.GetTitle()); title != "" {
+			name = title
+		}
+	}
+
+	return filepath.Join(dir, name+".state")
+}
+
+// QuickSave saves the emulator state to QuickStatePath
+func (gb *GameBoyCore) QuickSave() error {
+	return gb.SaveStateFile(gb.QuickStatePath())
+}
+
+// QuickLoad restores the emulator state from QuickStatePath
+func (gb *GameBoyCore) QuickLoad() error {
+	return gb.LoadStateFile(gb.QuickStatePath())
+}
+
+// sanitizeTitle turns a cartridge title into a safe file name
+func sanitizeTitle(title string) string {
+	title = strings.TrimRight(title, "\x00 ")
+	var b strings.Builder
+	for _, r := range title {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
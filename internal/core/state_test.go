@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestROM writes a 32KB ROM-only cartridge with the given program at 0x0100
+func writeTestROM(t *testing.T, title string, program []byte) string {
+	t.Helper()
+
+	rom := make([]byte, 32*1024)
+	copy(rom[0x100:], program)
+	copy(rom[0x134:0x143], title)
+	rom[0x147] = 0x00 // ROM ONLY
+	rom[0x148] = 0x00 // 32KB
+	rom[0x149] = 0x00 // No RAM
+
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatalf("Failed to write test ROM: %v", err)
+	}
+	return path
+}
+
+// newTestCore creates a core with the given program loaded
+func newTestCore(t *testing.T, program []byte) *GameBoyCore {
+	t.Helper()
+
+	gb, err := NewGameBoyCore(false)
+	if err != nil {
+		t.Fatalf("Failed to create core: %v", err)
+	}
+	gb.SetSaveDirectory(t.TempDir())
+	if err := gb.Init(writeTestROM(t, "STATETEST", program)); err != nil {
+		t.Fatalf("Failed to initialize core: %v", err)
+	}
+	return gb
+}
+
+// TestSaveLoadStateRoundTrip verifies that loading a state restores the exact machine state
+func TestSaveLoadStateRoundTrip(t *testing.T) {
+	// INC A; LD (C000),A; JR -6 (loop forever incrementing WRAM)
+	program := []byte{0x3C, 0xEA, 0x00, 0xC0, 0x18, 0xFA}
+	gb := newTestCore(t, program)
+
+	for i := 0; i < 10; i++ {
+		gb.StepInstruction()
+	}
+
+	var saved bytes.Buffer
+	if err := gb.SaveState(&saved); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	pc := gb.Cpu.GetRegisters().PC
+	wram := gb.Mmu.ReadByte(0xC000)
+
+	// Keep running so the state diverges
+	for i := 0; i < 30; i++ {
+		gb.StepInstruction()
+	}
+	if gb.Mmu.ReadByte(0xC000) == wram {
+		t.Fatal("Expected WRAM to change after running more instructions")
+	}
+
+	if err := gb.LoadState(bytes.NewReader(saved.Bytes())); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if gb.Cpu.GetRegisters().PC != pc {
+		t.Errorf("Expected PC %04X after load, got %04X", pc, gb.Cpu.GetRegisters().PC)
+	}
+	if gb.Mmu.ReadByte(0xC000) != wram {
+		t.Errorf("Expected WRAM %02X after load, got %02X", wram, gb.Mmu.ReadByte(0xC000))
+	}
+
+	// Saving again must produce an identical state
+	var again bytes.Buffer
+	if err := gb.SaveState(&again); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	if !bytes.Equal(saved.Bytes(), again.Bytes()) {
+		t.Error("Expected re-saved state to match the loaded state")
+	}
+}
+
+// TestLoadStateRejectsInvalidData verifies that a bad state leaves the emulator untouched
+func TestLoadStateRejectsInvalidData(t *testing.T) {
+	gb := newTestCore(t, []byte{0x3C, 0x18, 0xFD})
+	for i := 0; i < 5; i++ {
+		gb.StepInstruction()
+	}
+
+	var before bytes.Buffer
+	gb.SaveState(&before)
+
+	// Truncate a valid state
+	truncated := before.Bytes()[:before.Len()/2]
+	if err := gb.LoadState(bytes.NewReader(truncated)); err == nil {
+		t.Fatal("Expected error loading a truncated state")
+	}
+
+	var after bytes.Buffer
+	gb.SaveState(&after)
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Error("Expected failed load to leave the emulator state unchanged")
+	}
+}
+
+// TestLoadStateRejectsOtherCartridge verifies that states are tied to the cartridge
+func TestLoadStateRejectsOtherCartridge(t *testing.T) {
+	gb := newTestCore(t, []byte{0x00})
+
+	var saved bytes.Buffer
+	gb.SaveState(&saved)
+
+	other, _ := NewGameBoyCore(false)
+	other.SetSaveDirectory(t.TempDir())
+	if err := other.Init(writeTestROM(t, "OTHERGAME", []byte{0x00})); err != nil {
+		t.Fatalf("Failed to initialize core: %v", err)
+	}
+	if err := other.LoadState(bytes.NewReader(saved.Bytes())); err == nil {
+		t.Error("Expected error loading a state from a different cartridge")
+	}
+}
+
+// TestQuickSaveLoad verifies the file based quick save slot
+func TestQuickSaveLoad(t *testing.T) {
+	gb := newTestCore(t, []byte{0x3C, 0x18, 0xFD})
+	gb.StepInstruction()
+	a := gb.Cpu.GetRegisters().A
+
+	if err := gb.QuickSave(); err != nil {
+		t.Fatalf("QuickSave failed: %v", err)
+	}
+	if _, err := os.Stat(gb.QuickStatePath()); err != nil {
+		t.Fatalf("Expected state file at %s: %v", gb.QuickStatePath(), err)
+	}
+
+	gb.StepInstruction()
+	gb.StepInstruction()
+
+	if err := gb.QuickLoad(); err != nil {
+		t.Fatalf("QuickLoad failed: %v", err)
+	}
+	if gb.Cpu.GetRegisters().A != a {
+		t.Errorf("Expected A %02X after quick load, got %02X", a, gb.Cpu.GetRegisters().A)
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/trace"
+)
+
+// traceLog is a file receiving a Gameboy Doctor trace
+type traceLog struct {
+	file   *os.File
+	writer *trace.DoctorWriter
+}
+
+// StartTrace logs the CPU state before every instruction to path in the
+// Gameboy Doctor format. With stubLY set, LY always reads 0x90 as Gameboy
+// Doctor's reference logs expect. With labels set, each line names PC by
+// the ROM's symbols in a comment, see trace.DoctorWriter.SetLabels.
+func (gb *GameBoyCore) StartTrace(path string, stubLY, labels bool) error {
+	if gb.traceLog != nil {
+		return errors.New("trace already in progress")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := trace.NewDoctorWriter(f, gb.Mmu)
+	if labels {
+		writer.SetLabels(gb.LabelAt)
+	}
+	gb.traceLog = &traceLog{file: f, writer: writer}
+	gb.Cpu.AddObserver(writer)
+	gb.Mmu.SetLYStub(stubLY)
+
+	log.Printf("[Core] Tracing instructions to %s", path)
+	return nil
+}
+
+// StopTrace finishes the trace and closes the file. It does nothing if no
+// trace is in progress.
+func (gb *GameBoyCore) StopTrace() error {
+	tl := gb.traceLog
+	if tl == nil {
+		return nil
+	}
+	gb.traceLog = nil
+	gb.Cpu.RemoveObserver(tl.writer)
+	gb.Mmu.SetLYStub(false)
+
+	err := tl.writer.Flush()
+	if closeErr := tl.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("finishing trace %s: %w", tl.file.Name(), err)
+	}
+
+	log.Printf("[Core] Traced %d instructions to %s", tl.writer.Lines(), tl.file.Name())
+	return nil
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTrace verifies that a trace logs one Gameboy Doctor line per instruction
+func TestTrace(t *testing.T) {
+	// LDH A,($44); JR -4
+	gb := newTestCore(t, []byte{0xF0, 0x44, 0x18, 0xFC})
+
+	path := filepath.Join(t.TempDir(), "trace.log")
+	if err := gb.StartTrace(path, true, false); err != nil {
+		t.Fatalf("StartTrace failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		gb.StepInstruction()
+	}
+	if err := gb.StopTrace(); err != nil {
+		t.Fatalf("StopTrace failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[0], "PC:0100 PCMEM:F0,44,18,FC") {
+		t.Errorf("Unexpected first line %q", lines[0])
+	}
+
+	// LY was stubbed while tracing
+	if !strings.HasPrefix(lines[1], "A:90 ") {
+		t.Errorf("Expected A to be loaded with the stubbed LY, got %q", lines[1])
+	}
+
+	// Stopping again is a no-op
+	if err := gb.StopTrace(); err != nil {
+		t.Errorf("Expected second stop to succeed, got %v", err)
+	}
+}
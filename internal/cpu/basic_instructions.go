@@ -17,8 +17,8 @@ func (cpu *Z80) NOP() int {
 // 0x76: HALT - Halt the CPU until an interrupt occurs
 func (cpu *Z80) HALT() int {
 	// Check for HALT bug: If IME=0 and IE & IF != 0, the HALT bug occurs
-	interruptFlag := cpu.mmu.ReadByte(0xFF0F) & 0x1F
-	interruptEnable := cpu.mmu.ReadByte(0xFFFF) & 0x1F
+	interruptFlag := cpu.peek(0xFF0F) & 0x1F
+	interruptEnable := cpu.peek(0xFFFF) & 0x1F
 
 	if !cpu.interruptMaster && (interruptFlag&interruptEnable) != 0 {
 		// HALT bug: When interrupts are disabled (IME=0) and there are pending interrupts (IE & IF != 0),
@@ -42,8 +42,9 @@ func (cpu *Z80) STOP() int {
 
 // 0xF3: DI - Disable interrupts
 func (cpu *Z80) DI() int {
-	// Interrupts are disabled after the next instruction
-	cpu.interruptDisableScheduled = true
+	// Unlike EI, DI takes effect immediately
+	cpu.interruptMaster = false
+	cpu.interruptEnableScheduled = false
 	return 4
 }
 
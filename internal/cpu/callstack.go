@@ -0,0 +1,74 @@
+package cpu
+
+// CallFrame is a subroutine or interrupt handler that has not returned yet
+type CallFrame struct {
+	// Address of the CALL or RST, or of the instruction an interrupt
+	// preempted
+	CallSite uint16
+
+	// Address that was called
+	Target uint16
+
+	// SP after the return address was pushed. The frame has returned once SP
+	// rises above it.
+	SP uint16
+
+	// Set when the frame was entered by interrupt dispatch
+	Interrupt bool
+}
+
+// TrackCalls turns call stack tracking on or off. Tracking starts with an
+// empty stack, so turn it on before the frames of interest are entered.
+// Turning it on again keeps the stack tracked so far.
+func (cpu *Z80) TrackCalls(enabled bool) {
+	if enabled != cpu.trackCalls {
+		cpu.callStack = cpu.callStack[:0]
+	}
+	cpu.trackCalls = enabled
+}
+
+// CallStack returns the tracked call stack, outermost frame first. It is
+// empty unless TrackCalls is on.
+func (cpu *Z80) CallStack() []CallFrame {
+	return cpu.AppendCallStack(nil)
+}
+
+// AppendCallStack appends the tracked call stack to frames, outermost frame
+// first, to avoid allocating on every instruction
+func (cpu *Z80) AppendCallStack(frames []CallFrame) []CallFrame {
+	return append(frames, cpu.callStack...)
+}
+
+// isCallOpcode reports whether opcode is a CALL or RST
+func isCallOpcode(opcode byte) bool {
+	switch opcode {
+	case 0xCD, 0xC4, 0xCC, 0xD4, 0xDC,
+		0xC7, 0xCF, 0xD7, 0xDF, 0xE7, 0xEF, 0xF7, 0xFF:
+		return true
+	}
+	return false
+}
+
+// trackCall updates the call stack after the instruction at pc ran with SP
+// at sp. A CALL or RST that pushed a return address enters a frame, and any
+// frame whose return address has been popped, whether by RET or by code
+// adjusting the stack itself, has returned.
+func (cpu *Z80) trackCall(opcode byte, pc, sp uint16) {
+	cpu.unwindCalls()
+	if isCallOpcode(opcode) && cpu.reg.SP == sp-2 {
+		cpu.callStack = append(cpu.callStack, CallFrame{CallSite: pc, Target: cpu.reg.PC, SP: cpu.reg.SP})
+	}
+}
+
+// trackInterrupt enters a frame for an interrupt handler that preempted pc
+func (cpu *Z80) trackInterrupt(pc uint16) {
+	cpu.unwindCalls()
+	cpu.callStack = append(cpu.callStack, CallFrame{CallSite: pc, Target: cpu.reg.PC, SP: cpu.reg.SP, Interrupt: true})
+}
+
+// unwindCalls drops frames that have returned
+func (cpu *Z80) unwindCalls() {
+	for n := len(cpu.callStack); n > 0 && cpu.callStack[n-1].SP < cpu.reg.SP; n-- {
+		cpu.callStack = cpu.callStack[:n-1]
+	}
+}
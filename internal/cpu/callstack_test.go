@@ -0,0 +1,82 @@
+package cpu
+
+import (
+	"testing"
+)
+
+// TestCallStack tests tracking CALL, RST, RET and interrupt frames
+func TestCallStack(t *testing.T) {
+	mockMMU := &MockMMU{}
+	cpu, _ := NewCPU(mockMMU)
+	cpu.TrackCalls(true)
+
+	// 0100: CALL $0200
+	// 0200: RST $08
+	// 0008: RET
+	// 0201: RET
+	copy(mockMMU.memory[0x0100:], []byte{0xCD, 0x00, 0x02})
+	copy(mockMMU.memory[0x0200:], []byte{0xCF, 0xC9})
+	mockMMU.memory[0x0008] = 0xC9
+
+	expectDepth := func(step string, depth int) {
+		t.Helper()
+		if stack := cpu.CallStack(); len(stack) != depth {
+			t.Errorf("Expected %d frames after %s, got %+v", depth, step, stack)
+		}
+	}
+
+	cpu.Step()
+	expectDepth("CALL", 1)
+	cpu.Step()
+	expectDepth("RST", 2)
+	if frame := cpu.CallStack()[1]; frame.CallSite != 0x0200 || frame.Target != 0x0008 || frame.SP != 0xFFFA {
+		t.Errorf("Expected RST frame from 0200 to 0008 with SP FFFA, got %+v", frame)
+	}
+
+	// An interrupt preempts the RST handler
+	cpu.interruptMaster = true
+	mockMMU.WriteByte(0xFFFF, INT_VBLANK)
+	mockMMU.WriteByte(0xFF0F, INT_VBLANK)
+	mockMMU.memory[VBLANK_VECTOR] = 0xD9 // RETI
+	cpu.Step()
+	expectDepth("interrupt", 3)
+	if frame := cpu.CallStack()[2]; !frame.Interrupt || frame.CallSite != 0x0008 || frame.Target != VBLANK_VECTOR {
+		t.Errorf("Expected interrupt frame from 0008 to 0040, got %+v", frame)
+	}
+
+	for depth := 2; depth >= 0; depth-- {
+		cpu.Step()
+		expectDepth("a return", depth)
+	}
+	if cpu.reg.PC != 0x0103 {
+		t.Errorf("Expected PC to be 0x0103 after returning, got %04X", cpu.reg.PC)
+	}
+}
+
+// TestCallStackUnwind tests that frames end when code pops their return
+// address itself
+func TestCallStackUnwind(t *testing.T) {
+	mockMMU := &MockMMU{}
+	cpu, _ := NewCPU(mockMMU)
+	cpu.TrackCalls(true)
+
+	// 0100: CALL $0200
+	// 0200: POP HL
+	copy(mockMMU.memory[0x0100:], []byte{0xCD, 0x00, 0x02})
+	mockMMU.memory[0x0200] = 0xE1
+
+	cpu.Step()
+	cpu.Step()
+	if stack := cpu.CallStack(); len(stack) != 0 {
+		t.Errorf("Expected no frames after popping the return address, got %+v", stack)
+	}
+
+	// A conditional CALL that is not taken enters no frame
+	cpu.reg.PC = 0x0300
+	cpu.reg.F = 0
+	copy(mockMMU.memory[0x0300:], []byte{0xCC, 0x00, 0x02}) // CALL Z,$0200
+	cpu.Step()
+	if stack := cpu.CallStack(); len(stack) != 0 {
+		t.Errorf("Expected no frames after an untaken CALL, got %+v", stack)
+	}
+}
@@ -1,5 +1,7 @@
 package cpu
 
+import "github.com/briancain/gameboy-go/internal/snapshot"
+
 // CPU flags
 const (
 	FLAG_Z byte = 0x80 // Zero flag
@@ -22,9 +24,8 @@ type Z80 struct {
 	// Interrupt master enable flag
 	interruptMaster bool
 
-	// Interrupt scheduling flags
-	interruptEnableScheduled  bool
-	interruptDisableScheduled bool
+	// Interrupt scheduling flag
+	interruptEnableScheduled bool
 
 	// Pending interrupts
 	pendingInterrupts byte
@@ -33,6 +34,29 @@ type Z80 struct {
 	halted  bool
 	stopped bool
 	haltBug bool
+	locked  bool // Locked up by an illegal opcode until reset
+
+	// Notified before each instruction and after each step, see
+	// AddObserver
+	observers     []InstructionObserver
+	stepObservers []StepObserver
+
+	// The MMU, if it watches for execution or can be accessed without
+	// triggering watchpoints
+	executeWatcher ExecuteWatcher
+	peeker         Peeker
+
+	// Subroutine and interrupt frames, see TrackCalls
+	trackCalls bool
+	callStack  []CallFrame
+
+	// Ring buffer of the last instructions executed, see History
+	history     []HistoryEntry
+	historyNext int
+	historyLen  int
+
+	// Called when an instruction crashes the program, see SetFaultHandler
+	faultHandler func(Fault)
 }
 
 // Registers represents the CPU registers
@@ -116,9 +140,29 @@ func (r *Registers) ClearFlag(flag byte) {
 	r.F &= ^flag
 }
 
+// peek reads memory for the CPU's own use, without triggering watchpoints
+func (cpu *Z80) peek(addr uint16) byte {
+	if cpu.peeker != nil {
+		return cpu.peeker.Peek(addr)
+	}
+	return cpu.mmu.ReadByte(addr)
+}
+
+// poke writes memory for the CPU's own use, without triggering watchpoints
+func (cpu *Z80) poke(addr uint16, value byte) {
+	if cpu.peeker != nil {
+		cpu.peeker.Poke(addr, value)
+		return
+	}
+	cpu.mmu.WriteByte(addr, value)
+}
+
 // NewCPU creates a new Z80 CPU
 func NewCPU(mmu MMU) (*Z80, error) {
 	cpu := &Z80{mmu: mmu}
+	cpu.executeWatcher, _ = mmu.(ExecuteWatcher)
+	cpu.peeker, _ = mmu.(Peeker)
+	cpu.SetHistorySize(HISTORY_SIZE)
 	cpu.ResetCPU()
 
 	return cpu, nil
@@ -126,17 +170,30 @@ func NewCPU(mmu MMU) (*Z80, error) {
 
 // Step executes one instruction and returns the number of cycles taken
 func (cpu *Z80) Step() int {
-	// Save the interrupt enable/disable scheduled flags
+	cycles := cpu.step()
+	for _, observer := range cpu.stepObservers {
+		observer.AfterStep(cpu, cycles)
+	}
+	return cycles
+}
+
+// step executes one instruction, dispatches an interrupt or waits one
+// machine cycle while halted
+func (cpu *Z80) step() int {
+	// A locked up CPU does nothing, not even take interrupts
+	if cpu.locked {
+		return 4
+	}
+
+	// Save the interrupt enable scheduled flag
 	interruptEnableScheduled := cpu.interruptEnableScheduled
-	interruptDisableScheduled := cpu.interruptDisableScheduled
 
-	// Clear the scheduled flags
+	// Clear the scheduled flag
 	cpu.interruptEnableScheduled = false
-	cpu.interruptDisableScheduled = false
 
 	// Update pending interrupts
-	interruptFlag := cpu.mmu.ReadByte(0xFF0F)
-	interruptEnable := cpu.mmu.ReadByte(0xFFFF)
+	interruptFlag := cpu.peek(0xFF0F)
+	interruptEnable := cpu.peek(0xFFFF)
 	cpu.pendingInterrupts = interruptFlag & interruptEnable & 0x1F
 
 	// Handle interrupts
@@ -144,7 +201,11 @@ func (cpu *Z80) Step() int {
 		cpu.halted = false
 		cpu.stopped = false
 		// Process interrupts
+		pc := cpu.reg.PC
 		cpu.handleInterrupts()
+		if cpu.trackCalls {
+			cpu.trackInterrupt(pc)
+		}
 
 		// Return cycles for interrupt handling (5 machine cycles)
 		return 20
@@ -158,8 +219,17 @@ func (cpu *Z80) Step() int {
 		return 4
 	}
 
+	if cpu.executeWatcher != nil {
+		cpu.executeWatcher.WatchExecute(cpu.reg.PC)
+	}
+	for _, observer := range cpu.observers {
+		observer.BeforeInstruction(cpu)
+	}
+
 	// Fetch opcode
-	opcode := cpu.mmu.ReadByte(cpu.reg.PC)
+	pc, sp := cpu.reg.PC, cpu.reg.SP
+	opcode := cpu.mmu.ReadByte(pc)
+	cpu.recordHistory(pc, opcode)
 
 	// Handle HALT bug
 	// According to the manual, when the HALT bug occurs, the PC doesn't increment
@@ -173,14 +243,21 @@ func (cpu *Z80) Step() int {
 
 	// Execute instruction
 	cycles := cpu.executeInstruction(opcode)
+	if cpu.locked {
+		// Leave PC at the illegal opcode so debuggers show what happened
+		cpu.reg.PC = pc
+	}
+	if cpu.trackCalls {
+		cpu.trackCall(opcode, pc, sp)
+	}
+	if cpu.faultHandler != nil {
+		cpu.checkFault(opcode, pc, sp)
+	}
 
-	// Handle delayed interrupt enable/disable
+	// Handle delayed interrupt enable
 	if interruptEnableScheduled {
 		cpu.interruptMaster = true
 	}
-	if interruptDisableScheduled {
-		cpu.interruptMaster = false
-	}
 
 	// Update clock
 	cpu.clock.t += cycles
@@ -210,13 +287,32 @@ func (cpu *Z80) ResetCPU() {
 	// Initialize interrupt state
 	cpu.interruptMaster = false
 	cpu.interruptEnableScheduled = false
-	cpu.interruptDisableScheduled = false
 	cpu.pendingInterrupts = 0
 
 	// Initialize CPU state
 	cpu.halted = false
 	cpu.stopped = false
 	cpu.haltBug = false
+	cpu.locked = false
+	cpu.callStack = cpu.callStack[:0]
+	cpu.historyNext = 0
+	cpu.historyLen = 0
+}
+
+// Halted reports whether the CPU is waiting in HALT or STOP, or has locked
+// up
+func (cpu *Z80) Halted() bool {
+	return cpu.halted || cpu.stopped || cpu.locked
+}
+
+// GetRegisters returns a copy of the CPU registers
+func (cpu *Z80) GetRegisters() Registers {
+	return cpu.reg
+}
+
+// SetRegisters replaces the CPU registers
+func (cpu *Z80) SetRegisters(reg Registers) {
+	cpu.reg = reg
 }
 
 // ResetClock resets the CPU clock
@@ -237,4 +333,58 @@ func (cpu *Z80) popWord() uint16 {
 	return value
 }
 
+// SaveState writes the CPU registers, clock and interrupt/halt state
+func (cpu *Z80) SaveState(w *snapshot.StateWriter) {
+	w.Section("CPU ")
+	w.Uint8(cpu.reg.A)
+	w.Uint8(cpu.reg.F)
+	w.Uint8(cpu.reg.B)
+	w.Uint8(cpu.reg.C)
+	w.Uint8(cpu.reg.D)
+	w.Uint8(cpu.reg.E)
+	w.Uint8(cpu.reg.H)
+	w.Uint8(cpu.reg.L)
+	w.Uint16(cpu.reg.PC)
+	w.Uint16(cpu.reg.SP)
+
+	w.Int(cpu.clock.m)
+	w.Int(cpu.clock.t)
+
+	w.Bool(cpu.interruptMaster)
+	w.Bool(cpu.interruptEnableScheduled)
+	w.Uint8(cpu.pendingInterrupts)
+
+	w.Bool(cpu.halted)
+	w.Bool(cpu.stopped)
+	w.Bool(cpu.haltBug)
+	w.Bool(cpu.locked)
+}
+
+// LoadState restores the CPU state written by SaveState
+func (cpu *Z80) LoadState(r *snapshot.StateReader) {
+	r.Section("CPU ")
+	cpu.reg.A = r.Uint8()
+	cpu.reg.F = r.Uint8()
+	cpu.reg.B = r.Uint8()
+	cpu.reg.C = r.Uint8()
+	cpu.reg.D = r.Uint8()
+	cpu.reg.E = r.Uint8()
+	cpu.reg.H = r.Uint8()
+	cpu.reg.L = r.Uint8()
+	cpu.reg.PC = r.Uint16()
+	cpu.reg.SP = r.Uint16()
+
+	cpu.clock.m = r.Int()
+	cpu.clock.t = r.Int()
+
+	cpu.interruptMaster = r.Bool()
+	cpu.interruptEnableScheduled = r.Bool()
+	cpu.pendingInterrupts = r.Uint8()
+
+	cpu.halted = r.Bool()
+	cpu.stopped = r.Bool()
+	cpu.haltBug = r.Bool()
+	cpu.locked = r.Bool()
+}
+
 // More instructions will be implemented here
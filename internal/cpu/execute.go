@@ -437,7 +437,7 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xD2: // JP NC,a16
 		return cpu.JP_NC_a16()
 	case 0xD3: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xD4: // CALL NC,a16
 		return cpu.CALL_NC_a16()
 	case 0xD5: // PUSH DE
@@ -453,11 +453,11 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xDA: // JP C,a16
 		return cpu.JP_C_a16()
 	case 0xDB: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xDC: // CALL C,a16
 		return cpu.CALL_C_a16()
 	case 0xDD: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xDE: // SBC A,d8
 		return cpu.SBC_A_d8()
 	case 0xDF: // RST 18H
@@ -469,9 +469,9 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xE2: // LD (C),A
 		return cpu.LD_C_mem_A()
 	case 0xE3: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xE4: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xE5: // PUSH HL
 		return cpu.PUSH_HL()
 	case 0xE6: // AND d8
@@ -485,11 +485,11 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xEA: // LD (a16),A
 		return cpu.LD_a16_A()
 	case 0xEB: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xEC: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xED: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xEE: // XOR d8
 		return cpu.XOR_d8()
 	case 0xEF: // RST 28H
@@ -503,7 +503,7 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xF3: // DI
 		return cpu.DI()
 	case 0xF4: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xF5: // PUSH AF
 		return cpu.PUSH_AF()
 	case 0xF6: // OR d8
@@ -519,9 +519,9 @@ func (cpu *Z80) executeInstruction(opcode byte) int {
 	case 0xFB: // EI
 		return cpu.EI()
 	case 0xFC: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xFD: // Invalid opcode
-		return 4
+		return cpu.lockUp()
 	case 0xFE: // CP d8
 		return cpu.CP_d8()
 	case 0xFF: // RST 38H
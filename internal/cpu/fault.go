@@ -0,0 +1,91 @@
+package cpu
+
+import "fmt"
+
+// FaultKind is a way the program running on the CPU can crash
+type FaultKind int
+
+const (
+	FaultIllegalOpcode  FaultKind = iota // One of the opcodes the CPU does not implement, which locks it up
+	FaultStackUnderflow                  // Popping past the top of memory, wrapping SP into ROM
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultIllegalOpcode:
+		return "illegal opcode"
+	case FaultStackUnderflow:
+		return "stack underflow"
+	default:
+		return "unknown fault"
+	}
+}
+
+// Fault describes an instruction that crashed the program
+type Fault struct {
+	Kind FaultKind
+
+	// Address and first byte of the instruction
+	PC     uint16
+	Opcode byte
+
+	// SP before and after the instruction
+	SP     uint16
+	NextSP uint16
+}
+
+func (f Fault) String() string {
+	switch f.Kind {
+	case FaultIllegalOpcode:
+		return fmt.Sprintf("illegal opcode $%02X at $%04X", f.Opcode, f.PC)
+	case FaultStackUnderflow:
+		return fmt.Sprintf("stack underflow into ROM at $%04X, SP $%04X to $%04X", f.PC, f.SP, f.NextSP)
+	default:
+		return fmt.Sprintf("%s at $%04X", f.Kind, f.PC)
+	}
+}
+
+// SetFaultHandler sets a function to call after an instruction crashes the
+// program, nil for none. The CPU carries on as the hardware would.
+func (cpu *Z80) SetFaultHandler(handler func(Fault)) {
+	cpu.faultHandler = handler
+}
+
+// lockUp stops the CPU for good, as the illegal opcodes do on the hardware.
+// Only ResetCPU recovers it.
+func (cpu *Z80) lockUp() int {
+	cpu.locked = true
+	return 4
+}
+
+// Locked reports whether an illegal opcode has locked up the CPU. A locked
+// CPU stays at the illegal opcode and ignores interrupts until reset.
+func (cpu *Z80) Locked() bool {
+	return cpu.locked
+}
+
+// IsIllegalOpcode reports whether opcode is one of the 11 opcodes the CPU
+// does not implement
+func IsIllegalOpcode(opcode byte) bool {
+	switch opcode {
+	case 0xD3, 0xDB, 0xDD, 0xE3, 0xE4, 0xEB, 0xEC, 0xED, 0xF4, 0xFC, 0xFD:
+		return true
+	}
+	return false
+}
+
+// checkFault reports a fault if the instruction at pc crashed, given SP
+// before it ran
+func (cpu *Z80) checkFault(opcode byte, pc, sp uint16) {
+	fault := Fault{PC: pc, Opcode: opcode, SP: sp, NextSP: cpu.reg.SP}
+	switch {
+	case IsIllegalOpcode(opcode):
+		fault.Kind = FaultIllegalOpcode
+	case cpu.reg.SP-sp == 2 && cpu.reg.SP < sp:
+		// Popped the last word of memory, SP wrapped around to ROM
+		fault.Kind = FaultStackUnderflow
+	default:
+		return
+	}
+	cpu.faultHandler(fault)
+}
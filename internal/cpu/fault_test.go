@@ -0,0 +1,64 @@
+package cpu
+
+import (
+	"testing"
+)
+
+// TestFaults tests reporting illegal opcodes and stack underflow
+func TestFaults(t *testing.T) {
+	mockMMU := &MockMMU{}
+	cpu, _ := NewCPU(mockMMU)
+
+	var faults []Fault
+	cpu.SetFaultHandler(func(f Fault) { faults = append(faults, f) })
+
+	// 0100: POP BC; RET; illegal $D3 at 0000 after the return
+	copy(mockMMU.memory[0x0100:], []byte{0xC1, 0xC9})
+	cpu.reg.SP = 0xFFFC
+	cpu.Step()
+	if len(faults) != 0 {
+		t.Errorf("Expected no fault popping below the top of memory, got %v", faults)
+	}
+
+	cpu.Step()
+	if len(faults) != 1 || faults[0].Kind != FaultStackUnderflow || faults[0].PC != 0x0101 || faults[0].NextSP != 0x0000 {
+		t.Fatalf("Expected a stack underflow at 0101, got %v", faults)
+	}
+
+	mockMMU.memory[cpu.reg.PC] = 0xD3
+	cpu.Step()
+	if len(faults) != 2 || faults[1].Kind != FaultIllegalOpcode || faults[1].Opcode != 0xD3 {
+		t.Fatalf("Expected an illegal opcode, got %v", faults)
+	}
+	if s := faults[1].String(); s != "illegal opcode $D3 at $0000" {
+		t.Errorf("Expected the fault to be described, got %q", s)
+	}
+}
+
+// TestIllegalOpcodeLocksUp tests that every illegal opcode locks up the CPU
+// until reset, ignoring interrupts
+func TestIllegalOpcodeLocksUp(t *testing.T) {
+	for _, opcode := range []byte{0xD3, 0xDB, 0xDD, 0xE3, 0xE4, 0xEB, 0xEC, 0xED, 0xF4, 0xFC, 0xFD} {
+		mockMMU := &MockMMU{}
+		cpu, _ := NewCPU(mockMMU)
+		mockMMU.memory[0x0100] = opcode
+
+		cpu.Step()
+		if !cpu.Locked() || cpu.reg.PC != 0x0100 {
+			t.Errorf("Expected opcode %02X to lock up the CPU at 0100, got locked %v at %04X", opcode, cpu.Locked(), cpu.reg.PC)
+			continue
+		}
+
+		cpu.interruptMaster = true
+		mockMMU.WriteByte(0xFFFF, INT_VBLANK)
+		mockMMU.WriteByte(0xFF0F, INT_VBLANK)
+		if cycles := cpu.Step(); cycles != 4 || cpu.reg.PC != 0x0100 {
+			t.Errorf("Expected opcode %02X to ignore interrupts, got %d cycles and PC %04X", opcode, cycles, cpu.reg.PC)
+		}
+
+		cpu.ResetCPU()
+		if cpu.Locked() {
+			t.Errorf("Expected reset to recover from opcode %02X", opcode)
+		}
+	}
+}
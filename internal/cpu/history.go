@@ -0,0 +1,48 @@
+package cpu
+
+// HISTORY_SIZE is the number of instructions kept in the instruction history
+// by default
+const HISTORY_SIZE = 64
+
+// HistoryEntry is an executed instruction in the instruction history
+type HistoryEntry struct {
+	// Address and first byte of the instruction
+	PC     uint16
+	Opcode byte
+
+	// Registers before the instruction ran
+	Registers Registers
+}
+
+// SetHistorySize keeps the last n executed instructions for History,
+// discarding those kept so far. 0 turns the history off.
+func (cpu *Z80) SetHistorySize(n int) {
+	cpu.history = make([]HistoryEntry, n)
+	cpu.historyNext = 0
+	cpu.historyLen = 0
+}
+
+// History returns the last executed instructions, oldest first
+func (cpu *Z80) History() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, cpu.historyLen)
+	start := cpu.historyNext - cpu.historyLen
+	if start < 0 {
+		start += len(cpu.history)
+	}
+	for i := 0; i < cpu.historyLen; i++ {
+		entries = append(entries, cpu.history[(start+i)%len(cpu.history)])
+	}
+	return entries
+}
+
+// recordHistory adds the instruction about to run to the history
+func (cpu *Z80) recordHistory(pc uint16, opcode byte) {
+	if len(cpu.history) == 0 {
+		return
+	}
+	cpu.history[cpu.historyNext] = HistoryEntry{PC: pc, Opcode: opcode, Registers: cpu.reg}
+	cpu.historyNext = (cpu.historyNext + 1) % len(cpu.history)
+	if cpu.historyLen < len(cpu.history) {
+		cpu.historyLen++
+	}
+}
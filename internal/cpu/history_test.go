@@ -0,0 +1,46 @@
+package cpu
+
+import (
+	"testing"
+)
+
+// TestHistory tests keeping the last instructions in a ring buffer
+func TestHistory(t *testing.T) {
+	mockMMU := &MockMMU{}
+	cpu, _ := NewCPU(mockMMU)
+	cpu.SetHistorySize(3)
+
+	// 0100: INC A, five times
+	copy(mockMMU.memory[0x0100:], []byte{0x3C, 0x3C, 0x3C, 0x3C, 0x3C})
+	cpu.reg.A = 0
+
+	cpu.Step()
+	if history := cpu.History(); len(history) != 1 || history[0].PC != 0x0100 {
+		t.Errorf("Expected one instruction at 0100, got %+v", history)
+	}
+
+	for i := 0; i < 4; i++ {
+		cpu.Step()
+	}
+	history := cpu.History()
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 instructions, got %d", len(history))
+	}
+	for i, entry := range history {
+		pc := uint16(0x0102 + i)
+		if entry.PC != pc || entry.Opcode != 0x3C || entry.Registers.A != byte(2+i) {
+			t.Errorf("Expected INC A at %04X with A=%d before it, got %+v", pc, 2+i, entry)
+		}
+	}
+
+	cpu.ResetCPU()
+	if history := cpu.History(); len(history) != 0 {
+		t.Errorf("Expected no history after reset, got %+v", history)
+	}
+
+	cpu.SetHistorySize(0)
+	cpu.Step()
+	if history := cpu.History(); len(history) != 0 {
+		t.Errorf("Expected no history when turned off, got %+v", history)
+	}
+}
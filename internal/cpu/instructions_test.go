@@ -1257,8 +1257,8 @@ func TestSpecialInstructions(t *testing.T) {
 	// Test DI (disable interrupts)
 	cpu.interruptMaster = true
 	cycles = cpu.DI()
-	if !cpu.interruptDisableScheduled {
-		t.Error("DI: Expected interrupt disable to be scheduled")
+	if cpu.interruptMaster {
+		t.Error("DI: Expected interrupts to be disabled")
 	}
 	if cycles != 4 {
 		t.Errorf("DI: Expected 4 cycles, got %d", cycles)
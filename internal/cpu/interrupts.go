@@ -21,8 +21,8 @@ const (
 // Handle interrupts
 func (cpu *Z80) handleInterrupts() {
 	// Get interrupt flags (IF) and interrupt enable (IE)
-	interruptFlag := cpu.mmu.ReadByte(0xFF0F)
-	interruptEnable := cpu.mmu.ReadByte(0xFFFF)
+	interruptFlag := cpu.peek(0xFF0F)
+	interruptEnable := cpu.peek(0xFFFF)
 
 	// Calculate pending interrupts
 	pendingInterrupts := interruptFlag & interruptEnable & 0x1F
@@ -39,7 +39,7 @@ func (cpu *Z80) handleInterrupts() {
 	// Handle interrupts in priority order
 	if pendingInterrupts&INT_VBLANK != 0 {
 		// Clear the interrupt flag
-		cpu.mmu.WriteByte(0xFF0F, interruptFlag&(^byte(INT_VBLANK)))
+		cpu.poke(0xFF0F, interruptFlag&(^byte(INT_VBLANK)))
 		cpu.pendingInterrupts &= (^byte(INT_VBLANK))
 
 		// Call the interrupt handler
@@ -47,7 +47,7 @@ func (cpu *Z80) handleInterrupts() {
 
 	} else if pendingInterrupts&INT_LCDC != 0 {
 		// Clear the interrupt flag
-		cpu.mmu.WriteByte(0xFF0F, interruptFlag&(^byte(INT_LCDC)))
+		cpu.poke(0xFF0F, interruptFlag&(^byte(INT_LCDC)))
 		cpu.pendingInterrupts &= (^byte(INT_LCDC))
 
 		// Call the interrupt handler
@@ -55,7 +55,7 @@ func (cpu *Z80) handleInterrupts() {
 
 	} else if pendingInterrupts&INT_TIMER != 0 {
 		// Clear the interrupt flag
-		cpu.mmu.WriteByte(0xFF0F, interruptFlag&(^byte(INT_TIMER)))
+		cpu.poke(0xFF0F, interruptFlag&(^byte(INT_TIMER)))
 		cpu.pendingInterrupts &= (^byte(INT_TIMER))
 
 		// Call the interrupt handler
@@ -63,7 +63,7 @@ func (cpu *Z80) handleInterrupts() {
 
 	} else if pendingInterrupts&INT_SERIAL != 0 {
 		// Clear the interrupt flag
-		cpu.mmu.WriteByte(0xFF0F, interruptFlag&(^byte(INT_SERIAL)))
+		cpu.poke(0xFF0F, interruptFlag&(^byte(INT_SERIAL)))
 		cpu.pendingInterrupts &= (^byte(INT_SERIAL))
 
 		// Call the interrupt handler
@@ -71,7 +71,7 @@ func (cpu *Z80) handleInterrupts() {
 
 	} else if pendingInterrupts&INT_JOYPAD != 0 {
 		// Clear the interrupt flag
-		cpu.mmu.WriteByte(0xFF0F, interruptFlag&(^byte(INT_JOYPAD)))
+		cpu.poke(0xFF0F, interruptFlag&(^byte(INT_JOYPAD)))
 		cpu.pendingInterrupts &= (^byte(INT_JOYPAD))
 
 		// Call the interrupt handler
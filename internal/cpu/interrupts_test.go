@@ -146,18 +146,32 @@ func TestEIAndDIInstructions(t *testing.T) {
 	// Test DI instruction
 	cpu.interruptMaster = true
 	cpu.DI()
-	// DI should not disable interrupts immediately
-	if !cpu.interruptMaster {
-		t.Error("DI should not disable interrupts immediately")
-	}
-	if !cpu.interruptDisableScheduled {
-		t.Error("DI should schedule interrupt disable")
+	// DI should disable interrupts immediately
+	if cpu.interruptMaster {
+		t.Error("DI should disable interrupts immediately")
 	}
+}
+
+// TestNoInterruptAfterDI tests that an interrupt pending when DI runs is not
+// taken before the next instruction
+func TestNoInterruptAfterDI(t *testing.T) {
+	mockMMU := &MockMMU{}
+	cpu, _ := NewCPU(mockMMU)
+
+	cpu.reg.PC = 0xC000
+	mockMMU.WriteByte(0xC000, 0xF3) // DI
+	mockMMU.WriteByte(0xC001, 0x00) // NOP
+	mockMMU.WriteByte(0xFFFF, INT_VBLANK)
+	cpu.interruptMaster = true
 
-	// Execute a Step to trigger the delayed disable
 	cpu.Step()
-	if cpu.interruptMaster {
-		t.Error("Interrupts should be disabled after one instruction")
+	mockMMU.WriteByte(0xFF0F, INT_VBLANK)
+	cpu.Step()
+	if cpu.reg.PC != 0xC002 {
+		t.Errorf("Expected the NOP after DI to run, got PC %04X", cpu.reg.PC)
+	}
+	if mockMMU.ReadByte(0xFF0F)&INT_VBLANK == 0 {
+		t.Error("Expected the V-Blank interrupt to stay pending")
 	}
 }
 
@@ -0,0 +1,59 @@
+package cpu
+
+// InstructionObserver is notified before the CPU executes each instruction.
+// Interrupt dispatch and cycles spent halted are not instructions and are not
+// reported.
+type InstructionObserver interface {
+	// BeforeInstruction is called with PC pointing at the opcode about to be
+	// executed
+	BeforeInstruction(cpu *Z80)
+}
+
+// StepObserver is an InstructionObserver that is also told how many cycles
+// each Step took, including steps that dispatch an interrupt or wait while
+// halted
+type StepObserver interface {
+	InstructionObserver
+
+	// AfterStep is called at the end of every Step
+	AfterStep(cpu *Z80, cycles int)
+}
+
+// AddObserver adds an observer that is notified before every instruction,
+// and after every Step if it is a StepObserver
+func (cpu *Z80) AddObserver(observer InstructionObserver) {
+	cpu.observers = append(cpu.observers, observer)
+	if stepObserver, ok := observer.(StepObserver); ok {
+		cpu.stepObservers = append(cpu.stepObservers, stepObserver)
+	}
+}
+
+// RemoveObserver stops notifying an observer added with AddObserver
+func (cpu *Z80) RemoveObserver(observer InstructionObserver) {
+	for i, o := range cpu.observers {
+		if o == observer {
+			cpu.observers = append(cpu.observers[:i], cpu.observers[i+1:]...)
+			break
+		}
+	}
+	for i, o := range cpu.stepObservers {
+		if InstructionObserver(o) == observer {
+			cpu.stepObservers = append(cpu.stepObservers[:i], cpu.stepObservers[i+1:]...)
+			break
+		}
+	}
+}
+
+// ExecuteWatcher is implemented by an MMU that watches for instructions being
+// executed from particular addresses. NewCPU detects it on the MMU.
+type ExecuteWatcher interface {
+	WatchExecute(addr uint16)
+}
+
+// Peeker is implemented by an MMU that can access memory without triggering
+// watchpoints. The CPU uses it to poll and acknowledge interrupts, which are
+// not accesses made by the program. NewCPU detects it on the MMU.
+type Peeker interface {
+	Peek(addr uint16) byte
+	Poke(addr uint16, value byte)
+}
@@ -30,15 +30,23 @@ func LoadOpcodes(filePath string) (*OpcodesData, error) {
 		return nil, fmt.Errorf("error reading opcodes file: %v", err)
 	}
 
-	var opcodes OpcodesData
-	err = json.Unmarshal(data, &opcodes)
+	opcodes, err := ParseOpcodes(data)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing opcodes JSON: %v", err)
+		return nil, err
 	}
 
 	log.Printf("Loaded %d unprefixed opcodes and %d CB-prefixed opcodes",
 		len(opcodes.Unprefixed), len(opcodes.CBPrefixed))
 
+	return opcodes, nil
+}
+
+// ParseOpcodes parses opcodes from JSON data in the format of docs/Opcodes.json
+func ParseOpcodes(data []byte) (*OpcodesData, error) {
+	var opcodes OpcodesData
+	if err := json.Unmarshal(data, &opcodes); err != nil {
+		return nil, fmt.Errorf("error parsing opcodes JSON: %v", err)
+	}
 	return &opcodes, nil
 }
 
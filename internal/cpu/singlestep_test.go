@@ -0,0 +1,216 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Per-opcode JSON test vectors from https://github.com/SingleStepTests/sm83
+// are not distributed with the repository. Copy the v1 directory to
+// test/testdata/sm83/v1 to run them.
+var singleStepDir = filepath.Join("..", "..", "test", "testdata", "sm83", "v1")
+
+// Maximum failures reported per opcode before the rest are only counted
+const maxSingleStepFailures = 5
+
+// singleStepState is the CPU and memory state before or after a test
+type singleStepState struct {
+	PC  uint16      `json:"pc"`
+	SP  uint16      `json:"sp"`
+	A   byte        `json:"a"`
+	B   byte        `json:"b"`
+	C   byte        `json:"c"`
+	D   byte        `json:"d"`
+	E   byte        `json:"e"`
+	F   byte        `json:"f"`
+	H   byte        `json:"h"`
+	L   byte        `json:"l"`
+	IME byte        `json:"ime"`
+	EI  byte        `json:"ei"` // An EI waiting for the next instruction
+	RAM [][2]uint16 `json:"ram"`
+}
+
+// singleStepTest is one test vector. Each entry in Cycles is one machine
+// cycle of bus activity, only the count is checked.
+type singleStepTest struct {
+	Name    string            `json:"name"`
+	Initial singleStepState   `json:"initial"`
+	Final   singleStepState   `json:"final"`
+	Cycles  []json.RawMessage `json:"cycles"`
+}
+
+// TestSingleStep runs the SingleStepTests vectors for every opcode, comparing
+// registers, memory and cycle counts after executing each instruction
+func TestSingleStep(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join(singleStepDir, "*.json"))
+	if len(files) == 0 {
+		t.Skipf("No test vectors found in %s", singleStepDir)
+	}
+
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", file, err)
+			}
+			var tests []singleStepTest
+			if err := json.Unmarshal(data, &tests); err != nil {
+				t.Fatalf("Failed to parse %s: %v", file, err)
+			}
+
+			mmu := &MockMMU{}
+			cpu, _ := NewCPU(mmu)
+
+			failures := 0
+			for _, test := range tests {
+				errs := runSingleStep(cpu, mmu, test)
+				if len(errs) == 0 {
+					continue
+				}
+
+				failures++
+				if failures <= maxSingleStepFailures {
+					t.Errorf("%s:\n  %s", test.Name, strings.Join(errs, "\n  "))
+				}
+			}
+
+			if failures > maxSingleStepFailures {
+				t.Errorf("%d more of %d tests failed", failures-maxSingleStepFailures, len(tests))
+			}
+		})
+	}
+}
+
+// runSingleStep executes one test vector and returns the differences from
+// the expected final state
+func runSingleStep(cpu *Z80, mmu *MockMMU, test singleStepTest) []string {
+	initial, final := test.Initial, test.Final
+
+	cpu.ResetCPU()
+	cpu.SetRegisters(Registers{
+		A: initial.A, F: initial.F,
+		B: initial.B, C: initial.C,
+		D: initial.D, E: initial.E,
+		H: initial.H, L: initial.L,
+		PC: initial.PC, SP: initial.SP,
+	})
+	cpu.interruptMaster = initial.IME != 0
+	cpu.interruptEnableScheduled = initial.EI != 0
+	for _, entry := range initial.RAM {
+		mmu.memory[entry[0]] = byte(entry[1])
+	}
+
+	cycles := cpu.Step()
+
+	var errs []string
+	check := func(name string, got, expected uint16) {
+		if got != expected {
+			errs = append(errs, fmt.Sprintf("Expected %s to be 0x%02X, got 0x%02X", name, expected, got))
+		}
+	}
+
+	reg := cpu.GetRegisters()
+	check("A", uint16(reg.A), uint16(final.A))
+	check("F", uint16(reg.F), uint16(final.F))
+	check("B", uint16(reg.B), uint16(final.B))
+	check("C", uint16(reg.C), uint16(final.C))
+	check("D", uint16(reg.D), uint16(final.D))
+	check("E", uint16(reg.E), uint16(final.E))
+	check("H", uint16(reg.H), uint16(final.H))
+	check("L", uint16(reg.L), uint16(final.L))
+	check("PC", reg.PC, final.PC)
+	check("SP", reg.SP, final.SP)
+
+	// EI takes effect after the next instruction, which the vectors record
+	// in their ei field
+	check("IME", uint16(boolToInt(cpu.interruptMaster)), uint16(final.IME))
+	check("EI", uint16(boolToInt(cpu.interruptEnableScheduled)), uint16(final.EI))
+	for _, entry := range final.RAM {
+		check(fmt.Sprintf("memory[0x%04X]", entry[0]), uint16(mmu.memory[entry[0]]), entry[1])
+	}
+	if expected := len(test.Cycles) * 4; cycles != expected {
+		errs = append(errs, fmt.Sprintf("Expected %d cycles, got %d", expected, cycles))
+	}
+
+	// Clear the memory this test touched for the next one
+	for _, entry := range initial.RAM {
+		mmu.memory[entry[0]] = 0
+	}
+	for _, entry := range final.RAM {
+		mmu.memory[entry[0]] = 0
+	}
+
+	return errs
+}
+
+// TestSingleStepRunner tests the runner with a hand-written vector for
+// ADD A,B
+func TestSingleStepRunner(t *testing.T) {
+	var test singleStepTest
+	vector := `{
+		"name": "80 0000",
+		"initial": {"pc": 49152, "sp": 65534, "a": 58, "b": 198, "c": 0, "d": 0, "e": 0, "f": 0, "h": 0, "l": 0, "ime": 0, "ram": [[49152, 128]]},
+		"final": {"pc": 49153, "sp": 65534, "a": 0, "b": 198, "c": 0, "d": 0, "e": 0, "f": 176, "h": 0, "l": 0, "ime": 0, "ram": [[49152, 128]]},
+		"cycles": [[49152, 128, "r-m"]]
+	}`
+	if err := json.Unmarshal([]byte(vector), &test); err != nil {
+		t.Fatalf("Failed to parse test vector: %v", err)
+	}
+
+	mmu := &MockMMU{}
+	cpu, _ := NewCPU(mmu)
+	if errs := runSingleStep(cpu, mmu, test); len(errs) > 0 {
+		t.Errorf("Expected no differences, got:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	// A wrong expectation is reported
+	test.Final.A = 0x01
+	if errs := runSingleStep(cpu, mmu, test); len(errs) != 1 {
+		t.Errorf("Expected 1 difference, got %d", len(errs))
+	}
+}
+
+// TestSingleStepRunnerIME tests that the runner compares the interrupt
+// master enable after EI, DI and RETI
+func TestSingleStepRunnerIME(t *testing.T) {
+	tests := []struct {
+		opcode  uint16
+		initial byte
+		ime     byte
+		ei      byte
+	}{
+		{0xFB, 0, 0, 1}, // EI enables after the next instruction
+		{0xF3, 1, 0, 0}, // DI disables at once
+		{0xD9, 0, 1, 0}, // RETI enables at once
+	}
+
+	mmu := &MockMMU{}
+	cpu, _ := NewCPU(mmu)
+	for _, test := range tests {
+		vector := singleStepTest{
+			Name:    fmt.Sprintf("%02X", test.opcode),
+			Initial: singleStepState{PC: 0xC000, SP: 0xD000, IME: test.initial, RAM: [][2]uint16{{0xC000, test.opcode}}},
+			Final:   singleStepState{PC: 0xC001, SP: 0xD000, IME: test.ime, EI: test.ei, RAM: [][2]uint16{{0xC000, test.opcode}}},
+			Cycles:  make([]json.RawMessage, 1),
+		}
+		if test.opcode == 0xD9 {
+			vector.Final.PC, vector.Final.SP = 0x0000, 0xD002
+			vector.Cycles = make([]json.RawMessage, 4)
+		}
+		if errs := runSingleStep(cpu, mmu, vector); len(errs) > 0 {
+			t.Errorf("%s: expected no differences, got:\n  %s", vector.Name, strings.Join(errs, "\n  "))
+		}
+
+		// A wrong IME is reported
+		vector.Final.IME ^= 1
+		if errs := runSingleStep(cpu, mmu, vector); len(errs) != 1 {
+			t.Errorf("%s: expected a wrong IME to be reported, got %d differences", vector.Name, len(errs))
+		}
+	}
+}
@@ -0,0 +1,812 @@
+// Package dap implements a Debug Adapter Protocol server, so editors such as
+// VS Code can debug ROMs running in the emulator
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/disasm"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// Target is the emulator being debugged
+type Target interface {
+	StepInstruction() (int, error)
+	Registers() cpu.Registers
+	ReadMemory(addr uint16) byte
+	CurrentROMBank() int
+}
+
+// CallStacker is implemented by targets that track the call stack, see
+// cpu.TrackCalls. Stack traces of other targets only show the current
+// instruction.
+type CallStacker interface {
+	TrackCalls(enabled bool)
+	CallStack() []cpu.CallFrame
+}
+
+// LockReporter is implemented by targets that report the CPU locking up on
+// an illegal opcode, see cpu.Locked. Running stops with an exception when
+// it does.
+type LockReporter interface {
+	Locked() bool
+}
+
+// The CPU is reported as the only thread
+const threadID = 1
+
+// Variable references of the scopes
+const (
+	registersReference = 1
+	flagsReference     = 2
+)
+
+// errRunning is returned for requests that need the target to be stopped
+var errRunning = errors.New("the target is running, pause it first")
+
+// location is a breakpoint address, in a ROM bank or in any bank if bank is
+// -1
+type location struct {
+	bank int
+	addr uint16
+}
+
+// Server serves the Debug Adapter Protocol for a target
+type Server struct {
+	target  Target
+	disasm  *disasm.Disassembler
+	symbols *symbols.Table
+
+	// Responses and events are written from the request loop and from
+	// the goroutine running the target
+	writeMu sync.Mutex
+	w       io.Writer
+	seq     int
+
+	// Breakpoints by the request that set them. The run loop reads the
+	// combined set from active.
+	sourceBreakpoints      map[string][]location
+	functionBreakpoints    []location
+	instructionBreakpoints []location
+	active                 atomic.Pointer[map[uint16][]int]
+
+	stopOnEntry bool
+
+	// Set by a request handler to send events or resume the target once
+	// the response has been sent
+	afterResponse func()
+
+	// Set while the target runs in the background, see start
+	running atomic.Bool
+	pause   atomic.Bool
+	stopped chan struct{}
+}
+
+// NewServer creates a server debugging target
+func NewServer(target Target) *Server {
+	s := &Server{
+		target:            target,
+		disasm:            disasm.New(),
+		sourceBreakpoints: make(map[string][]location),
+	}
+	if stacker, ok := target.(CallStacker); ok {
+		stacker.TrackCalls(true)
+	}
+	s.updateBreakpoints()
+	return s
+}
+
+// SetSymbols sets the labels used for breakpoints, stack frames and
+// disassembly
+func (s *Server) SetSymbols(table *symbols.Table) {
+	s.symbols = table
+	s.disasm.SetSymbols(table)
+}
+
+// ListenAndServe waits for a client to connect on addr and serves the
+// connection until the client disconnects
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	log.Printf("[DAP] Waiting for a debugger to connect on %s", l.Addr())
+	conn, err := l.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Printf("[DAP] Connected to %s", conn.RemoteAddr())
+	return s.Serve(conn)
+}
+
+// Serve handles requests from a connected client until it disconnects
+func (s *Server) Serve(conn io.ReadWriter) error {
+	s.w = conn
+	r := bufio.NewReader(conn)
+
+	for {
+		req, err := readRequest(r)
+		if err == io.EOF {
+			s.stop()
+			return nil
+		}
+		if err != nil {
+			s.stop()
+			return err
+		}
+
+		s.afterResponse = nil
+		body, err := s.handle(req)
+		if err := s.respond(req, body, err); err != nil {
+			return err
+		}
+		if s.afterResponse != nil {
+			s.afterResponse()
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// handle runs a request and returns the response body
+func (s *Server) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		s.afterResponse = func() { s.sendEvent("initialized", nil) }
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsFunctionBreakpoints:      true,
+			SupportsInstructionBreakpoints:   true,
+			SupportsReadMemoryRequest:        true,
+			SupportsDisassembleRequest:       true,
+			SupportsEvaluateForHovers:        true,
+		}, nil
+	case "launch", "attach":
+		return nil, s.launch(req.Arguments)
+	case "configurationDone":
+		if s.stopOnEntry {
+			s.afterResponse = func() { s.sendStopped("entry", "") }
+			return nil, nil
+		}
+		return nil, s.start(func(executed byte) bool { return false })
+	case "setBreakpoints":
+		return s.setBreakpoints(req.Arguments)
+	case "setFunctionBreakpoints":
+		return s.setFunctionBreakpoints(req.Arguments)
+	case "setInstructionBreakpoints":
+		return s.setInstructionBreakpoints(req.Arguments)
+	case "setExceptionBreakpoints":
+		return map[string]interface{}{"breakpoints": []breakpoint{}}, nil
+	case "threads":
+		return map[string]interface{}{"threads": []thread{{ID: threadID, Name: "CPU"}}}, nil
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.start(func(executed byte) bool { return false })
+	case "pause":
+		if s.running.Load() {
+			s.pause.Store(true)
+		} else {
+			s.afterResponse = func() { s.sendStopped("pause", "") }
+		}
+		return nil, nil
+	case "stepIn":
+		return nil, s.start(func(executed byte) bool { return true })
+	case "next":
+		return nil, s.stepOver()
+	case "stepOut":
+		return nil, s.stepOut()
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		return map[string]interface{}{"scopes": []scope{
+			{Name: "Registers", VariablesReference: registersReference},
+			{Name: "Flags", VariablesReference: flagsReference},
+		}}, nil
+	case "variables":
+		return s.variables(req.Arguments)
+	case "readMemory":
+		return s.readMemory(req.Arguments)
+	case "disassemble":
+		return s.disassemble(req.Arguments)
+	case "evaluate":
+		return s.evaluate(req.Arguments)
+	case "terminate":
+		s.stop()
+		s.afterResponse = func() { s.sendEvent("terminated", nil) }
+		return nil, nil
+	case "disconnect":
+		s.stop()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+func (s *Server) launch(arguments json.RawMessage) error {
+	var args launchArguments
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return err
+		}
+	}
+
+	s.stopOnEntry = args.StopOnEntry
+	if args.Symbols != "" {
+		table, err := symbols.Load(args.Symbols)
+		if err != nil {
+			return err
+		}
+		s.SetSymbols(table)
+		log.Printf("[DAP] Loaded %d symbols from %s", table.Len(), args.Symbols)
+	}
+	return nil
+}
+
+// start runs the target in the background, once the response has been
+// sent, until done returns true after an instruction, a breakpoint is hit or
+// the client pauses. done receives the opcode that was just executed.
+func (s *Server) start(done func(executed byte) bool) error {
+	if s.running.Load() {
+		return errRunning
+	}
+
+	s.pause.Store(false)
+	s.stopped = make(chan struct{})
+	s.running.Store(true)
+	s.afterResponse = func() { go s.run(done) }
+	return nil
+}
+
+// run steps the target for start and sends a stopped event when it stops
+func (s *Server) run(done func(executed byte) bool) {
+	defer close(s.stopped)
+
+	reason, text := "step", ""
+	for {
+		opcode := s.target.ReadMemory(s.target.Registers().PC)
+		if _, err := s.target.StepInstruction(); err != nil {
+			log.Printf("[DAP] Step failed: %v", err)
+			break
+		}
+		if locker, ok := s.target.(LockReporter); ok && locker.Locked() {
+			reason = "exception"
+			text = fmt.Sprintf("CPU locked up by illegal opcode $%02X", s.target.ReadMemory(s.target.Registers().PC))
+			break
+		}
+		if done(opcode) {
+			break
+		}
+		if s.breakpointHit() {
+			reason = "breakpoint"
+			break
+		}
+		if s.pause.Load() {
+			reason = "pause"
+			break
+		}
+	}
+
+	s.running.Store(false)
+	s.sendStopped(reason, text)
+}
+
+// stop pauses the target if it is running and waits for it to stop
+func (s *Server) stop() {
+	if s.running.Load() {
+		s.pause.Store(true)
+		<-s.stopped
+	}
+}
+
+// stepOver runs until the instruction after a CALL or RST
+func (s *Server) stepOver() error {
+	if s.running.Load() {
+		return errRunning
+	}
+	start := s.target.Registers()
+	if !disasm.IsCall(s.target.ReadMemory(start.PC)) {
+		return s.start(func(executed byte) bool { return true })
+	}
+
+	// The SP check keeps recursive calls from stopping early
+	returnAddr := start.PC + uint16(s.disasm.Decode(s.target, start.PC).Len())
+	return s.start(func(executed byte) bool {
+		reg := s.target.Registers()
+		return reg.PC == returnAddr && reg.SP >= start.SP
+	})
+}
+
+// stepOut runs until a return pops the current stack frame
+func (s *Server) stepOut() error {
+	if s.running.Load() {
+		return errRunning
+	}
+	startSP := s.target.Registers().SP
+	return s.start(func(executed byte) bool {
+		return disasm.IsReturn(executed) && s.target.Registers().SP > startSP
+	})
+}
+
+// breakpointHit reports whether PC is at a breakpoint
+func (s *Server) breakpointHit() bool {
+	pc := s.target.Registers().PC
+	banks, ok := (*s.active.Load())[pc]
+	if !ok {
+		return false
+	}
+	for _, bank := range banks {
+		if bank < 0 || bank == symbols.BankAt(pc, s.target) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateBreakpoints combines the breakpoints for the run loop
+func (s *Server) updateBreakpoints() {
+	active := make(map[uint16][]int)
+	add := func(locations []location) {
+		for _, loc := range locations {
+			active[loc.addr] = append(active[loc.addr], loc.bank)
+		}
+	}
+	for _, locations := range s.sourceBreakpoints {
+		add(locations)
+	}
+	add(s.functionBreakpoints)
+	add(s.instructionBreakpoints)
+	s.active.Store(&active)
+}
+
+// setBreakpoints sets breakpoints on label definitions in an assembly
+// source file. There is no line information in symbol files, so only lines
+// defining a label can be mapped to an address.
+func (s *Server) setBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	var args setBreakpointsArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	labels, err := sourceLabels(args.Source.Path)
+	if err != nil && len(args.Breakpoints) > 0 {
+		return nil, err
+	}
+
+	var locations []location
+	breakpoints := make([]breakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		breakpoints[i] = breakpoint{Source: &args.Source, Line: bp.Line}
+
+		label, ok := labels[bp.Line]
+		if !ok {
+			breakpoints[i].Message = "Breakpoints can only be set on lines defining a label"
+			continue
+		}
+		sym, ok := s.symbols.Lookup(label)
+		if !ok {
+			breakpoints[i].Message = fmt.Sprintf("Label %s is not in the symbol file", label)
+			continue
+		}
+
+		locations = append(locations, location{bank: sym.ROMBank(), addr: sym.Addr})
+		breakpoints[i].Verified = true
+		breakpoints[i].InstructionReference = formatReference(sym.Addr)
+	}
+
+	s.sourceBreakpoints[args.Source.Path] = locations
+	s.updateBreakpoints()
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// setFunctionBreakpoints sets breakpoints by label or address
+func (s *Server) setFunctionBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	var args setFunctionBreakpointsArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	var locations []location
+	breakpoints := make([]breakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		loc, err := s.resolve(bp.Name)
+		if err != nil {
+			breakpoints[i].Message = err.Error()
+			continue
+		}
+		locations = append(locations, loc)
+		breakpoints[i].Verified = true
+		breakpoints[i].InstructionReference = formatReference(loc.addr)
+	}
+
+	s.functionBreakpoints = locations
+	s.updateBreakpoints()
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// setInstructionBreakpoints sets breakpoints from the disassembly view
+func (s *Server) setInstructionBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	var args setInstructionBreakpointsArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	var locations []location
+	breakpoints := make([]breakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		addr, err := parseReference(bp.InstructionReference)
+		if err != nil {
+			breakpoints[i].Message = err.Error()
+			continue
+		}
+		addr += uint16(bp.Offset)
+		locations = append(locations, location{bank: -1, addr: addr})
+		breakpoints[i].Verified = true
+		breakpoints[i].InstructionReference = formatReference(addr)
+	}
+
+	s.instructionBreakpoints = locations
+	s.updateBreakpoints()
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (s *Server) stackTrace() (interface{}, error) {
+	if s.running.Load() {
+		return nil, errRunning
+	}
+
+	// The innermost frame is at PC, each caller at its CALL or at the
+	// instruction an interrupt preempted
+	frames := []stackFrame{s.frame(0, s.target.Registers().PC, "")}
+	if stacker, ok := s.target.(CallStacker); ok {
+		stack := stacker.CallStack()
+		for i := len(stack) - 1; i >= 0; i-- {
+			suffix := ""
+			if stack[i].Interrupt {
+				suffix = " [interrupted]"
+			}
+			frames = append(frames, s.frame(len(frames), stack[i].CallSite, suffix))
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *Server) variables(arguments json.RawMessage) (interface{}, error) {
+	if s.running.Load() {
+		return nil, errRunning
+	}
+	var args variablesArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	reg := s.target.Registers()
+	var vars []variable
+	switch args.VariablesReference {
+	case registersReference:
+		for _, r := range []struct {
+			name  string
+			value byte
+		}{{"A", reg.A}, {"F", reg.F}, {"B", reg.B}, {"C", reg.C}, {"D", reg.D}, {"E", reg.E}, {"H", reg.H}, {"L", reg.L}} {
+			vars = append(vars, variable{Name: r.name, Value: fmt.Sprintf("$%02X", r.value)})
+		}
+		for _, r := range []struct {
+			name  string
+			value uint16
+		}{{"AF", reg.GetAF()}, {"BC", reg.GetBC()}, {"DE", reg.GetDE()}, {"HL", reg.GetHL()}, {"SP", reg.SP}, {"PC", reg.PC}} {
+			vars = append(vars, variable{Name: r.name, Value: fmt.Sprintf("$%04X", r.value), MemoryReference: formatReference(r.value)})
+		}
+		vars = append(vars, variable{Name: "ROM bank", Value: strconv.Itoa(s.target.CurrentROMBank())})
+	case flagsReference:
+		for _, flag := range []struct {
+			name string
+			mask byte
+		}{{"Z", cpu.FLAG_Z}, {"N", cpu.FLAG_N}, {"H", cpu.FLAG_H}, {"C", cpu.FLAG_C}} {
+			vars = append(vars, variable{Name: flag.name, Value: strconv.FormatBool(reg.F&flag.mask != 0)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+func (s *Server) readMemory(arguments json.RawMessage) (interface{}, error) {
+	if s.running.Load() {
+		return nil, errRunning
+	}
+	var args readMemoryArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseReference(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+
+	start := int(addr) + args.Offset
+	if start < 0 || start > 0xFFFF {
+		return map[string]interface{}{"address": formatReference(addr), "unreadableBytes": args.Count}, nil
+	}
+	count := args.Count
+	if start+count > 0x10000 {
+		count = 0x10000 - start
+	}
+
+	data := make([]byte, count)
+	for i := range data {
+		data[i] = s.target.ReadMemory(uint16(start + i))
+	}
+	return map[string]interface{}{
+		"address": formatReference(uint16(start)),
+		"data":    base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+func (s *Server) disassemble(arguments json.RawMessage) (interface{}, error) {
+	if s.running.Load() {
+		return nil, errRunning
+	}
+	var args disassembleArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	ref, err := parseReference(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	addr := ref + uint16(args.Offset)
+
+	var instructions []disassembledInstruction
+	if args.InstructionOffset < 0 {
+		instructions = s.disassembleBefore(addr, -args.InstructionOffset)
+	} else {
+		for i := 0; i < args.InstructionOffset; i++ {
+			addr += uint16(s.disasm.Decode(s.target, addr).Len())
+		}
+	}
+	for len(instructions) < args.InstructionCount {
+		inst := s.disasm.Decode(s.target, addr)
+		instructions = append(instructions, s.formatInstruction(inst))
+		addr += uint16(inst.Len())
+	}
+
+	return map[string]interface{}{"instructions": instructions[:args.InstructionCount]}, nil
+}
+
+// disassembleBefore returns count instructions ending at addr. Instructions
+// have different lengths, so decoding starts far enough before addr to
+// usually fall into step with the instruction stream.
+func (s *Server) disassembleBefore(addr uint16, count int) []disassembledInstruction {
+	var decoded []disasm.Instruction
+	for a := int(addr) - count*3; a < int(addr); {
+		if a < 0 {
+			a++
+			continue
+		}
+		inst := s.disasm.Decode(s.target, uint16(a))
+		decoded = append(decoded, inst)
+		a += inst.Len()
+	}
+	if len(decoded) > count {
+		decoded = decoded[len(decoded)-count:]
+	}
+
+	// Pad the start of memory with placeholders
+	var instructions []disassembledInstruction
+	for i := len(decoded); i < count; i++ {
+		instructions = append(instructions, disassembledInstruction{
+			Address:          formatReference(0),
+			Instruction:      "??",
+			PresentationHint: "invalid",
+		})
+	}
+	for _, inst := range decoded {
+		instructions = append(instructions, s.formatInstruction(inst))
+	}
+	return instructions
+}
+
+func (s *Server) formatInstruction(inst disasm.Instruction) disassembledInstruction {
+	result := disassembledInstruction{
+		Address:          formatReference(inst.Addr),
+		InstructionBytes: strings.ToUpper(hex.EncodeToString(inst.Bytes)),
+		Instruction:      inst.Text(),
+	}
+	result.Symbol = inst.Label
+	return result
+}
+
+// evaluate shows registers, labels and memory for hovers and the debug
+// console
+func (s *Server) evaluate(arguments json.RawMessage) (interface{}, error) {
+	if s.running.Load() {
+		return nil, errRunning
+	}
+	var args evaluateArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	expr := strings.TrimSpace(args.Expression)
+
+	reg := s.target.Registers()
+	registers := map[string]uint16{
+		"A": uint16(reg.A), "F": uint16(reg.F), "B": uint16(reg.B), "C": uint16(reg.C),
+		"D": uint16(reg.D), "E": uint16(reg.E), "H": uint16(reg.H), "L": uint16(reg.L),
+		"AF": reg.GetAF(), "BC": reg.GetBC(), "DE": reg.GetDE(), "HL": reg.GetHL(),
+		"SP": reg.SP, "PC": reg.PC,
+	}
+	if value, ok := registers[strings.ToUpper(expr)]; ok {
+		return map[string]interface{}{"result": fmt.Sprintf("$%X", value), "variablesReference": 0}, nil
+	}
+
+	loc, err := s.resolve(expr)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"result":             fmt.Sprintf("$%04X = $%02X", loc.addr, s.target.ReadMemory(loc.addr)),
+		"variablesReference": 0,
+		"memoryReference":    formatReference(loc.addr),
+	}, nil
+}
+
+// resolve parses a label with an optional offset, addr or bank:addr
+func (s *Server) resolve(expr string) (location, error) {
+	if sym, ok := s.symbols.Resolve(expr); ok {
+		return location{bank: sym.ROMBank(), addr: sym.Addr}, nil
+	}
+
+	bankText, addrText, hasBank := strings.Cut(expr, ":")
+	if !hasBank {
+		addr, err := parseAddress(expr)
+		if err != nil {
+			return location{}, fmt.Errorf("unknown label or address %q", expr)
+		}
+		return location{bank: -1, addr: addr}, nil
+	}
+
+	bank, err := strconv.ParseUint(strings.TrimPrefix(bankText, "$"), 16, 16)
+	if err != nil {
+		return location{}, fmt.Errorf("invalid bank %q", bankText)
+	}
+	addr, err := parseAddress(addrText)
+	if err != nil {
+		return location{}, err
+	}
+	return location{bank: int(bank), addr: addr}, nil
+}
+
+// frame creates a stack frame at addr, named by its label
+func (s *Server) frame(id int, addr uint16, suffix string) stackFrame {
+	location := fmt.Sprintf("%04X", addr)
+	if bank := symbols.BankAt(addr, s.target); bank >= 0 {
+		location = fmt.Sprintf("%02X:%04X", bank, addr)
+	}
+	name := location
+	if label := s.symbols.LabelAt(addr, s.target); label != "" {
+		name = label + " (" + location + ")"
+	}
+	return stackFrame{
+		ID:                          id,
+		Name:                        name + suffix,
+		InstructionPointerReference: formatReference(addr),
+	}
+}
+
+func (s *Server) respond(req *request, body interface{}, err error) error {
+	resp := response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    err == nil,
+		Command:    req.Command,
+		Body:       body,
+	}
+	if err != nil {
+		resp.Message = err.Error()
+		resp.Body = nil
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	resp.Seq = s.seq
+	return writeMessage(s.w, &resp)
+}
+
+func (s *Server) sendEvent(name string, body interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	if err := writeMessage(s.w, &event{Seq: s.seq, Type: "event", Event: name, Body: body}); err != nil {
+		log.Printf("[DAP] Failed to send %s event: %v", name, err)
+	}
+}
+
+// sendStopped sends a stopped event. text describes an exception, it is
+// left out if empty.
+func (s *Server) sendStopped(reason, text string) {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	}
+	if text != "" {
+		body["text"] = text
+	}
+	s.sendEvent("stopped", body)
+}
+
+// labelPattern matches a label definition at the start of a source line
+var labelPattern = regexp.MustCompile(`^\s*([A-Za-z_.][\w.#@]*):`)
+
+// sourceLabels returns the labels defined in an RGBDS assembly file by line
+// number. Local labels are qualified with the global label before them, as
+// in symbol files.
+func sourceLabels(path string) (map[int]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[int]string)
+	global := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		match := labelPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		label := match[1]
+		switch {
+		case strings.HasPrefix(label, "."):
+			label = global + label
+		case !strings.Contains(label, "."):
+			global = label
+		}
+		labels[i+1] = label
+	}
+	return labels, nil
+}
+
+// Memory references are addresses in the 0x0000 form
+
+func formatReference(addr uint16) string {
+	return fmt.Sprintf("0x%04X", addr)
+}
+
+func parseReference(ref string) (uint16, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(ref), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %q", ref)
+	}
+	return uint16(value), nil
+}
+
+// parseAddress parses a hexadecimal address with an optional $ or 0x prefix
+func parseAddress(s string) (uint16, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0x"), "$")
+	value, err := strconv.ParseUint(trimmed, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(value), nil
+}
@@ -0,0 +1,502 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// program calls a subroutine in a loop
+//
+//	0100: CALL $0110
+//	0103: INC B
+//	0104: JR $0100
+//	0110: INC C
+//	0111: RET
+var program = map[uint16][]byte{
+	0x0100: {0xCD, 0x10, 0x01, 0x04, 0x18, 0xFA},
+	0x0110: {0x0C, 0xC9},
+}
+
+const testSymbols = `; rgblink output
+00:0100 Main
+00:0103 Main.next
+00:0110 Sub
+`
+
+const testSource = `SECTION "Main", ROM0[$100]
+Main:
+	call Sub
+.next:
+	inc b
+	jr Main
+
+Sub:
+	inc c
+	ret
+`
+
+// testMessage is a response or event received by the test client
+type testMessage struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// testClient sends requests to a server over loopback TCP
+type testClient struct {
+	t         *testing.T
+	conn      net.Conn
+	seq       int
+	responses chan testMessage
+	events    chan testMessage
+	dir       string
+	target    *MockTarget
+}
+
+// newTestClient starts a server for a CPU running program and connects to it
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	target := newMockTarget()
+	for addr, code := range program {
+		copy(target.memory[addr:], code)
+	}
+	target.cpu.SetRegisters(cpu.Registers{PC: 0x0100, SP: 0xFFFE})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		NewServer(target).Serve(conn)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testClient{
+		t:         t,
+		conn:      conn,
+		responses: make(chan testMessage, 16),
+		events:    make(chan testMessage, 16),
+		dir:       t.TempDir(),
+		target:    target,
+	}
+	go c.read()
+	return c
+}
+
+func (c *testClient) read() {
+	r := bufio.NewReader(c.conn)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		var msg testMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			c.t.Errorf("Invalid message %s: %v", body, err)
+			return
+		}
+		if msg.Type == "event" {
+			c.events <- msg
+		} else {
+			c.responses <- msg
+		}
+	}
+}
+
+// request sends a request and waits for its response
+func (c *testClient) request(command string, arguments interface{}) testMessage {
+	c.t.Helper()
+	c.seq++
+	args, _ := json.Marshal(arguments)
+	if err := writeMessage(c.conn, &request{Seq: c.seq, Type: "request", Command: command, Arguments: args}); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", command, err)
+	}
+
+	select {
+	case resp := <-c.responses:
+		if resp.RequestSeq != c.seq {
+			c.t.Fatalf("Expected response to request %d, got %d", c.seq, resp.RequestSeq)
+		}
+		return resp
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("Timed out waiting for the %s response", command)
+	}
+	return testMessage{}
+}
+
+// event waits for the next event, which must have the given name
+func (c *testClient) event(name string) testMessage {
+	c.t.Helper()
+	select {
+	case ev := <-c.events:
+		if ev.Event != name {
+			c.t.Fatalf("Expected %s event, got %s", name, ev.Event)
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("Timed out waiting for the %s event", name)
+	}
+	return testMessage{}
+}
+
+// stopped waits for a stopped event and returns its reason
+func (c *testClient) stopped() string {
+	c.t.Helper()
+	reason, _ := c.stoppedText()
+	return reason
+}
+
+// stoppedText waits for a stopped event and returns its reason and text
+func (c *testClient) stoppedText() (string, string) {
+	c.t.Helper()
+	var body struct {
+		Reason string `json:"reason"`
+		Text   string `json:"text"`
+	}
+	json.Unmarshal(c.event("stopped").Body, &body)
+	return body.Reason, body.Text
+}
+
+// stackTrace returns the stack frames, innermost first
+func (c *testClient) stackTrace() []stackFrame {
+	c.t.Helper()
+	var body struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+	}
+	json.Unmarshal(c.request("stackTrace", map[string]int{"threadId": threadID}).Body, &body)
+	if len(body.StackFrames) == 0 {
+		c.t.Fatal("Expected a stack frame")
+	}
+	return body.StackFrames
+}
+
+// pc returns the program counter from the stack trace
+func (c *testClient) pc() string {
+	c.t.Helper()
+	return c.stackTrace()[0].InstructionPointerReference
+}
+
+// writeFile writes a file to the client's temporary directory
+func (c *testClient) writeFile(name, content string) string {
+	c.t.Helper()
+	path := filepath.Join(c.dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		c.t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// startSession initializes and launches with the test symbols, stopped on
+// entry
+func (c *testClient) startSession() {
+	c.t.Helper()
+	if resp := c.request("initialize", map[string]string{"adapterID": "gameboy-go"}); !resp.Success {
+		c.t.Fatalf("Expected initialize to succeed, got %s", resp.Message)
+	}
+	c.event("initialized")
+
+	resp := c.request("launch", map[string]interface{}{
+		"symbols":     c.writeFile("game.sym", testSymbols),
+		"stopOnEntry": true,
+	})
+	if !resp.Success {
+		c.t.Fatalf("Expected launch to succeed, got %s", resp.Message)
+	}
+}
+
+// TestSourceBreakpoints tests breakpoints on label lines of a source file
+func TestSourceBreakpoints(t *testing.T) {
+	c := newTestClient(t)
+	c.startSession()
+
+	resp := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": c.writeFile("main.asm", testSource)},
+		"breakpoints": []map[string]int{{"line": 8}, {"line": 3}},
+	})
+	var body struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	if len(body.Breakpoints) != 2 || !body.Breakpoints[0].Verified || body.Breakpoints[1].Verified {
+		t.Fatalf("Expected only the label line to be verified, got %+v", body.Breakpoints)
+	}
+
+	c.request("configurationDone", nil)
+	if reason := c.stopped(); reason != "entry" {
+		t.Errorf("Expected to stop on entry, got %s", reason)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	if reason := c.stopped(); reason != "breakpoint" {
+		t.Errorf("Expected to stop at a breakpoint, got %s", reason)
+	}
+	if pc := c.pc(); pc != "0x0110" {
+		t.Errorf("Expected to stop at Sub (0x0110), got %s", pc)
+	}
+
+	c.request("disconnect", nil)
+}
+
+// TestStepping tests function breakpoints and stepping requests
+func TestStepping(t *testing.T) {
+	c := newTestClient(t)
+	c.startSession()
+	c.request("configurationDone", nil)
+	c.stopped()
+
+	// Local labels resolve, unknown names are not verified
+	resp := c.request("setFunctionBreakpoints", map[string]interface{}{
+		"breakpoints": []map[string]string{{"name": "Main.next"}, {"name": "Missing"}},
+	})
+	var body struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	if !body.Breakpoints[0].Verified || body.Breakpoints[1].Verified {
+		t.Errorf("Expected only the known label to be verified, got %+v", body.Breakpoints)
+	}
+
+	c.request("stepIn", map[string]int{"threadId": threadID})
+	if reason := c.stopped(); reason != "step" {
+		t.Errorf("Expected a step, got %s", reason)
+	}
+	if pc := c.pc(); pc != "0x0110" {
+		t.Errorf("Expected to step into Sub (0x0110), got %s", pc)
+	}
+	frames := c.stackTrace()
+	if len(frames) != 2 || frames[0].Name != "Sub (00:0110)" || frames[1].Name != "Main (00:0100)" {
+		t.Errorf("Expected Sub called from Main, got %+v", frames)
+	}
+
+	c.request("stepOut", map[string]int{"threadId": threadID})
+	c.stopped()
+	if pc := c.pc(); pc != "0x0103" {
+		t.Errorf("Expected to step out to 0x0103, got %s", pc)
+	}
+
+	// Back around the loop to the CALL, then step over it
+	for i := 0; i < 2; i++ {
+		c.request("next", map[string]int{"threadId": threadID})
+		c.stopped()
+	}
+	if pc := c.pc(); pc != "0x0100" {
+		t.Errorf("Expected to be back at the call at 0x0100, got %s", pc)
+	}
+	c.request("next", map[string]int{"threadId": threadID})
+	c.stopped()
+	if pc := c.pc(); pc != "0x0103" {
+		t.Errorf("Expected to step over the call to 0x0103, got %s", pc)
+	}
+
+	// Running with no breakpoints until paused
+	c.request("setFunctionBreakpoints", map[string]interface{}{"breakpoints": []interface{}{}})
+	c.request("continue", map[string]int{"threadId": threadID})
+	if resp := c.request("stackTrace", map[string]int{"threadId": threadID}); resp.Success {
+		t.Error("Expected stackTrace to fail while running")
+	}
+	c.request("pause", map[string]int{"threadId": threadID})
+	if reason := c.stopped(); reason != "pause" {
+		t.Errorf("Expected to pause, got %s", reason)
+	}
+
+	c.request("disconnect", nil)
+}
+
+// TestLockUp tests stopping with an exception when an illegal opcode locks
+// up the CPU
+func TestLockUp(t *testing.T) {
+	c := newTestClient(t)
+	c.target.memory[0x0110] = 0xFC
+	c.startSession()
+	c.request("configurationDone", nil)
+	c.stopped()
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	reason, text := c.stoppedText()
+	if reason != "exception" || text != "CPU locked up by illegal opcode $FC" {
+		t.Errorf("Expected an exception for the illegal opcode, got %s %q", reason, text)
+	}
+	if pc := c.pc(); pc != "0x0110" {
+		t.Errorf("Expected to stop at the illegal opcode at 0x0110, got %s", pc)
+	}
+
+	c.request("disconnect", nil)
+}
+
+// TestInspection tests variables, memory, disassembly and evaluation
+func TestInspection(t *testing.T) {
+	c := newTestClient(t)
+	c.startSession()
+	c.request("configurationDone", nil)
+	c.stopped()
+
+	var vars struct {
+		Variables []variable `json:"variables"`
+	}
+	json.Unmarshal(c.request("variables", map[string]int{"variablesReference": registersReference}).Body, &vars)
+	found := false
+	for _, v := range vars.Variables {
+		if v.Name == "PC" {
+			found = true
+			if v.Value != "$0100" {
+				t.Errorf("Expected PC $0100, got %s", v.Value)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a PC variable, got %+v", vars.Variables)
+	}
+
+	var mem struct {
+		Address string `json:"address"`
+		Data    string `json:"data"`
+	}
+	json.Unmarshal(c.request("readMemory", map[string]interface{}{"memoryReference": "0x0100", "count": 3}).Body, &mem)
+	if mem.Address != "0x0100" || mem.Data != "zRAB" {
+		t.Errorf("Expected CD 10 01 at 0x0100, got %+v", mem)
+	}
+
+	var dis struct {
+		Instructions []disassembledInstruction `json:"instructions"`
+	}
+	json.Unmarshal(c.request("disassemble", map[string]interface{}{
+		"memoryReference":   "0x0103",
+		"instructionOffset": -1,
+		"instructionCount":  3,
+	}).Body, &dis)
+	if len(dis.Instructions) != 3 {
+		t.Fatalf("Expected 3 instructions, got %+v", dis.Instructions)
+	}
+	expected := []disassembledInstruction{
+		{Address: "0x0100", InstructionBytes: "CD1001", Instruction: "CALL Sub", Symbol: "Main"},
+		{Address: "0x0103", InstructionBytes: "04", Instruction: "INC B", Symbol: "Main.next"},
+		{Address: "0x0104", InstructionBytes: "18FA", Instruction: "JR Main"},
+	}
+	for i, inst := range dis.Instructions {
+		if inst != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], inst)
+		}
+	}
+
+	var eval struct {
+		Result string `json:"result"`
+	}
+	json.Unmarshal(c.request("evaluate", map[string]string{"expression": "Sub"}).Body, &eval)
+	if eval.Result != "$0110 = $0C" {
+		t.Errorf("Expected Sub to evaluate to its address and byte, got %q", eval.Result)
+	}
+	json.Unmarshal(c.request("evaluate", map[string]string{"expression": "Sub+1"}).Body, &eval)
+	if eval.Result != "$0111 = $C9" {
+		t.Errorf("Expected Sub+1 to evaluate to the RET, got %q", eval.Result)
+	}
+	json.Unmarshal(c.request("evaluate", map[string]string{"expression": "sp"}).Body, &eval)
+	if eval.Result != "$FFFE" {
+		t.Errorf("Expected SP $FFFE, got %q", eval.Result)
+	}
+
+	c.request("disconnect", nil)
+}
+
+// TestSourceLabels tests mapping source lines to labels
+func TestSourceLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.asm")
+	os.WriteFile(path, []byte(testSource), 0644)
+
+	labels, err := sourceLabels(path)
+	if err != nil {
+		t.Fatalf("Failed to read labels: %v", err)
+	}
+	expected := map[int]string{2: "Main", 4: "Main.next", 8: "Sub"}
+	if len(labels) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+	for line, label := range expected {
+		if labels[line] != label {
+			t.Errorf("Expected %s on line %d, got %q", label, line, labels[line])
+		}
+	}
+	if strings.Contains(labels[1], "SECTION") {
+		t.Error("Expected no label on the SECTION line")
+	}
+}
+
+// MockTarget runs a CPU over a flat 64KB memory
+type MockTarget struct {
+	cpu    *cpu.Z80
+	memory [0x10000]byte
+}
+
+func newMockTarget() *MockTarget {
+	t := &MockTarget{}
+	t.cpu, _ = cpu.NewCPU(t)
+	return t
+}
+
+func (t *MockTarget) StepInstruction() (int, error) {
+	return t.cpu.Step(), nil
+}
+
+func (t *MockTarget) Registers() cpu.Registers {
+	return t.cpu.GetRegisters()
+}
+
+func (t *MockTarget) ReadMemory(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) CurrentROMBank() int {
+	return 1
+}
+
+func (t *MockTarget) Locked() bool {
+	return t.cpu.Locked()
+}
+
+func (t *MockTarget) TrackCalls(enabled bool) {
+	t.cpu.TrackCalls(enabled)
+}
+
+func (t *MockTarget) CallStack() []cpu.CallFrame {
+	return t.cpu.CallStack()
+}
+
+func (t *MockTarget) ReadByte(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) WriteByte(addr uint16, value byte) {
+	t.memory[addr] = value
+}
+
+func (t *MockTarget) ReadWord(addr uint16) uint16 {
+	return uint16(t.memory[addr]) | uint16(t.memory[addr+1])<<8
+}
+
+func (t *MockTarget) WriteWord(addr uint16, value uint16) {
+	t.WriteByte(addr, byte(value&0xFF))
+	t.WriteByte(addr+1, byte(value>>8))
+}
@@ -0,0 +1,705 @@
+// Package debugger implements an interactive terminal debugger for the
+// emulated CPU
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/disasm"
+	"github.com/briancain/gameboy-go/internal/mmu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// Target is the emulator being debugged
+type Target interface {
+	StepInstruction() (int, error)
+	Registers() cpu.Registers
+	ReadMemory(addr uint16) byte
+	CurrentROMBank() int
+}
+
+// WatchTarget is implemented by targets that support memory watchpoints
+type WatchTarget interface {
+	AddWatchpoint(w mmu.Watchpoint) int
+	RemoveWatchpoint(id int) bool
+}
+
+// CallStackTarget is implemented by targets that track the call stack, see
+// cpu.TrackCalls
+type CallStackTarget interface {
+	TrackCalls(enabled bool)
+	CallStack() []cpu.CallFrame
+}
+
+// LockTarget is implemented by targets that report the CPU locking up on
+// an illegal opcode, see cpu.Locked
+type LockTarget interface {
+	Locked() bool
+}
+
+// Breakpoint stops execution before the instruction at an address
+type Breakpoint struct {
+	// ROM bank the address must be in, or -1 for any bank
+	Bank int
+	Addr uint16
+}
+
+func (b Breakpoint) String() string {
+	if b.Bank < 0 {
+		return fmt.Sprintf("$%04X", b.Addr)
+	}
+	return fmt.Sprintf("%02X:%04X", b.Bank, b.Addr)
+}
+
+// watchpoint is a watchpoint installed on the target
+type watchpoint struct {
+	id int
+	mmu.Watchpoint
+}
+
+// Debugger is a REPL controlling the emulator one instruction at a time
+type Debugger struct {
+	target  Target
+	disasm  *disasm.Disassembler
+	symbols *symbols.Table
+
+	in  *bufio.Scanner
+	out io.Writer
+
+	breakpoints []Breakpoint
+	lastCommand string
+
+	// Installed watchpoints and the accesses that hit them during the
+	// last instruction
+	watchpoints []watchpoint
+	watchHits   []mmu.Access
+
+	// Set to stop a running command, see Interrupt
+	interrupted atomic.Bool
+}
+
+// New creates a debugger reading commands from in and writing to out
+func New(target Target, in io.Reader, out io.Writer) *Debugger {
+	if stacker, ok := target.(CallStackTarget); ok {
+		stacker.TrackCalls(true)
+	}
+	return &Debugger{
+		target: target,
+		disasm: disasm.New(),
+		in:     bufio.NewScanner(in),
+		out:    out,
+	}
+}
+
+// SetSymbols sets the labels accepted as addresses and shown in listings
+func (d *Debugger) SetSymbols(table *symbols.Table) {
+	d.symbols = table
+	d.disasm.SetSymbols(table)
+}
+
+// Interrupt stops continue, next or finish before the next instruction.
+// It is safe to call from another goroutine, e.g. a signal handler.
+func (d *Debugger) Interrupt() {
+	d.interrupted.Store(true)
+}
+
+// Run reads and executes commands until quit or the end of the input
+func (d *Debugger) Run() error {
+	fmt.Fprintln(d.out, "Game Boy debugger, type 'help' for commands")
+	d.printLocation()
+
+	for {
+		fmt.Fprint(d.out, "(gb) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return d.in.Err()
+		}
+
+		if quit := d.Execute(d.in.Text()); quit {
+			return nil
+		}
+	}
+}
+
+// Execute runs one command line. An empty line repeats the last command.
+// It returns true when the debugger should exit.
+func (d *Debugger) Execute(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		line = d.lastCommand
+	}
+	if line == "" {
+		return false
+	}
+	d.lastCommand = line
+
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+
+	var err error
+	switch command {
+	case "help", "h", "?":
+		d.help()
+	case "break", "b":
+		err = d.cmdBreak(args)
+	case "delete":
+		err = d.cmdDelete(args)
+	case "watch", "w":
+		err = d.cmdWatch(args)
+	case "unwatch":
+		err = d.cmdUnwatch(args)
+	case "step", "s":
+		err = d.cmdStep(args)
+	case "next", "n":
+		err = d.cmdNext()
+	case "continue", "c":
+		err = d.run(func(executed byte) bool { return false })
+	case "finish":
+		err = d.cmdFinish()
+	case "backtrace", "bt":
+		err = d.cmdBacktrace()
+	case "regs", "r":
+		d.printRegisters()
+	case "mem", "x":
+		err = d.cmdMem(args)
+	case "disasm", "d":
+		err = d.cmdDisasm(args)
+	case "quit", "q":
+		return true
+	default:
+		err = fmt.Errorf("unknown command %q, type 'help' for commands", command)
+	}
+
+	if err != nil {
+		fmt.Fprintf(d.out, "Error: %v\n", err)
+	}
+	return false
+}
+
+func (d *Debugger) help() {
+	fmt.Fprint(d.out, `Commands:
+  break, b [addr|bank:addr]   Set a breakpoint, or list them without an address
+  delete [addr|bank:addr]     Delete a breakpoint, or all of them
+  watch, w [addr[-end]] [rwx] [=value]
+                              Stop on reads, writes or execution of an address
+                              range (default w), or list watchpoints
+  unwatch [n]                 Delete watchpoint n, or all of them
+  step, s [n]                 Execute n instructions (default 1)
+  next, n                     Step over a CALL or RST
+  continue, c                 Run until a breakpoint (Ctrl-C to stop)
+  finish                      Run until the current subroutine returns
+  backtrace, bt               Show the calls and interrupts leading to PC
+  regs, r                     Show the CPU registers
+  mem, x <addr> [len]         Dump memory (default 64 bytes)
+  disasm, d [addr] [count]    Disassemble instructions (default at PC, 10)
+  quit, q                     Exit the emulator
+Addresses are hexadecimal, optionally prefixed with $ or 0x, or labels from
+the symbol file with an optional +offset. An empty line repeats the last
+command.
+`)
+}
+
+func (d *Debugger) cmdBreak(args []string) error {
+	if len(args) == 0 {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints")
+		}
+		for i, bp := range d.breakpoints {
+			fmt.Fprintf(d.out, "%d: %s%s\n", i+1, bp, d.breakpointLabel(bp))
+		}
+		return nil
+	}
+
+	bp, err := d.parseBreakpoint(args[0])
+	if err != nil {
+		return err
+	}
+	for _, existing := range d.breakpoints {
+		if existing == bp {
+			return fmt.Errorf("breakpoint at %s already set", bp)
+		}
+	}
+
+	d.breakpoints = append(d.breakpoints, bp)
+	fmt.Fprintf(d.out, "Breakpoint %d at %s%s\n", len(d.breakpoints), bp, d.breakpointLabel(bp))
+	return nil
+}
+
+func (d *Debugger) cmdDelete(args []string) error {
+	if len(args) == 0 {
+		d.breakpoints = nil
+		fmt.Fprintln(d.out, "Deleted all breakpoints")
+		return nil
+	}
+
+	bp, err := d.parseBreakpoint(args[0])
+	if err != nil {
+		return err
+	}
+	for i, existing := range d.breakpoints {
+		if existing == bp {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			fmt.Fprintf(d.out, "Deleted breakpoint at %s\n", bp)
+			return nil
+		}
+	}
+	return fmt.Errorf("no breakpoint at %s", bp)
+}
+
+func (d *Debugger) cmdStep(args []string) error {
+	count := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q", args[0])
+		}
+		count = n
+	}
+
+	for i := 0; i < count; i++ {
+		if _, err := d.target.StepInstruction(); err != nil {
+			return err
+		}
+		d.checkExecuteWatch()
+		if d.reportWatchHits() || d.reportLockUp() {
+			break
+		}
+	}
+	d.printLocation()
+	return nil
+}
+
+// cmdNext steps over calls by running until the instruction after them
+func (d *Debugger) cmdNext() error {
+	start := d.target.Registers()
+	if !disasm.IsCall(d.target.ReadMemory(start.PC)) {
+		return d.cmdStep(nil)
+	}
+
+	// The SP check keeps recursive calls from stopping early
+	returnAddr := start.PC + uint16(d.disasm.Decode(d.target, start.PC).Len())
+	return d.run(func(executed byte) bool {
+		reg := d.target.Registers()
+		return reg.PC == returnAddr && reg.SP >= start.SP
+	})
+}
+
+// cmdFinish runs until a return pops the current stack frame
+func (d *Debugger) cmdFinish() error {
+	startSP := d.target.Registers().SP
+	return d.run(func(executed byte) bool {
+		return disasm.IsReturn(executed) && d.target.Registers().SP > startSP
+	})
+}
+
+// run executes instructions until done returns true after one of them, a
+// breakpoint or watchpoint is hit or the debugger is interrupted. done receives the
+// opcode that was just executed.
+func (d *Debugger) run(done func(executed byte) bool) error {
+	d.interrupted.Store(false)
+
+	for {
+		opcode := d.target.ReadMemory(d.target.Registers().PC)
+		if _, err := d.target.StepInstruction(); err != nil {
+			return err
+		}
+
+		d.checkExecuteWatch()
+		if d.reportWatchHits() || d.reportLockUp() {
+			break
+		}
+		if done(opcode) {
+			break
+		}
+		if d.interrupted.Load() {
+			fmt.Fprintln(d.out, "Interrupted")
+			break
+		}
+		if bp, ok := d.breakpointHit(); ok {
+			fmt.Fprintf(d.out, "Breakpoint at %s%s\n", bp, d.breakpointLabel(bp))
+			break
+		}
+	}
+
+	d.printLocation()
+	return nil
+}
+
+// reportLockUp prints a message if the CPU has locked up and reports
+// whether it has, as running it any further does nothing
+func (d *Debugger) reportLockUp() bool {
+	target, ok := d.target.(LockTarget)
+	if !ok || !target.Locked() {
+		return false
+	}
+	pc := d.target.Registers().PC
+	fmt.Fprintf(d.out, "CPU locked up by illegal opcode $%02X at %s\n", d.target.ReadMemory(pc), d.location(pc))
+	return true
+}
+
+// breakpointHit returns the breakpoint at PC, if any
+func (d *Debugger) breakpointHit() (Breakpoint, bool) {
+	pc := d.target.Registers().PC
+	for _, bp := range d.breakpoints {
+		if bp.Addr != pc {
+			continue
+		}
+		if bp.Bank < 0 || bp.Bank == symbols.BankAt(pc, d.target) {
+			return bp, true
+		}
+	}
+	return Breakpoint{}, false
+}
+
+func (d *Debugger) cmdWatch(args []string) error {
+	if len(args) == 0 {
+		if len(d.watchpoints) == 0 {
+			fmt.Fprintln(d.out, "No watchpoints")
+		}
+		for _, w := range d.watchpoints {
+			fmt.Fprintf(d.out, "%d: %s\n", w.id, formatWatchpoint(w.Watchpoint))
+		}
+		return nil
+	}
+
+	target, ok := d.target.(WatchTarget)
+	if !ok {
+		return fmt.Errorf("watchpoints are not supported")
+	}
+
+	w, err := d.parseWatchpoint(args)
+	if err != nil {
+		return err
+	}
+	w.Callback = func(a mmu.Access) {
+		// Execute watchpoints stop before the instruction, see
+		// checkExecuteWatch
+		if a.Kind != mmu.AccessExecute {
+			d.watchHits = append(d.watchHits, a)
+		}
+	}
+
+	id := target.AddWatchpoint(w)
+	d.watchpoints = append(d.watchpoints, watchpoint{id: id, Watchpoint: w})
+	fmt.Fprintf(d.out, "Watchpoint %d on %s\n", id, formatWatchpoint(w))
+	return nil
+}
+
+func (d *Debugger) cmdUnwatch(args []string) error {
+	target, ok := d.target.(WatchTarget)
+	if !ok {
+		return fmt.Errorf("watchpoints are not supported")
+	}
+
+	if len(args) == 0 {
+		for _, w := range d.watchpoints {
+			target.RemoveWatchpoint(w.id)
+		}
+		d.watchpoints = nil
+		fmt.Fprintln(d.out, "Deleted all watchpoints")
+		return nil
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid watchpoint number %q", args[0])
+	}
+	for i, w := range d.watchpoints {
+		if w.id == id {
+			target.RemoveWatchpoint(id)
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			fmt.Fprintf(d.out, "Deleted watchpoint %d\n", id)
+			return nil
+		}
+	}
+	return fmt.Errorf("no watchpoint %d", id)
+}
+
+// checkExecuteWatch adds a hit if the instruction at PC is watched for
+// execution. Like breakpoints, execute watchpoints stop before the
+// instruction runs rather than after it, as the MMU reports them.
+func (d *Debugger) checkExecuteWatch() {
+	pc := d.target.Registers().PC
+	for _, w := range d.watchpoints {
+		if w.Kinds&mmu.AccessExecute == 0 || pc < w.Start || pc > w.End {
+			continue
+		}
+		opcode := d.target.ReadMemory(pc)
+		if w.MatchValue && opcode != w.Value {
+			continue
+		}
+		d.watchHits = append(d.watchHits, mmu.Access{Kind: mmu.AccessExecute, Addr: pc, Value: opcode, Old: opcode})
+		return
+	}
+}
+
+// reportWatchHits prints the watchpoints hit by the last instruction and
+// reports whether there were any
+func (d *Debugger) reportWatchHits() bool {
+	if len(d.watchHits) == 0 {
+		return false
+	}
+	for _, a := range d.watchHits {
+		switch a.Kind {
+		case mmu.AccessWrite:
+			fmt.Fprintf(d.out, "Watchpoint: write $%04X = %02X (was %02X)\n", a.Addr, a.Value, a.Old)
+		case mmu.AccessRead:
+			fmt.Fprintf(d.out, "Watchpoint: read $%04X = %02X\n", a.Addr, a.Value)
+		case mmu.AccessExecute:
+			fmt.Fprintf(d.out, "Watchpoint: execute $%04X\n", a.Addr)
+		}
+	}
+	d.watchHits = d.watchHits[:0]
+	return true
+}
+
+// breakpointLabel names a breakpoint's address, with a leading space, or
+// returns "" if no label covers it
+func (d *Debugger) breakpointLabel(bp Breakpoint) string {
+	bank := bp.Bank
+	if bank < 0 {
+		bank = symbols.BankAt(bp.Addr, d.target)
+	}
+	if label := d.symbols.Label(bank, bp.Addr); label != "" {
+		return " (" + label + ")"
+	}
+	return ""
+}
+
+// cmdBacktrace lists the tracked call stack, innermost frame first. Each
+// caller is shown at its CALL, or at the instruction an interrupt preempted.
+func (d *Debugger) cmdBacktrace() error {
+	stacker, ok := d.target.(CallStackTarget)
+	if !ok {
+		return fmt.Errorf("the target does not track calls")
+	}
+
+	d.printFrame(0, d.target.Registers().PC, "")
+	stack := stacker.CallStack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		note := ""
+		if stack[i].Interrupt {
+			note = "  (interrupted)"
+		}
+		d.printFrame(len(stack)-i, stack[i].CallSite, note)
+	}
+	return nil
+}
+
+func (d *Debugger) printFrame(n int, addr uint16, note string) {
+	fmt.Fprintf(d.out, "#%-2d %s  %s%s\n", n, d.location(addr), d.symbols.LabelAt(addr, d.target), note)
+}
+
+// location formats addr with the ROM bank mapped there
+func (d *Debugger) location(addr uint16) string {
+	if bank := symbols.BankAt(addr, d.target); bank >= 0 {
+		return fmt.Sprintf("%02X:%04X", bank, addr)
+	}
+	return fmt.Sprintf("%04X", addr)
+}
+
+func (d *Debugger) printRegisters() {
+	reg := d.target.Registers()
+
+	flags := []byte("----")
+	for i, flag := range []struct {
+		mask byte
+		name byte
+	}{{cpu.FLAG_Z, 'Z'}, {cpu.FLAG_N, 'N'}, {cpu.FLAG_H, 'H'}, {cpu.FLAG_C, 'C'}} {
+		if reg.F&flag.mask != 0 {
+			flags[i] = flag.name
+		}
+	}
+
+	fmt.Fprintf(d.out, "AF=%04X BC=%04X DE=%04X HL=%04X SP=%04X PC=%04X  %s  ROM bank %d\n",
+		reg.GetAF(), reg.GetBC(), reg.GetDE(), reg.GetHL(), reg.SP, reg.PC, flags, d.target.CurrentROMBank())
+}
+
+func (d *Debugger) cmdMem(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mem <addr> [len]")
+	}
+	addr, err := d.parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	length := 64
+	if len(args) > 1 {
+		if length, err = strconv.Atoi(args[1]); err != nil || length < 1 {
+			return fmt.Errorf("invalid length %q", args[1])
+		}
+	}
+
+	for offset := 0; offset < length; offset += 16 {
+		lineAddr := addr + uint16(offset)
+		var hex, ascii strings.Builder
+		for i := 0; i < 16 && offset+i < length; i++ {
+			b := d.target.ReadMemory(lineAddr + uint16(i))
+			fmt.Fprintf(&hex, "%02X ", b)
+			if b >= 0x20 && b < 0x7F {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		fmt.Fprintf(d.out, "%04X: %-48s %s\n", lineAddr, hex.String(), ascii.String())
+	}
+	return nil
+}
+
+func (d *Debugger) cmdDisasm(args []string) error {
+	addr := d.target.Registers().PC
+	count := 10
+	var err error
+	if len(args) > 0 {
+		if addr, err = d.parseAddress(args[0]); err != nil {
+			return err
+		}
+	}
+	if len(args) > 1 {
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 1 {
+			return fmt.Errorf("invalid count %q", args[1])
+		}
+	}
+	for i := 0; i < count; i++ {
+		inst := d.disasm.Decode(d.target, addr)
+		d.printInstruction(inst)
+		addr += uint16(inst.Len())
+	}
+	return nil
+}
+
+// printLocation shows the next instruction to execute, under the label it
+// is part of
+func (d *Debugger) printLocation() {
+	inst := d.disasm.Decode(d.target, d.target.Registers().PC)
+	if label := d.symbols.LabelAt(inst.Addr, d.target); inst.Label == "" && label != "" {
+		fmt.Fprintf(d.out, "%s:\n", label)
+	}
+	d.printInstruction(inst)
+}
+
+func (d *Debugger) printInstruction(inst disasm.Instruction) {
+	marker := " "
+	if inst.Addr == d.target.Registers().PC {
+		marker = ">"
+	}
+
+	var hex strings.Builder
+	for _, b := range inst.Bytes {
+		fmt.Fprintf(&hex, "%02X ", b)
+	}
+
+	if inst.Label != "" {
+		fmt.Fprintf(d.out, "%s:\n", inst.Label)
+	}
+	fmt.Fprintf(d.out, "%s %s  %-9s %s\n", marker, d.location(inst.Addr), hex.String(), inst.Text())
+}
+
+// parseAddress parses a label, with an optional offset, or an address
+func (d *Debugger) parseAddress(s string) (uint16, error) {
+	if sym, ok := d.symbols.Resolve(s); ok {
+		return sym.Addr, nil
+	}
+	return parseAddress(s)
+}
+
+// parseAddress parses a hexadecimal address with an optional $ or 0x prefix
+func parseAddress(s string) (uint16, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0x"), "$")
+	value, err := strconv.ParseUint(trimmed, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(value), nil
+}
+
+// parseWatchpoint parses addr[-end] [rwx] [=value]
+func (d *Debugger) parseWatchpoint(args []string) (mmu.Watchpoint, error) {
+	startText, endText, isRange := strings.Cut(args[0], "-")
+	start, err := d.parseAddress(startText)
+	if err != nil {
+		return mmu.Watchpoint{}, err
+	}
+	w := mmu.Watchpoint{Start: start, End: start, Kinds: mmu.AccessWrite}
+	if isRange {
+		if w.End, err = d.parseAddress(endText); err != nil {
+			return mmu.Watchpoint{}, err
+		}
+		if w.End < w.Start {
+			return mmu.Watchpoint{}, fmt.Errorf("invalid range %q", args[0])
+		}
+	}
+
+	for _, arg := range args[1:] {
+		if valueText, ok := strings.CutPrefix(arg, "="); ok {
+			value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(valueText), "0x"), 16, 8)
+			if err != nil {
+				return mmu.Watchpoint{}, fmt.Errorf("invalid value %q", valueText)
+			}
+			w.MatchValue = true
+			w.Value = byte(value)
+			continue
+		}
+
+		w.Kinds = 0
+		for _, c := range arg {
+			switch c {
+			case 'r':
+				w.Kinds |= mmu.AccessRead
+			case 'w':
+				w.Kinds |= mmu.AccessWrite
+			case 'x':
+				w.Kinds |= mmu.AccessExecute
+			default:
+				return mmu.Watchpoint{}, fmt.Errorf("invalid access kinds %q, use r, w and x", arg)
+			}
+		}
+	}
+	return w, nil
+}
+
+func formatWatchpoint(w mmu.Watchpoint) string {
+	s := fmt.Sprintf("$%04X", w.Start)
+	if w.End != w.Start {
+		s += fmt.Sprintf("-$%04X", w.End)
+	}
+	s += " " + w.Kinds.String()
+	if w.MatchValue {
+		s += fmt.Sprintf(" =%02X", w.Value)
+	}
+	return s
+}
+
+// parseBreakpoint parses a label, addr or bank:addr. Breakpoints on ROM
+// labels only stop in the label's bank.
+func (d *Debugger) parseBreakpoint(s string) (Breakpoint, error) {
+	if sym, ok := d.symbols.Resolve(s); ok {
+		return Breakpoint{Bank: sym.ROMBank(), Addr: sym.Addr}, nil
+	}
+
+	bankText, addrText, hasBank := strings.Cut(s, ":")
+	if !hasBank {
+		addr, err := parseAddress(s)
+		return Breakpoint{Bank: -1, Addr: addr}, err
+	}
+
+	bank, err := strconv.ParseUint(strings.TrimPrefix(bankText, "$"), 16, 16)
+	if err != nil {
+		return Breakpoint{}, fmt.Errorf("invalid bank %q", bankText)
+	}
+	addr, err := parseAddress(addrText)
+	if err != nil {
+		return Breakpoint{}, err
+	}
+	if addr >= 0x8000 {
+		return Breakpoint{}, fmt.Errorf("banked breakpoints must be in ROM (0000-7FFF)")
+	}
+	return Breakpoint{Bank: int(bank), Addr: addr}, nil
+}
@@ -0,0 +1,309 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/mmu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// program calls a subroutine in a loop
+//
+//	0100: CALL $0110
+//	0103: INC B
+//	0104: JR $0100
+//	0110: INC C
+//	0111: RET
+var program = map[uint16][]byte{
+	0x0100: {0xCD, 0x10, 0x01, 0x04, 0x18, 0xFA},
+	0x0110: {0x0C, 0xC9},
+}
+
+// newTestDebugger creates a debugger for a CPU running program
+func newTestDebugger(t *testing.T) (*Debugger, *MockTarget, *bytes.Buffer) {
+	t.Helper()
+
+	target := newMockTarget()
+	for addr, code := range program {
+		copy(target.memory[addr:], code)
+	}
+
+	var out bytes.Buffer
+	d := New(target, strings.NewReader(""), &out)
+	return d, target, &out
+}
+
+// TestBreakContinue tests stopping at a breakpoint
+func TestBreakContinue(t *testing.T) {
+	d, target, out := newTestDebugger(t)
+
+	d.Execute("break 0111")
+	d.Execute("continue")
+	if pc := target.Registers().PC; pc != 0x0111 {
+		t.Errorf("Expected PC 0x0111, got 0x%04X", pc)
+	}
+	if !strings.Contains(out.String(), "Breakpoint at $0111") {
+		t.Errorf("Expected breakpoint message, got:\n%s", out.String())
+	}
+
+	// Continuing from the breakpoint runs the loop once more
+	d.Execute("continue")
+	if c := target.Registers().C; c != 0x15 {
+		t.Errorf("Expected C to be 0x15 after two calls, got 0x%02X", c)
+	}
+}
+
+// TestBankedBreakpoint tests that banked breakpoints only match their bank
+func TestBankedBreakpoint(t *testing.T) {
+	d, target, _ := newTestDebugger(t)
+
+	d.Execute("break 2:4000")
+	d.Execute("break 1:4000")
+
+	target.cpu.SetRegisters(cpu.Registers{PC: 0x4000})
+	if bp, ok := d.breakpointHit(); !ok || bp.Bank != 1 {
+		t.Errorf("Expected the bank 1 breakpoint to match, got %v %v", bp, ok)
+	}
+
+	if _, err := d.parseBreakpoint("1:C000"); err == nil {
+		t.Error("Expected an error for a banked breakpoint outside ROM")
+	}
+}
+
+// TestNextFinish tests stepping over and out of a call
+func TestNextFinish(t *testing.T) {
+	d, target, _ := newTestDebugger(t)
+
+	d.Execute("next")
+	if reg := target.Registers(); reg.PC != 0x0103 || reg.C != 0x14 {
+		t.Errorf("Expected to step over the call to 0x0103 with C=0x14, got PC=0x%04X C=0x%02X", reg.PC, reg.C)
+	}
+
+	d.Execute("step 3")
+	if pc := target.Registers().PC; pc != 0x0110 {
+		t.Fatalf("Expected to step into the call at 0x0110, got 0x%04X", pc)
+	}
+
+	d.Execute("finish")
+	if pc := target.Registers().PC; pc != 0x0103 {
+		t.Errorf("Expected finish to return to 0x0103, got 0x%04X", pc)
+	}
+}
+
+// TestMemDisasm tests the memory dump and disassembly output
+func TestMemDisasm(t *testing.T) {
+	d, _, out := newTestDebugger(t)
+
+	d.Execute("mem 0100 6")
+	if !strings.Contains(out.String(), "0100: CD 10 01 04 18 FA") {
+		t.Errorf("Expected memory dump, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	d.Execute("disasm 0100 3")
+	for _, expected := range []string{"CALL $0110", "INC B", "JR $0100"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in disassembly, got:\n%s", expected, out.String())
+		}
+	}
+}
+
+// TestWatch tests stopping on a write watchpoint
+func TestWatch(t *testing.T) {
+	d, target, out := newTestDebugger(t)
+
+	// The CALL pushes its return address below SP
+	sp := target.Registers().SP
+	d.Execute(fmt.Sprintf("watch %04X-%04X w", sp-2, sp-1))
+	d.Execute("continue")
+	if pc := target.Registers().PC; pc != 0x0110 {
+		t.Errorf("Expected to stop after the CALL at 0x0110, got 0x%04X", pc)
+	}
+	if !strings.Contains(out.String(), "Watchpoint: write") {
+		t.Errorf("Expected watchpoint message, got:\n%s", out.String())
+	}
+
+	d.Execute("unwatch")
+	if len(target.watchpoints) != 0 {
+		t.Errorf("Expected the watchpoints to be removed, got %d", len(target.watchpoints))
+	}
+
+	w, err := d.parseWatchpoint([]string{"C000-C0FF", "rw", "=3F"})
+	if err != nil {
+		t.Fatalf("Failed to parse watchpoint: %v", err)
+	}
+	expected := mmu.Watchpoint{Start: 0xC000, End: 0xC0FF, Kinds: mmu.AccessRead | mmu.AccessWrite, MatchValue: true, Value: 0x3F}
+	if formatWatchpoint(w) != formatWatchpoint(expected) {
+		t.Errorf("Expected %s, got %s", formatWatchpoint(expected), formatWatchpoint(w))
+	}
+}
+
+// TestWatchExecute tests that execute watchpoints stop before the
+// instruction, like breakpoints
+func TestWatchExecute(t *testing.T) {
+	d, target, out := newTestDebugger(t)
+
+	d.Execute("watch 0110-0111 x =C9")
+	d.Execute("continue")
+	if pc := target.Registers().PC; pc != 0x0111 {
+		t.Errorf("Expected to stop before the RET at 0x0111, got 0x%04X", pc)
+	}
+	if !strings.Contains(out.String(), "Watchpoint: execute $0111") {
+		t.Errorf("Expected watchpoint message, got:\n%s", out.String())
+	}
+
+	// Stepping runs the watched instruction without stopping on it again
+	out.Reset()
+	d.Execute("step")
+	if pc := target.Registers().PC; pc != 0x0103 || strings.Contains(out.String(), "Watchpoint") {
+		t.Errorf("Expected to step to 0x0103 without a watchpoint, got 0x%04X:\n%s", pc, out.String())
+	}
+}
+
+// TestSymbols tests labels in commands, listings and the backtrace
+func TestSymbols(t *testing.T) {
+	d, target, out := newTestDebugger(t)
+	table, err := symbols.Parse(strings.NewReader("00:0100 Main\n00:0110 Sub\n00:C000 wCounter\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse symbols: %v", err)
+	}
+	d.SetSymbols(table)
+
+	d.Execute("break Sub+1")
+	d.Execute("continue")
+	if pc := target.Registers().PC; pc != 0x0111 {
+		t.Errorf("Expected to stop at Sub+1 (0x0111), got 0x%04X", pc)
+	}
+
+	d.Execute("backtrace")
+	for _, expected := range []string{"Breakpoint at 00:0111 (Sub+$1)", "#0  00:0111  Sub+$1", "#1  00:0100  Main"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the output, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	d.Execute("disasm Main 3")
+	for _, expected := range []string{"Main:", "CALL Sub", "JR Main"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in disassembly, got:\n%s", expected, out.String())
+		}
+	}
+
+	if w, err := d.parseWatchpoint([]string{"wCounter"}); err != nil || w.Start != 0xC000 {
+		t.Errorf("Expected a watchpoint on wCounter at 0xC000, got %s %v", formatWatchpoint(w), err)
+	}
+}
+
+// TestLockUp tests that running stops when an illegal opcode locks up the
+// CPU
+func TestLockUp(t *testing.T) {
+	d, target, out := newTestDebugger(t)
+	target.memory[0x0103] = 0xDD
+
+	d.Execute("continue")
+	if pc := target.Registers().PC; pc != 0x0103 {
+		t.Errorf("Expected to stop at the illegal opcode at 0x0103, got 0x%04X", pc)
+	}
+	if !strings.Contains(out.String(), "CPU locked up by illegal opcode $DD at 00:0103") {
+		t.Errorf("Expected a lock up message, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	d.Execute("step 5")
+	if !strings.Contains(out.String(), "CPU locked up") {
+		t.Errorf("Expected stepping to report the lock up, got:\n%s", out.String())
+	}
+}
+
+// MockTarget runs a CPU over a flat 64KB memory
+type MockTarget struct {
+	cpu         *cpu.Z80
+	memory      [0x10000]byte
+	watchpoints map[int]mmu.Watchpoint
+	nextWatchID int
+}
+
+func newMockTarget() *MockTarget {
+	t := &MockTarget{}
+	t.cpu, _ = cpu.NewCPU(t)
+	return t
+}
+
+func (t *MockTarget) StepInstruction() (int, error) {
+	return t.cpu.Step(), nil
+}
+
+func (t *MockTarget) Registers() cpu.Registers {
+	return t.cpu.GetRegisters()
+}
+
+func (t *MockTarget) ReadMemory(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) CurrentROMBank() int {
+	return 1
+}
+
+func (t *MockTarget) Locked() bool {
+	return t.cpu.Locked()
+}
+
+func (t *MockTarget) TrackCalls(enabled bool) {
+	t.cpu.TrackCalls(enabled)
+}
+
+func (t *MockTarget) CallStack() []cpu.CallFrame {
+	return t.cpu.CallStack()
+}
+
+func (t *MockTarget) ReadByte(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) WriteByte(addr uint16, value byte) {
+	old := t.memory[addr]
+	t.memory[addr] = value
+	for _, w := range t.watchpoints {
+		if w.Kinds&mmu.AccessWrite != 0 && addr >= w.Start && addr <= w.End {
+			w.Callback(mmu.Access{Kind: mmu.AccessWrite, Addr: addr, Value: value, Old: old})
+		}
+	}
+}
+
+func (t *MockTarget) WatchExecute(addr uint16) {
+	for _, w := range t.watchpoints {
+		if w.Kinds&mmu.AccessExecute != 0 && addr >= w.Start && addr <= w.End {
+			w.Callback(mmu.Access{Kind: mmu.AccessExecute, Addr: addr, Value: t.memory[addr], Old: t.memory[addr]})
+		}
+	}
+}
+
+func (t *MockTarget) AddWatchpoint(w mmu.Watchpoint) int {
+	if t.watchpoints == nil {
+		t.watchpoints = make(map[int]mmu.Watchpoint)
+	}
+	t.nextWatchID++
+	t.watchpoints[t.nextWatchID] = w
+	return t.nextWatchID
+}
+
+func (t *MockTarget) RemoveWatchpoint(id int) bool {
+	_, ok := t.watchpoints[id]
+	delete(t.watchpoints, id)
+	return ok
+}
+
+func (t *MockTarget) ReadWord(addr uint16) uint16 {
+	return uint16(t.memory[addr]) | (uint16(t.memory[addr+1]) << 8)
+}
+
+func (t *MockTarget) WriteWord(addr uint16, value uint16) {
+	t.WriteByte(addr, byte(value&0xFF))
+	t.WriteByte(addr+1, byte(value>>8))
+}
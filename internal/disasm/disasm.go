@@ -0,0 +1,201 @@
+// Package disasm disassembles Game Boy machine code using the gbdev opcode
+// table in docs/Opcodes.json
+package disasm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/briancain/gameboy-go/docs"
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// Memory is read to decode instructions. If it also implements
+// symbols.ROMBanker, labels are looked up in the ROM bank it has mapped.
+type Memory interface {
+	ReadMemory(addr uint16) byte
+}
+
+// Instruction is a decoded instruction
+type Instruction struct {
+	Addr     uint16
+	Bytes    []byte
+	Mnemonic string
+
+	// Operands with immediate data and relative jump targets resolved, and
+	// replaced by labels when there are symbols
+	Operands []string
+
+	// Label defined at Addr, if any
+	Label string
+}
+
+// Len returns the length of the instruction in bytes
+func (i Instruction) Len() int {
+	return len(i.Bytes)
+}
+
+// Text returns the instruction in assembly syntax, e.g. "LD A, [$FF44]"
+func (i Instruction) Text() string {
+	if len(i.Operands) == 0 {
+		return i.Mnemonic
+	}
+	return i.Mnemonic + " " + strings.Join(i.Operands, ", ")
+}
+
+// loadOpcodes parses the embedded opcode table once
+var loadOpcodes = sync.OnceValue(func() *cpu.OpcodesData {
+	opcodes, err := cpu.ParseOpcodes(docs.OpcodesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("disasm: embedded opcode table: %v", err))
+	}
+	return opcodes
+})
+
+// loadLengths builds the length of every unprefixed opcode once, for Length
+var loadLengths = sync.OnceValue(func() [256]int {
+	opcodes := loadOpcodes()
+	var lengths [256]int
+	for i := range lengths {
+		lengths[i] = 1
+		if info := opcodes.GetOpcodeInfo(byte(i), false); info != nil && info.Bytes > 0 {
+			lengths[i] = info.Bytes
+		}
+	}
+	lengths[0xCB] = 2
+	return lengths
+})
+
+// Length returns the length in bytes of the instruction starting with
+// opcode, without decoding it. Invalid opcodes are one byte, like in Decode.
+func Length(opcode byte) int {
+	return loadLengths()[opcode]
+}
+
+// Disassembler decodes instructions with an opcode table
+type Disassembler struct {
+	opcodes *cpu.OpcodesData
+	symbols *symbols.Table
+}
+
+// New creates a disassembler using the opcode table embedded from
+// docs/Opcodes.json
+func New() *Disassembler {
+	return &Disassembler{opcodes: loadOpcodes()}
+}
+
+// SetSymbols sets the labels shown for addresses, nil for none
+func (d *Disassembler) SetSymbols(table *symbols.Table) {
+	d.symbols = table
+}
+
+// Decode disassembles the instruction at addr. Bytes that are not valid
+// opcodes decode as a one byte DB directive.
+func (d *Disassembler) Decode(mem Memory, addr uint16) Instruction {
+	opcode := mem.ReadMemory(addr)
+
+	var info *cpu.OpcodeInfo
+	if opcode == 0xCB {
+		info = d.opcodes.GetOpcodeInfo(mem.ReadMemory(addr+1), true)
+	} else {
+		info = d.opcodes.GetOpcodeInfo(opcode, false)
+	}
+
+	banker, _ := mem.(symbols.ROMBanker)
+	var instLabel string
+	if sym, ok := d.symbols.At(symbols.BankAt(addr, banker), addr); ok {
+		instLabel = sym.Name
+	}
+
+	if info == nil || strings.HasPrefix(info.Mnemonic, "ILLEGAL") {
+		return Instruction{
+			Addr:     addr,
+			Bytes:    []byte{opcode},
+			Mnemonic: "DB",
+			Operands: []string{fmt.Sprintf("$%02X", opcode)},
+			Label:    instLabel,
+		}
+	}
+
+	inst := Instruction{Addr: addr, Mnemonic: info.Mnemonic, Label: instLabel}
+	for i := 0; i < info.Bytes; i++ {
+		inst.Bytes = append(inst.Bytes, mem.ReadMemory(addr+uint16(i)))
+	}
+
+	// Immediate data follows the opcode, CB opcodes have none
+	immediate := inst.Bytes[1:]
+	if opcode == 0xCB {
+		immediate = nil
+	}
+	next := addr + uint16(info.Bytes)
+
+	for i := 0; i < len(info.Operands); i++ {
+		operand := info.Operands[i]
+
+		// LD HL,SP+e8 lists SP and the offset as separate operands
+		if inc, _ := operand["increment"].(bool); inc && operand["name"] == "SP" {
+			inst.Operands = append(inst.Operands, fmt.Sprintf("SP%+d", int8(immediate[0])))
+			i++
+			continue
+		}
+
+		inst.Operands = append(inst.Operands, d.formatOperand(info.Mnemonic, operand, immediate, next, banker))
+	}
+	return inst
+}
+
+// formatOperand formats one operand from the opcode table. next is the
+// address after the instruction, for relative jump targets.
+func (d *Disassembler) formatOperand(mnemonic string, operand map[string]interface{}, immediate []byte, next uint16, banker symbols.ROMBanker) string {
+	name, _ := operand["name"].(string)
+
+	var text string
+	switch name {
+	case "n8":
+		text = fmt.Sprintf("$%02X", immediate[0])
+	case "a8":
+		text = d.address(0xFF00|uint16(immediate[0]), banker, false)
+	case "a16":
+		text = d.address(uint16(immediate[0])|uint16(immediate[1])<<8, banker, false)
+	case "n16":
+		// Often an address, but only an exact label match is likely to be
+		// one
+		text = d.address(uint16(immediate[0])|uint16(immediate[1])<<8, banker, true)
+	case "e8":
+		offset := int8(immediate[0])
+		if mnemonic == "JR" {
+			text = d.address(next+uint16(offset), banker, false)
+		} else {
+			text = fmt.Sprintf("%d", offset)
+		}
+	default:
+		text = name
+	}
+
+	if inc, _ := operand["increment"].(bool); inc {
+		text += "+"
+	}
+	if dec, _ := operand["decrement"].(bool); dec {
+		text += "-"
+	}
+	if imm, _ := operand["immediate"].(bool); !imm {
+		text = "[" + text + "]"
+	}
+	return text
+}
+
+// address formats an address as its label, or in hex if no label covers it.
+// With exact set, only a label defined at addr is used.
+func (d *Disassembler) address(addr uint16, banker symbols.ROMBanker, exact bool) string {
+	bank := symbols.BankAt(addr, banker)
+	if exact {
+		if sym, ok := d.symbols.At(bank, addr); ok {
+			return sym.Name
+		}
+	} else if label := d.symbols.Label(bank, addr); label != "" {
+		return label
+	}
+	return fmt.Sprintf("$%04X", addr)
+}
@@ -0,0 +1,83 @@
+package disasm
+
+import "testing"
+
+// TestDecode tests decoding instructions with each kind of operand
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   []byte
+		text   string
+		length int
+	}{
+		{"no operands", []byte{0x00}, "NOP", 1},
+		{"d8", []byte{0x3E, 0x12}, "LD A, $12", 2},
+		{"d16", []byte{0x21, 0x34, 0x12}, "LD HL, $1234", 3},
+		{"a16", []byte{0xEA, 0x00, 0xC0}, "LD [$C000], A", 3},
+		{"a8", []byte{0xE0, 0x44}, "LDH [$FF44], A", 2},
+		{"jr forward", []byte{0x18, 0x05}, "JR $0107", 2},
+		{"jr backward", []byte{0x20, 0xFE}, "JR NZ, $0100", 2},
+		{"sp offset", []byte{0xE8, 0xFE}, "ADD SP, -2", 2},
+		{"hl sp offset", []byte{0xF8, 0x05}, "LD HL, SP+5", 2},
+		{"hl increment", []byte{0x2A}, "LD A, [HL+]", 1},
+		{"hl decrement", []byte{0x32}, "LD [HL-], A", 1},
+		{"cb prefixed", []byte{0xCB, 0x7C}, "BIT 7, H", 2},
+		{"rst", []byte{0xFF}, "RST $38", 1},
+		{"illegal", []byte{0xD3}, "DB $D3", 1},
+	}
+
+	d := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mem := &MockMemory{}
+			copy(mem.data[0x0100:], test.code)
+
+			inst := d.Decode(mem, 0x0100)
+			if inst.Text() != test.text {
+				t.Errorf("Expected %q, got %q", test.text, inst.Text())
+			}
+			if inst.Len() != test.length {
+				t.Errorf("Expected length %d, got %d", test.length, inst.Len())
+			}
+			if n := Length(test.code[0]); n != test.length {
+				t.Errorf("Expected Length %d, got %d", test.length, n)
+			}
+		})
+	}
+}
+
+// TestBankedROM tests reading the ROM through a bank mapping
+func TestBankedROM(t *testing.T) {
+	data := make([]byte, 4*ROM_BANK_SIZE)
+	for bank := 0; bank < 4; bank++ {
+		data[bank*ROM_BANK_SIZE] = byte(bank)
+	}
+	rom := NewBankedROM(data, 2)
+
+	if rom.Banks() != 4 {
+		t.Errorf("Expected 4 banks, got %d", rom.Banks())
+	}
+	if value := rom.ReadMemory(0x0000); value != 0 {
+		t.Errorf("Expected bank 0 at 0x0000, got %d", value)
+	}
+	if value := rom.ReadMemory(0x4000); value != 2 {
+		t.Errorf("Expected bank 2 at 0x4000, got %d", value)
+	}
+
+	rom.SetBank(5)
+	if value := rom.ReadMemory(0x4000); value != 0xFF {
+		t.Errorf("Expected 0xFF past the end of the ROM, got 0x%02X", value)
+	}
+	if bank := rom.BankAt(0xC000); bank != -1 {
+		t.Errorf("Expected no bank outside ROM, got %d", bank)
+	}
+}
+
+// MockMemory is a flat 64KB memory
+type MockMemory struct {
+	data [0x10000]byte
+}
+
+func (m *MockMemory) ReadMemory(addr uint16) byte {
+	return m.data[addr]
+}
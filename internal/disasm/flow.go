@@ -0,0 +1,24 @@
+package disasm
+
+// Opcodes of instructions that call a subroutine
+var callOpcodes = map[byte]bool{
+	0xCD: true, 0xC4: true, 0xCC: true, 0xD4: true, 0xDC: true, // CALL
+	0xC7: true, 0xCF: true, 0xD7: true, 0xDF: true, // RST
+	0xE7: true, 0xEF: true, 0xF7: true, 0xFF: true,
+}
+
+// Opcodes of instructions that return from a subroutine
+var returnOpcodes = map[byte]bool{
+	0xC9: true, 0xD9: true, // RET, RETI
+	0xC0: true, 0xC8: true, 0xD0: true, 0xD8: true, // RET cc
+}
+
+// IsCall reports whether opcode is a CALL or RST
+func IsCall(opcode byte) bool {
+	return callOpcodes[opcode]
+}
+
+// IsReturn reports whether opcode is a RET or RETI, conditional or not
+func IsReturn(opcode byte) bool {
+	return returnOpcodes[opcode]
+}
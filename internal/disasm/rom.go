@@ -0,0 +1,67 @@
+package disasm
+
+// ROM_BANK_SIZE is the size of a switchable ROM bank
+const ROM_BANK_SIZE = 0x4000
+
+// BankedROM reads a ROM image with one bank mapped at 0x4000-0x7FFF, the
+// way the CPU sees it. It disassembles code without running the ROM.
+type BankedROM struct {
+	rom  []byte
+	bank int
+}
+
+// NewBankedROM creates a reader for rom with bank mapped at 0x4000
+func NewBankedROM(rom []byte, bank int) *BankedROM {
+	return &BankedROM{rom: rom, bank: bank}
+}
+
+// Banks returns the number of 16KB banks in the ROM
+func (r *BankedROM) Banks() int {
+	return (len(r.rom) + ROM_BANK_SIZE - 1) / ROM_BANK_SIZE
+}
+
+// SetBank maps bank at 0x4000-0x7FFF
+func (r *BankedROM) SetBank(bank int) {
+	r.bank = bank
+}
+
+// Bank returns the bank mapped at 0x4000-0x7FFF
+func (r *BankedROM) Bank() int {
+	return r.bank
+}
+
+// CurrentROMBank returns the bank mapped at 0x4000-0x7FFF, so labels are
+// looked up in it
+func (r *BankedROM) CurrentROMBank() int {
+	return r.bank
+}
+
+// BankAt returns the bank visible at addr, or -1 outside ROM
+func (r *BankedROM) BankAt(addr uint16) int {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return 0
+	case addr < 2*ROM_BANK_SIZE:
+		return r.bank
+	default:
+		return -1
+	}
+}
+
+// ReadMemory reads a byte of the ROM. Addresses outside the ROM read 0xFF,
+// like an open bus.
+func (r *BankedROM) ReadMemory(addr uint16) byte {
+	offset := int(addr)
+	switch r.BankAt(addr) {
+	case -1:
+		return 0xFF
+	case 0:
+	default:
+		offset = r.bank*ROM_BANK_SIZE + int(addr) - ROM_BANK_SIZE
+	}
+
+	if offset >= len(r.rom) {
+		return 0xFF
+	}
+	return r.rom[offset]
+}
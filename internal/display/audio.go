@@ -0,0 +1,53 @@
+package display
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/sound"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// AudioHandler is implemented by emulators that produce audio samples
+type AudioHandler interface {
+	AddSampleSink(sink sound.SampleSink)
+	SetSampleRate(rate int)
+}
+
+// EnableAudio plays the emulator's audio through ebiten. Samples are handed
+// from the emulator to the audio player through a lock-free ring buffer, and
+// bufferSize sets how much audio the player buffers ahead (lower values
+// reduce latency but underrun more easily).
+func (d *EbitenDisplay) EnableAudio(sampleRate int, bufferSize time.Duration) error {
+	handler, ok := d.emulator.(AudioHandler)
+	if !ok {
+		return errors.New("emulator does not produce audio")
+	}
+	if sampleRate <= 0 {
+		sampleRate = sound.DEFAULT_SAMPLE_RATE
+	}
+
+	// Leave room for a few player buffers so frame time jitter doesn't drop samples
+	bufferSamples := int(bufferSize.Seconds() * float64(sampleRate))
+	ring := sound.NewSampleRing(bufferSamples * 4)
+
+	context := audio.NewContext(sampleRate)
+	player, err := context.NewPlayerF32(ring)
+	if err != nil {
+		return err
+	}
+	if bufferSize > 0 {
+		player.SetBufferSize(bufferSize)
+	}
+
+	handler.SetSampleRate(sampleRate)
+	handler.AddSampleSink(ring)
+	player.Play()
+
+	d.audioPlayer = player
+	d.audioRing = ring
+
+	log.Printf("Audio output enabled: %d Hz, %v buffer", sampleRate, bufferSize)
+	return nil
+}
@@ -1,10 +1,15 @@
 package display
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/rewind"
+	"github.com/briancain/gameboy-go/internal/sound"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
@@ -34,6 +39,19 @@ type EbitenDisplay struct {
 
 	// Debug counters
 	stepCount int
+
+	// Whether the rewind key is held this frame
+	rewinding bool
+
+	// Cycles still to run to keep emulation at the hardware clock rate
+	cycleDebt int
+
+	// Audio output, nil when audio is disabled
+	audioPlayer *audio.Player
+	audioRing   *sound.SampleRing
+
+	// Why the CPU locked up, shown over the screen until it runs again
+	lockup string
 }
 
 // Emulator interface for the display to interact with the core
@@ -51,19 +69,46 @@ type InputHandler interface {
 	SetButtonState(button string, pressed bool)
 }
 
+// StateHandler is implemented by emulators that support quick save states
+type StateHandler interface {
+	QuickSave() error
+	QuickLoad() error
+}
+
+// RewindHandler is implemented by emulators that can step back in time
+type RewindHandler interface {
+	Rewind(frames int) error
+}
+
+// LockupReporter is implemented by emulators that announce CPU lock-ups
+type LockupReporter interface {
+	OnLockup(handler func(cpu.Fault))
+	Locked() bool
+}
+
 // NewEbitenDisplay creates a new ebiten-based display
 func NewEbitenDisplay(emulator Emulator, inputHandler InputHandler, scale int, debug bool) *EbitenDisplay {
 	if scale < 1 || scale > 4 {
 		scale = 2 // Default scale
 	}
 
-	return &EbitenDisplay{
+	d := &EbitenDisplay{
 		screenImage:  ebiten.NewImage(SCREEN_WIDTH, SCREEN_HEIGHT),
 		scale:        scale,
 		emulator:     emulator,
 		inputHandler: inputHandler,
 		debug:        debug,
 	}
+	if reporter, ok := emulator.(LockupReporter); ok {
+		reporter.OnLockup(d.onLockup)
+	}
+	return d
+}
+
+// onLockup logs a CPU lock-up and keeps it to show over the screen
+func (d *EbitenDisplay) onLockup(f cpu.Fault) {
+	log.Printf("CPU locked up: %s", f)
+	d.lockup = f.String()
 }
 
 // Update is called every frame by ebiten
@@ -72,19 +117,19 @@ func (d *EbitenDisplay) Update() error {
 	d.handleInput()
 
 	// Step the emulator for the right number of cycles per frame
-	// GameBoy runs at ~70,224 cycles per frame at 60 FPS
-	if d.emulator.IsRunning() {
-		// Run approximately 1/60th of GameBoy cycles per update
-		cyclesThisUpdate := 0
-		targetCycles := 1170 // 70224 / 60
+	// GameBoy runs at 4,194,304 cycles per second, ~69,905 per update at 60 TPS
+	if d.emulator.IsRunning() && !d.rewinding {
+		// Carry over the cycles an instruction overshot last time so the
+		// emulated clock (and the audio it produces) keeps real time
+		d.cycleDebt += sound.CLOCK_RATE / 60
 
-		for cyclesThisUpdate < targetCycles {
+		for d.cycleDebt > 0 {
 			cycles, err := d.emulator.StepInstruction()
 			if err != nil {
 				log.Printf("Emulator step error: %v", err)
 				return err
 			}
-			cyclesThisUpdate += cycles
+			d.cycleDebt -= cycles
 			d.stepCount++ // Count total steps for debugging
 		}
 	}
@@ -112,6 +157,21 @@ func (d *EbitenDisplay) Draw(screen *ebiten.Image) {
 	if d.debug {
 		d.drawDebugInfo(screen)
 	}
+
+	d.drawLockup(screen)
+}
+
+// drawLockup tells the player the CPU locked up, until a reset, quick load
+// or rewind gets it running again
+func (d *EbitenDisplay) drawLockup(screen *ebiten.Image) {
+	if d.lockup == "" {
+		return
+	}
+	if reporter, ok := d.emulator.(LockupReporter); !ok || !reporter.Locked() {
+		d.lockup = ""
+		return
+	}
+	ebitenutil.DebugPrintAt(screen, "CPU locked up\n"+d.lockup, 0, SCREEN_HEIGHT*d.scale-32)
 }
 
 // Layout returns the screen size
@@ -174,6 +234,29 @@ func (d *EbitenDisplay) handleInput() {
 		}
 	}
 
+	// Handle quick save (F5) and quick load (F9)
+	if states, ok := d.emulator.(StateHandler); ok {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+			if err := states.QuickSave(); err != nil {
+				log.Printf("Quick save failed: %v", err)
+			}
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+			if err := states.QuickLoad(); err != nil {
+				log.Printf("Quick load failed: %v", err)
+			}
+		}
+	}
+
+	// Handle rewind (hold Backspace), one frame back per displayed frame
+	d.rewinding = false
+	if rewinder, ok := d.emulator.(RewindHandler); ok && ebiten.IsKeyPressed(ebiten.KeyBackspace) {
+		d.rewinding = true
+		if err := rewinder.Rewind(1); err != nil && !errors.Is(err, rewind.ErrEmpty) {
+			log.Printf("Rewind failed: %v", err)
+		}
+	}
+
 	// Handle quit (ESC key)
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		d.emulator.Exit()
@@ -186,6 +269,10 @@ func (d *EbitenDisplay) drawDebugInfo(screen *ebiten.Image) {
 	fps := ebiten.ActualFPS()
 	debugText := fmt.Sprintf("FPS: %.2f", fps)
 	debugText += fmt.Sprintf("\nSteps: %d", d.stepCount)
+	if d.audioRing != nil {
+		debugText += fmt.Sprintf("\nAudio: %d buffered, %d dropped, %d underruns",
+			d.audioRing.Len(), d.audioRing.Dropped(), d.audioRing.Underruns())
+	}
 
 	// Add PPU debug info
 	ppuInfo := d.emulator.GetPPUDebugInfo()
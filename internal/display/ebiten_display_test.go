@@ -2,6 +2,8 @@ package display
 
 import (
 	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
 )
 
 // Mock emulator for testing
@@ -121,3 +123,32 @@ func TestInputHandling(t *testing.T) {
 		t.Error("Input handler should be set correctly")
 	}
 }
+
+// TestLockupMessage tests that the display keeps a lock-up the emulator
+// announces
+func TestLockupMessage(t *testing.T) {
+	mockEmulator := &MockLockupEmulator{MockEmulator: MockEmulator{running: true}}
+	display := NewEbitenDisplay(mockEmulator, &MockInputHandler{}, 1, false)
+
+	if mockEmulator.handler == nil {
+		t.Fatal("Expected the display to subscribe to lock-ups")
+	}
+	mockEmulator.handler(cpu.Fault{Kind: cpu.FaultIllegalOpcode, PC: 0x0150, Opcode: 0xDD})
+	if display.lockup != "illegal opcode $DD at $0150" {
+		t.Errorf("Expected the lock-up to be kept, got %q", display.lockup)
+	}
+}
+
+// MockLockupEmulator is a mock emulator that announces lock-ups
+type MockLockupEmulator struct {
+	MockEmulator
+	handler func(cpu.Fault)
+}
+
+func (m *MockLockupEmulator) OnLockup(handler func(cpu.Fault)) {
+	m.handler = handler
+}
+
+func (m *MockLockupEmulator) Locked() bool {
+	return m.handler != nil
+}
@@ -0,0 +1,546 @@
+// Package gdbstub implements a GDB remote serial protocol server, so GDB and
+// GDB-based front ends can debug the emulated CPU
+package gdbstub
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// Target is the emulator being debugged
+type Target interface {
+	StepInstruction() (int, error)
+	Registers() cpu.Registers
+	SetRegisters(reg cpu.Registers)
+	ReadMemory(addr uint16) byte
+	WriteMemory(addr uint16, value byte)
+}
+
+// LockReporter is implemented by targets that report the CPU locking up on
+// an illegal opcode, see cpu.Locked. GDB is told the target stopped with
+// SIGILL when it does.
+type LockReporter interface {
+	Locked() bool
+}
+
+// Registers are described to GDB by target.xml as the Z80's, which GDB's
+// gbz80 architecture uses, 16 bits each in little endian byte order. The
+// SM83 only has AF to PC, the Z80's other registers read as zero and writes
+// to them are ignored.
+const (
+	REG_AF = iota
+	REG_BC
+	REG_DE
+	REG_HL
+	REG_SP
+	REG_PC
+	numCPURegisters
+	numRegisters = numCPURegisters + 7 // IX, IY, AF', BC', DE', HL', IR
+)
+
+// targetXML is the target description GDB reads with qXfer:features:read
+//
+//go:embed target.xml
+var targetXML string
+
+// Stop replies, with the signal that stopped the target
+const (
+	stopTrap      = "S05" // SIGTRAP, a breakpoint or finished step
+	stopInterrupt = "S02" // SIGINT, the client sent Ctrl-C
+	stopIllegal   = "S04" // SIGILL, an illegal opcode locked up the CPU
+)
+
+// interruptByte is sent outside of a packet to stop a running target
+const interruptByte = 0x03
+
+// Server serves the GDB remote serial protocol for a target
+type Server struct {
+	target Target
+
+	breakpoints map[uint16]bool
+
+	// Set by the reader when the client sends Ctrl-C
+	interrupted atomic.Bool
+
+	// Packets and acknowledgements are written from both the reader and
+	// the command loop
+	writeMu sync.Mutex
+	w       io.Writer
+	noAck   atomic.Bool
+}
+
+// NewServer creates a server debugging target
+func NewServer(target Target) *Server {
+	return &Server{
+		target:      target,
+		breakpoints: make(map[uint16]bool),
+	}
+}
+
+// ListenAndServe waits for GDB to connect on addr and serves the connection
+// until the client detaches or disconnects
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	log.Printf("[GDB] Waiting for GDB to connect on %s", l.Addr())
+	conn, err := l.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Printf("[GDB] Connected to %s", conn.RemoteAddr())
+	return s.Serve(conn)
+}
+
+// Serve runs commands from a connected client until it detaches, kills the
+// target or disconnects. The caller closes conn.
+func (s *Server) Serve(conn io.ReadWriter) error {
+	s.w = conn
+	s.noAck.Store(false)
+
+	packets := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- s.readPackets(bufio.NewReader(conn), packets, done)
+		close(packets)
+	}()
+
+	for packet := range packets {
+		if strings.HasPrefix(packet, "k") {
+			// Kill has no reply
+			return nil
+		}
+
+		reply, detach := s.handle(packet)
+		if err := s.send(reply); err != nil {
+			return err
+		}
+		if detach {
+			return nil
+		}
+	}
+
+	if err := <-readErr; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// readPackets parses packets from the client and acknowledges them until
+// the connection fails or done is closed
+func (s *Server) readPackets(r *bufio.Reader, packets chan<- string, done <-chan struct{}) error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch c {
+		case '$':
+		case interruptByte:
+			s.interrupted.Store(true)
+			continue
+		default:
+			// Acknowledgements of our packets, and noise between packets
+			continue
+		}
+
+		data, err := r.ReadString('#')
+		if err != nil {
+			return err
+		}
+		data = data[:len(data)-1]
+
+		var checksum [2]byte
+		if _, err := io.ReadFull(r, checksum[:]); err != nil {
+			return err
+		}
+
+		if !s.noAck.Load() {
+			sum, err := strconv.ParseUint(string(checksum[:]), 16, 8)
+			if err != nil || byte(sum) != packetChecksum(data) {
+				s.write("-")
+				continue
+			}
+			s.write("+")
+		}
+
+		select {
+		case packets <- data:
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// handle runs one command and returns the reply. detach is set when the
+// client is done debugging.
+func (s *Server) handle(packet string) (reply string, detach bool) {
+	if packet == "" {
+		return "", false
+	}
+	command, args := packet[0], packet[1:]
+
+	switch command {
+	case '?':
+		if s.locked() {
+			return stopIllegal, false
+		}
+		return stopTrap, false
+	case 'g':
+		return s.readRegisters(), false
+	case 'G':
+		return s.writeRegisters(args), false
+	case 'p':
+		return s.readRegister(args), false
+	case 'P':
+		return s.writeRegister(args), false
+	case 'm':
+		return s.readMemory(args), false
+	case 'M':
+		return s.writeMemory(args), false
+	case 'Z', 'z':
+		return s.setBreakpoint(command == 'Z', args), false
+	case 's':
+		if reply := s.resumeAt(args); reply != "" {
+			return reply, false
+		}
+		return s.step(), false
+	case 'c':
+		if reply := s.resumeAt(args); reply != "" {
+			return reply, false
+		}
+		return s.resume(), false
+	case 'H':
+		// There is only one thread
+		return "OK", false
+	case 'D':
+		return "OK", true
+	case 'q', 'Q':
+		return s.query(packet), false
+	}
+
+	// An empty reply tells GDB the command is not supported
+	return "", false
+}
+
+func (s *Server) query(packet string) string {
+	name, _, _ := strings.Cut(packet, ":")
+	switch name {
+	case "qSupported":
+		return "PacketSize=1000;QStartNoAckMode+;qXfer:features:read+"
+	case "QStartNoAckMode":
+		s.noAck.Store(true)
+		return "OK"
+	case "qXfer":
+		return s.readFeatures(packet)
+	case "qAttached":
+		// GDB detaches instead of killing an attached process when it quits
+		return "1"
+	case "qC":
+		return "QC1"
+	case "qfThreadInfo":
+		return "m1"
+	case "qsThreadInfo":
+		return "l"
+	}
+	return ""
+}
+
+// readFeatures answers qXfer:features:read:annex:offset,length with the
+// requested part of the target description. The reply starts with l when it
+// reaches the end, or m when there is more to read.
+func (s *Server) readFeatures(packet string) string {
+	args, ok := strings.CutPrefix(packet, "qXfer:features:read:")
+	if !ok {
+		return ""
+	}
+	annex, window, _ := strings.Cut(args, ":")
+	if annex != "target.xml" {
+		return "E00"
+	}
+	offsetText, lengthText, found := strings.Cut(window, ",")
+	offset, err := strconv.ParseUint(offsetText, 16, 32)
+	if !found || err != nil {
+		return "E01"
+	}
+	length, err := strconv.ParseUint(lengthText, 16, 32)
+	if err != nil {
+		return "E01"
+	}
+
+	if offset >= uint64(len(targetXML)) {
+		return "l"
+	}
+	data := targetXML[offset:]
+	if uint64(len(data)) > length {
+		return "m" + escapeBinary(data[:length])
+	}
+	return "l" + escapeBinary(data)
+}
+
+func (s *Server) readRegisters() string {
+	var sb strings.Builder
+	for _, value := range registerValues(s.target.Registers()) {
+		sb.WriteString(encodeWord(value))
+	}
+	return sb.String()
+}
+
+func (s *Server) writeRegisters(args string) string {
+	if len(args) != numRegisters*4 {
+		return "E01"
+	}
+
+	reg := s.target.Registers()
+	for i := 0; i < numRegisters; i++ {
+		value, ok := decodeWord(args[i*4 : i*4+4])
+		if !ok {
+			return "E01"
+		}
+		setRegister(&reg, i, value)
+	}
+	s.target.SetRegisters(reg)
+	return "OK"
+}
+
+func (s *Server) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || n >= numRegisters {
+		return "E01"
+	}
+	return encodeWord(registerValues(s.target.Registers())[n])
+}
+
+func (s *Server) writeRegister(args string) string {
+	numText, valueText, found := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(numText, 16, 8)
+	if !found || err != nil || n >= numRegisters {
+		return "E01"
+	}
+	value, ok := decodeWord(valueText)
+	if !ok {
+		return "E01"
+	}
+
+	reg := s.target.Registers()
+	setRegister(&reg, int(n), value)
+	s.target.SetRegisters(reg)
+	return "OK"
+}
+
+func (s *Server) readMemory(args string) string {
+	addr, length, ok := parseAddrLength(args)
+	if !ok {
+		return "E01"
+	}
+
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = s.target.ReadMemory(addr + uint16(i))
+	}
+	return hex.EncodeToString(data)
+}
+
+func (s *Server) writeMemory(args string) string {
+	location, dataText, found := strings.Cut(args, ":")
+	addr, length, ok := parseAddrLength(location)
+	if !found || !ok {
+		return "E01"
+	}
+	data, err := hex.DecodeString(dataText)
+	if err != nil || len(data) != length {
+		return "E01"
+	}
+
+	for i, value := range data {
+		s.target.WriteMemory(addr+uint16(i), value)
+	}
+	return "OK"
+}
+
+// setBreakpoint handles Z and z packets. Breakpoints are checked before each
+// instruction instead of patching memory, so they also work in ROM.
+func (s *Server) setBreakpoint(insert bool, args string) string {
+	fields := strings.Split(args, ",")
+	if len(fields) < 2 {
+		return "E01"
+	}
+	// Software (0) and hardware (1) breakpoints are the same here,
+	// watchpoints are not supported
+	if fields[0] != "0" && fields[0] != "1" {
+		return ""
+	}
+	addr, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return "E01"
+	}
+
+	if insert {
+		s.breakpoints[uint16(addr)] = true
+	} else {
+		delete(s.breakpoints, uint16(addr))
+	}
+	return "OK"
+}
+
+// resumeAt sets PC to the optional address of an s or c packet. It returns
+// an error reply if the address is invalid.
+func (s *Server) resumeAt(args string) string {
+	if args == "" {
+		return ""
+	}
+	addr, err := strconv.ParseUint(args, 16, 16)
+	if err != nil {
+		return "E01"
+	}
+	reg := s.target.Registers()
+	reg.PC = uint16(addr)
+	s.target.SetRegisters(reg)
+	return ""
+}
+
+func (s *Server) step() string {
+	if _, err := s.target.StepInstruction(); err != nil {
+		log.Printf("[GDB] Step failed: %v", err)
+	}
+	if s.locked() {
+		return stopIllegal
+	}
+	return stopTrap
+}
+
+// locked reports whether the target's CPU has locked up
+func (s *Server) locked() bool {
+	locker, ok := s.target.(LockReporter)
+	return ok && locker.Locked()
+}
+
+// resume runs until a breakpoint or the client interrupts
+func (s *Server) resume() string {
+	s.interrupted.Store(false)
+
+	for {
+		if _, err := s.target.StepInstruction(); err != nil {
+			log.Printf("[GDB] Step failed: %v", err)
+			return stopTrap
+		}
+		if s.locked() {
+			return stopIllegal
+		}
+		if s.breakpoints[s.target.Registers().PC] {
+			return stopTrap
+		}
+		if s.interrupted.Load() {
+			return stopInterrupt
+		}
+	}
+}
+
+// send writes a packet
+func (s *Server) send(data string) error {
+	return s.write(fmt.Sprintf("$%s#%02x", data, packetChecksum(data)))
+}
+
+func (s *Server) write(data string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := io.WriteString(s.w, data)
+	return err
+}
+
+// packetChecksum is the sum of the packet data modulo 256
+func packetChecksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// registerValues returns the registers in GDB's order, with the Z80's
+// registers the SM83 does not have as zero
+func registerValues(reg cpu.Registers) [numRegisters]uint16 {
+	return [numRegisters]uint16{reg.GetAF(), reg.GetBC(), reg.GetDE(), reg.GetHL(), reg.SP, reg.PC}
+}
+
+func setRegister(reg *cpu.Registers, n int, value uint16) {
+	switch n {
+	case REG_AF:
+		reg.SetAF(value)
+	case REG_BC:
+		reg.SetBC(value)
+	case REG_DE:
+		reg.SetDE(value)
+	case REG_HL:
+		reg.SetHL(value)
+	case REG_SP:
+		reg.SP = value
+	case REG_PC:
+		reg.PC = value
+	}
+}
+
+// escapeBinary escapes the characters that cannot appear in binary packet
+// data, as } followed by the character XOR 0x20
+func escapeBinary(data string) string {
+	var sb strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '#', '$', '}', '*':
+			sb.WriteByte('}')
+			sb.WriteByte(c ^ 0x20)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// encodeWord encodes a register as hex in little endian byte order
+func encodeWord(value uint16) string {
+	return hex.EncodeToString([]byte{byte(value), byte(value >> 8)})
+}
+
+func decodeWord(s string) (uint16, bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 2 {
+		return 0, false
+	}
+	return uint16(b[0]) | uint16(b[1])<<8, true
+}
+
+// parseAddrLength parses the addr,length of m and M packets
+func parseAddrLength(s string) (uint16, int, bool) {
+	addrText, lengthText, found := strings.Cut(s, ",")
+	if !found {
+		return 0, 0, false
+	}
+	addr, err := strconv.ParseUint(addrText, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.ParseUint(lengthText, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint16(addr), int(length), true
+}
@@ -0,0 +1,336 @@
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// program loops forever
+//
+//	0100: LD A,$42
+//	0102: INC B
+//	0103: JR $0102
+var program = []byte{0x3E, 0x42, 0x04, 0x18, 0xFD}
+
+// testClient is a minimal GDB client talking to a server over loopback TCP
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	r      *bufio.Reader
+	served chan error
+}
+
+// newTestClient starts a server for a CPU running program and connects to it
+func newTestClient(t *testing.T) (*testClient, *MockTarget) {
+	t.Helper()
+
+	target := newMockTarget()
+	copy(target.memory[0x0100:], program)
+	target.cpu.SetRegisters(cpu.Registers{PC: 0x0100, SP: 0xFFFE})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			served <- err
+			return
+		}
+		defer conn.Close()
+		served <- NewServer(target).Serve(conn)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn), served: served}, target
+}
+
+// send writes a packet and waits for its acknowledgement
+func (c *testClient) send(data string) {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", data, packetChecksum(data))
+	if ack, err := c.r.ReadByte(); err != nil || ack != '+' {
+		c.t.Fatalf("Expected + for %q, got %q %v", data, ack, err)
+	}
+}
+
+// receive reads a reply packet and acknowledges it
+func (c *testClient) receive() string {
+	c.t.Helper()
+	if _, err := c.r.ReadString('$'); err != nil {
+		c.t.Fatalf("Failed to read reply: %v", err)
+	}
+	data, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatalf("Failed to read reply: %v", err)
+	}
+	var checksum [2]byte
+	if _, err := io.ReadFull(c.r, checksum[:]); err != nil {
+		c.t.Fatalf("Failed to read checksum: %v", err)
+	}
+	io.WriteString(c.conn, "+")
+
+	data = data[:len(data)-1]
+	if expected := fmt.Sprintf("%02x", packetChecksum(data)); string(checksum[:]) != expected {
+		c.t.Errorf("Expected checksum %s, got %s", expected, checksum)
+	}
+	return data
+}
+
+// command sends a packet and returns the reply
+func (c *testClient) command(data string) string {
+	c.t.Helper()
+	c.send(data)
+	return c.receive()
+}
+
+// TestRegistersMemory tests reading and writing registers and memory
+func TestRegistersMemory(t *testing.T) {
+	client, target := newTestClient(t)
+
+	if reply := client.command("?"); reply != "S05" {
+		t.Errorf("Expected S05, got %q", reply)
+	}
+
+	// AF, BC, DE, HL, SP, PC, little endian, then the Z80's other seven
+	if reply := client.command("g"); reply != "0000000000000000feff0001"+strings.Repeat("0000", 7) {
+		t.Errorf("Expected registers with PC 0100, got %q", reply)
+	}
+	if reply := client.command("P1=3412"); reply != "OK" {
+		t.Errorf("Expected OK, got %q", reply)
+	}
+	if reg := target.Registers(); reg.GetBC() != 0x1234 {
+		t.Errorf("Expected BC 0x1234, got 0x%04X", reg.GetBC())
+	}
+	if reply := client.command("p1"); reply != "3412" {
+		t.Errorf("Expected BC 3412, got %q", reply)
+	}
+	if reply := client.command("pc"); reply != "0000" {
+		t.Errorf("Expected IR to read as zero, got %q", reply)
+	}
+	if reply := client.command("pd"); reply != "E01" {
+		t.Errorf("Expected an error for a register past IR, got %q", reply)
+	}
+
+	if reply := client.command("m100,5"); reply != "3e420418fd" {
+		t.Errorf("Expected the program bytes, got %q", reply)
+	}
+	if reply := client.command("Mc000,2:abcd"); reply != "OK" {
+		t.Errorf("Expected OK, got %q", reply)
+	}
+	if target.memory[0xC000] != 0xAB || target.memory[0xC001] != 0xCD {
+		t.Errorf("Expected memory write, got %02X %02X", target.memory[0xC000], target.memory[0xC001])
+	}
+
+	if reply := client.command("vMustReplyEmpty"); reply != "" {
+		t.Errorf("Expected an empty reply for an unsupported packet, got %q", reply)
+	}
+}
+
+// TestTargetDescription tests the packets GDB sends on connecting to read
+// the target description and the registers it describes
+func TestTargetDescription(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	reply := client.command("qSupported:multiprocess+;swbreak+;hwbreak+;qRelocInsn+;fork-events+;vfork-events+;exec-events+;vContSupported+;QThreadEvents+;no-resumed+;xmlRegisters=i386")
+	if !strings.Contains(reply, "qXfer:features:read+") {
+		t.Fatalf("Expected qXfer:features:read+ to be supported, got %q", reply)
+	}
+	if reply := client.command("qXfer:features:read:other.xml:0,ffb"); reply != "E00" {
+		t.Errorf("Expected E00 for an unknown annex, got %q", reply)
+	}
+
+	// Read in small windows to check the m and l replies
+	var doc strings.Builder
+	for offset := 0; ; offset += 0x100 {
+		reply := client.command(fmt.Sprintf("qXfer:features:read:target.xml:%x,100", offset))
+		if reply == "" || (reply[0] != 'm' && reply[0] != 'l') {
+			t.Fatalf("Expected an m or l reply, got %q", reply)
+		}
+		doc.WriteString(reply[1:])
+		if reply[0] == 'l' {
+			break
+		}
+	}
+
+	var target struct {
+		Architecture string `xml:"architecture"`
+		Feature      struct {
+			Name string `xml:"name,attr"`
+			Regs []struct {
+				Name    string `xml:"name,attr"`
+				Bitsize int    `xml:"bitsize,attr"`
+			} `xml:"reg"`
+		} `xml:"feature"`
+	}
+	if err := xml.Unmarshal([]byte(doc.String()), &target); err != nil {
+		t.Fatalf("Failed to parse target.xml: %v", err)
+	}
+	if target.Architecture != "gbz80" || target.Feature.Name != "org.gnu.gdb.z80.cpu" {
+		t.Errorf("Expected the gbz80 architecture and Z80 feature, got %q %q", target.Architecture, target.Feature.Name)
+	}
+
+	expected := []string{"af", "bc", "de", "hl", "sp", "pc", "ix", "iy", "af'", "bc'", "de'", "hl'", "ir"}
+	if len(target.Feature.Regs) != numRegisters || len(expected) != numRegisters {
+		t.Fatalf("Expected %d registers, got %d", numRegisters, len(target.Feature.Regs))
+	}
+	for i, reg := range target.Feature.Regs {
+		if reg.Name != expected[i] || reg.Bitsize != 16 {
+			t.Errorf("Expected register %d to be 16-bit %s, got %d-bit %s", i, expected[i], reg.Bitsize, reg.Name)
+		}
+	}
+
+	// The g reply has every register described
+	if reply := client.command("g"); len(reply) != numRegisters*4 {
+		t.Errorf("Expected %d hex digits of registers, got %q", numRegisters*4, reply)
+	}
+}
+
+// TestEscapeBinary tests escaping binary packet data
+func TestEscapeBinary(t *testing.T) {
+	if escaped := escapeBinary("a#b$c}d*e"); escaped != "a}\x03b}\x04c}]d}\x0ae" {
+		t.Errorf("Expected the special characters escaped, got %q", escaped)
+	}
+}
+
+// TestStepBreakpoint tests single stepping and continuing to a breakpoint
+func TestStepBreakpoint(t *testing.T) {
+	client, target := newTestClient(t)
+
+	if reply := client.command("s"); reply != "S05" {
+		t.Errorf("Expected S05 after a step, got %q", reply)
+	}
+	if reg := target.Registers(); reg.PC != 0x0102 || reg.A != 0x42 {
+		t.Errorf("Expected PC 0x0102 with A 0x42, got PC 0x%04X A 0x%02X", reg.PC, reg.A)
+	}
+
+	client.command("Z0,103,1")
+	for i := 1; i <= 2; i++ {
+		if reply := client.command("c"); reply != "S05" {
+			t.Errorf("Expected S05 at the breakpoint, got %q", reply)
+		}
+		if reg := target.Registers(); reg.PC != 0x0103 || reg.B != byte(i) {
+			t.Errorf("Expected PC 0x0103 with B %d, got PC 0x%04X B %d", i, reg.PC, reg.B)
+		}
+	}
+
+	if reply := client.command("z0,103,1"); reply != "OK" {
+		t.Errorf("Expected OK removing the breakpoint, got %q", reply)
+	}
+}
+
+// TestLockUp tests that an illegal opcode stops the target with SIGILL
+func TestLockUp(t *testing.T) {
+	client, target := newTestClient(t)
+	target.memory[0x0102] = 0xED
+
+	if reply := client.command("c"); reply != "S04" {
+		t.Errorf("Expected S04 for the illegal opcode, got %q", reply)
+	}
+	if pc := target.Registers().PC; pc != 0x0102 {
+		t.Errorf("Expected to stop at the illegal opcode at 0x0102, got 0x%04X", pc)
+	}
+	if reply := client.command("?"); reply != "S04" {
+		t.Errorf("Expected the stop reason to stay S04, got %q", reply)
+	}
+}
+
+// TestInterrupt tests stopping a running target with Ctrl-C
+func TestInterrupt(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	client.send("c")
+	time.Sleep(10 * time.Millisecond)
+	client.conn.Write([]byte{interruptByte})
+	if reply := client.receive(); reply != "S02" {
+		t.Errorf("Expected S02 after an interrupt, got %q", reply)
+	}
+}
+
+// TestDetach tests that the server returns when the client detaches
+func TestDetach(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	if reply := client.command("D"); reply != "OK" {
+		t.Errorf("Expected OK, got %q", reply)
+	}
+	select {
+	case err := <-client.served:
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the server to return after detaching")
+	}
+}
+
+// MockTarget runs a CPU over a flat 64KB memory
+type MockTarget struct {
+	cpu    *cpu.Z80
+	memory [0x10000]byte
+}
+
+func newMockTarget() *MockTarget {
+	t := &MockTarget{}
+	t.cpu, _ = cpu.NewCPU(t)
+	return t
+}
+
+func (t *MockTarget) StepInstruction() (int, error) {
+	return t.cpu.Step(), nil
+}
+
+func (t *MockTarget) Registers() cpu.Registers {
+	return t.cpu.GetRegisters()
+}
+
+func (t *MockTarget) SetRegisters(reg cpu.Registers) {
+	t.cpu.SetRegisters(reg)
+}
+
+func (t *MockTarget) ReadMemory(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) Locked() bool {
+	return t.cpu.Locked()
+}
+
+func (t *MockTarget) WriteMemory(addr uint16, value byte) {
+	t.memory[addr] = value
+}
+
+func (t *MockTarget) ReadByte(addr uint16) byte {
+	return t.memory[addr]
+}
+
+func (t *MockTarget) WriteByte(addr uint16, value byte) {
+	t.memory[addr] = value
+}
+
+func (t *MockTarget) ReadWord(addr uint16) uint16 {
+	return uint16(t.memory[addr]) | uint16(t.memory[addr+1])<<8
+}
+
+func (t *MockTarget) WriteWord(addr uint16, value uint16) {
+	t.WriteByte(addr, byte(value&0xFF))
+	t.WriteByte(addr+1, byte(value>>8))
+}
@@ -0,0 +1,280 @@
+package link
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/serial"
+)
+
+// Wire protocol
+//
+// Each message is three bytes: type, sequence number and data.
+//
+//	msgTransfer  the sender started a transfer on its internal clock and is
+//	             shifting out data; it waits for the matching reply
+//	msgReply     the receiver's byte for the transfer with the same sequence
+//	             number, sent once its Game Boy has clocked the byte in
+//
+// The clock master's transfer does not start shifting until the reply
+// arrives, so both sides see every byte exchanged in lockstep. This syncs
+// whole bytes, not cycles: the two emulators otherwise run on independent
+// clocks, and the master keeps running while it waits.
+const (
+	msgTransfer byte = 1
+	msgReply    byte = 2
+
+	messageSize = 3
+)
+
+// DefaultTimeout is how long a transfer waits for the other side before
+// giving up and receiving 0xFF, as if the cable were unplugged
+const DefaultTimeout = time.Second
+
+type message struct {
+	seq  byte
+	data byte
+}
+
+// Cable is a serial.SerialPeer that connects the link port to another
+// emulator instance over a network connection
+type Cable struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	// Messages from the reader goroutine
+	transfers chan message
+	replies   chan message
+	done      chan struct{}
+
+	// Sequence number of the last transfer we started, and when it was
+	// sent while it is waiting for a reply
+	seq      byte
+	awaiting bool
+	sentAt   time.Time
+
+	// Transfer from the other side waiting for our Game Boy to be ready
+	pending      *message
+	pendingSince time.Time
+
+	closeOnce sync.Once
+}
+
+// Listen waits for another emulator to connect on addr (e.g. ":5000")
+func Listen(addr string) (*Cable, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	log.Printf("[Link] Waiting for link cable connection on %s...", l.Addr())
+	return Accept(l)
+}
+
+// Accept waits for one connection on l
+func Accept(l net.Listener) (*Cable, error) {
+	conn, err := l.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Link] Connected to %s", conn.RemoteAddr())
+	return NewCable(conn), nil
+}
+
+// Dial connects to an emulator listening on addr (e.g. "localhost:5000")
+func Dial(addr string) (*Cable, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Link] Connected to %s", conn.RemoteAddr())
+	return NewCable(conn), nil
+}
+
+// NewCable creates a link cable over an established connection
+func NewCable(conn net.Conn) *Cable {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		// Every message is latency sensitive
+		tcp.SetNoDelay(true)
+	}
+
+	c := &Cable{
+		conn:      conn,
+		timeout:   DefaultTimeout,
+		transfers: make(chan message, 16),
+		replies:   make(chan message, 16),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop()
+
+	return c
+}
+
+// SetTimeout sets how long a transfer waits for the other side
+func (c *Cable) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// Close disconnects the cable
+func (c *Cable) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// StartExchange sends a byte clocked by our Game Boy. Poll passes the byte
+// the other Game Boy shifts back to the serial controller once it arrives.
+func (c *Cable) StartExchange(out byte) {
+	c.seq++
+	c.awaiting = c.send(msgTransfer, c.seq, out) == nil
+	c.sentAt = time.Now()
+}
+
+// Exchange sends a byte clocked by our Game Boy and waits for the byte the
+// other Game Boy shifts back. The serial controller uses StartExchange
+// instead, so this only blocks callers using the cable directly.
+func (c *Cable) Exchange(out byte) byte {
+	c.seq++
+	if err := c.send(msgTransfer, c.seq, out); err != nil {
+		return 0xFF
+	}
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case reply := <-c.replies:
+			// Replies to transfers we already gave up on are stale
+			if reply.seq == c.seq {
+				return reply.data
+			}
+		case transfer := <-c.transfers:
+			// Both sides are using their internal clock, neither one is
+			// listening so each receives an undriven line
+			c.send(msgReply, transfer.seq, 0xFF)
+		case <-timeout.C:
+			log.Printf("[Link] No response from the other side, transfer timed out")
+			return 0xFF
+		case <-c.done:
+			return 0xFF
+		}
+	}
+}
+
+// Poll completes our transfers once the other Game Boy replies, and
+// transfers clocked by the other Game Boy once ours is waiting for an
+// external clock transfer
+func (c *Cable) Poll(s *serial.Serial) {
+	c.pollReply(s)
+	if c.pending == nil && len(c.transfers) == 0 {
+		return
+	}
+
+	// Both sides are using their internal clock, neither one is listening
+	// so each receives an undriven line
+	if c.awaiting {
+		for len(c.transfers) > 0 {
+			transfer := <-c.transfers
+			c.send(msgReply, transfer.seq, 0xFF)
+		}
+		return
+	}
+
+	// Only the newest transfer matters, older ones have timed out
+	for len(c.transfers) > 0 {
+		transfer := <-c.transfers
+		c.pending = &transfer
+		c.pendingSince = time.Now()
+	}
+
+	if out, ok := s.ExternalTransfer(c.pending.data); ok {
+		c.send(msgReply, c.pending.seq, out)
+		c.pending = nil
+		return
+	}
+
+	// The other side has given up waiting for us
+	if time.Since(c.pendingSince) > c.timeout {
+		c.pending = nil
+	}
+}
+
+// pollReply passes the reply to our transfer to s, or 0xFF if the other
+// side does not answer in time
+func (c *Cable) pollReply(s *serial.Serial) {
+	if !s.AwaitingReply() {
+		// The game aborted the transfer, any reply is stale
+		c.awaiting = false
+	}
+
+	for len(c.replies) > 0 {
+		reply := <-c.replies
+		// Replies to transfers we already gave up on are stale
+		if c.awaiting && reply.seq == c.seq {
+			c.awaiting = false
+			s.Reply(reply.data)
+		}
+	}
+	if !s.AwaitingReply() {
+		return
+	}
+
+	// Receive an undriven line if sending failed, the cable is unplugged or
+	// the other side takes too long
+	select {
+	case <-c.done:
+		c.awaiting = false
+	default:
+		if c.awaiting && time.Since(c.sentAt) > c.timeout {
+			log.Printf("[Link] No response from the other side, transfer timed out")
+			c.awaiting = false
+		}
+	}
+	if !c.awaiting {
+		s.Reply(0xFF)
+	}
+}
+
+func (c *Cable) send(kind, seq, data byte) error {
+	_, err := c.conn.Write([]byte{kind, seq, data})
+	if err != nil {
+		log.Printf("[Link] Send failed: %v", err)
+	}
+	return err
+}
+
+// readLoop decodes incoming messages until the connection closes
+func (c *Cable) readLoop() {
+	defer close(c.done)
+
+	var buf [messageSize]byte
+	for {
+		if _, err := io.ReadFull(c.conn, buf[:]); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("[Link] Connection error: %v", err)
+			}
+			log.Printf("[Link] Link cable disconnected")
+			return
+		}
+
+		msg := message{seq: buf[1], data: buf[2]}
+		switch buf[0] {
+		case msgTransfer:
+			c.transfers <- msg
+		case msgReply:
+			c.replies <- msg
+		default:
+			log.Printf("[Link] Ignoring unknown message type %d", buf[0])
+		}
+	}
+}
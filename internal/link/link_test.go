@@ -0,0 +1,190 @@
+package link
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/serial"
+)
+
+// connectPair creates two cables connected over loopback TCP
+func connectPair(t *testing.T) (*Cable, *Cable) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *Cable)
+	go func() {
+		cable, err := Accept(l)
+		if err != nil {
+			t.Errorf("Accept failed: %v", err)
+		}
+		accepted <- cable
+	}()
+
+	dialed, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	listened := <-accepted
+
+	t.Cleanup(func() {
+		dialed.Close()
+		listened.Close()
+	})
+	return listened, dialed
+}
+
+// TestLinkTransfer tests a byte exchange between a master and a slave
+func TestLinkTransfer(t *testing.T) {
+	masterCable, slaveCable := connectPair(t)
+
+	master := serial.NewSerial(&MockMMU{})
+	master.SetPeer(masterCable)
+	slaveMMU := &MockMMU{}
+	slave := serial.NewSerial(slaveMMU)
+	slave.SetPeer(slaveCable)
+
+	// The slave waits for the external clock
+	slave.WriteRegister(0xFF01, 0x22)
+	slave.WriteRegister(0xFF02, 0x80)
+
+	// Run the slave's emulation thread until its transfer completes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		deadline := time.Now().Add(5 * time.Second)
+		for slave.WaitingForExternalClock() && time.Now().Before(deadline) {
+			slave.Step(4)
+		}
+	}()
+
+	// The master runs on while it waits for the slave to answer
+	master.WriteRegister(0xFF01, 0x11)
+	master.WriteRegister(0xFF02, 0x81)
+	if !runUntilDone(master) {
+		t.Fatal("Expected the master's transfer to complete")
+	}
+	<-done
+
+	if value := master.ReadRegister(0xFF01); value != 0x22 {
+		t.Errorf("Expected master to receive 0x22, got %02X", value)
+	}
+	if value := slave.ReadRegister(0xFF01); value != 0x11 {
+		t.Errorf("Expected slave to receive 0x11, got %02X", value)
+	}
+	if slaveMMU.interruptFlag&0x08 == 0 {
+		t.Error("Expected serial interrupt on the slave")
+	}
+}
+
+// TestLinkTimeout tests a transfer when the other side never gets ready
+func TestLinkTimeout(t *testing.T) {
+	masterCable, _ := connectPair(t)
+	masterCable.SetTimeout(50 * time.Millisecond)
+
+	master := serial.NewSerial(&MockMMU{})
+	master.SetPeer(masterCable)
+	master.WriteRegister(0xFF01, 0x11)
+	master.WriteRegister(0xFF02, 0x81)
+
+	// Stepping never blocks on the other side
+	start := time.Now()
+	master.Step(8 * serial.CYCLES_PER_BIT)
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Errorf("Expected Step not to wait for the other side, took %v", elapsed)
+	}
+	if !master.AwaitingReply() {
+		t.Error("Expected the transfer to wait for a reply")
+	}
+
+	if !runUntilDone(master) {
+		t.Fatal("Expected the transfer to time out")
+	}
+	if value := master.ReadRegister(0xFF01); value != 0xFF {
+		t.Errorf("Expected 0xFF after timeout, got %02X", value)
+	}
+}
+
+// TestLinkBothMasters tests that two masters don't deadlock
+func TestLinkBothMasters(t *testing.T) {
+	a, b := connectPair(t)
+
+	results := make(chan byte, 2)
+	go func() { results <- a.Exchange(0x01) }()
+	go func() { results <- b.Exchange(0x02) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case value := <-results:
+			if value != 0xFF {
+				t.Errorf("Expected 0xFF when both sides are masters, got %02X", value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Transfers deadlocked")
+		}
+	}
+}
+
+// TestLinkBothMastersStepping tests that two masters stepped by their
+// emulation threads each receive 0xFF
+func TestLinkBothMastersStepping(t *testing.T) {
+	a, b := connectPair(t)
+
+	masters := []*serial.Serial{serial.NewSerial(&MockMMU{}), serial.NewSerial(&MockMMU{})}
+	masters[0].SetPeer(a)
+	masters[1].SetPeer(b)
+	for _, master := range masters {
+		master.WriteRegister(0xFF01, 0x01)
+		master.WriteRegister(0xFF02, 0x81)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for masters[0].ReadRegister(0xFF02)&0x80 != 0 || masters[1].ReadRegister(0xFF02)&0x80 != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Transfers deadlocked")
+		}
+		masters[0].Step(4)
+		masters[1].Step(4)
+	}
+	for i, master := range masters {
+		if value := master.ReadRegister(0xFF01); value != 0xFF {
+			t.Errorf("Expected master %d to receive 0xFF, got %02X", i, value)
+		}
+	}
+}
+
+// runUntilDone steps s like an emulation thread until its transfer completes
+func runUntilDone(s *serial.Serial) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for s.ReadRegister(0xFF02)&serial.SC_TRANSFER_START != 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		s.Step(4)
+	}
+	return true
+}
+
+// MockMMU is a mock implementation of the serial MMU interface for testing
+type MockMMU struct {
+	interruptFlag byte
+}
+
+func (m *MockMMU) WriteByte(addr uint16, value byte) {
+	if addr == 0xFF0F {
+		m.interruptFlag = value
+	}
+}
+
+func (m *MockMMU) ReadByte(addr uint16) byte {
+	if addr == 0xFF0F {
+		return m.interruptFlag
+	}
+	return 0
+}
@@ -1,7 +1,10 @@
 package mmu
 
 import (
+	"fmt"
 	"log"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
 )
 
 // Memory map:
@@ -20,7 +23,7 @@ import (
 
 type MemoryManagedUnit struct {
 	// Memory regions
-	bios [0x100]byte  // 0x0000-0x00FF (only during boot)
+	bios []byte       // Boot ROM, see LoadBIOS
 	rom  []byte       // Cartridge ROM
 	vram [0x2000]byte // 0x8000-0x9FFF
 	eram [0x2000]byte // 0xA000-0xBFFF (Cartridge RAM)
@@ -31,13 +34,25 @@ type MemoryManagedUnit struct {
 	ie   byte         // 0xFFFF (Interrupt Enable register)
 
 	// Control flags
-	biosActive bool // Whether BIOS is active
+	biosActive bool // Whether the boot ROM is mapped
+	stubLY     bool // Whether LY always reads 0x90
+
+	// Watchpoints, see AddWatchpoint. watchKinds has a bit set for each
+	// kind of access any watchpoint is interested in, so memory accesses
+	// only pay for a single bit test when nothing is being watched.
+	watchpoints  []*Watchpoint
+	watchKinds   AccessKind
+	nextWatchID  int
+	inWatchpoint bool
+	inDMA        bool // Reads are by OAM DMA
 
 	// References to other components
 	cartridge  Cartridge
 	timer      Timer
 	controller Controller
 	ppu        PPU
+	sound      Sound
+	serial     Serial
 }
 
 // Cartridge interface for memory banking
@@ -63,11 +78,27 @@ type PPU interface {
 	WriteRegister(addr uint16, value byte)
 }
 
+// Serial interface for handling serial registers (FF01-FF02)
+type Serial interface {
+	ReadRegister(addr uint16) byte
+	WriteRegister(addr uint16, value byte)
+}
+
+// Sound interface for handling sound registers (FF10-FF3F)
+type Sound interface {
+	ReadRegister(addr uint16) byte
+	WriteRegister(addr uint16, value byte)
+}
+
+// Boot ROM sizes
+const (
+	DMG_BOOT_ROM_SIZE = 0x100 // Mapped at 0x0000-0x00FF
+	CGB_BOOT_ROM_SIZE = 0x900 // Mapped at 0x0000-0x00FF and 0x0200-0x08FF
+)
+
 // Initialize a new MMU
 func NewMMU() *MemoryManagedUnit {
-	mmu := &MemoryManagedUnit{
-		biosActive: true,
-	}
+	mmu := &MemoryManagedUnit{}
 	return mmu
 }
 
@@ -150,13 +181,25 @@ func (m *MemoryManagedUnit) SetPPU(ppu PPU) {
 	m.ppu = ppu
 }
 
-// Load BIOS
+// Set the serial controller
+func (m *MemoryManagedUnit) SetSerial(serial Serial) {
+	m.serial = serial
+}
+
+// Set the sound system
+func (m *MemoryManagedUnit) SetSound(sound Sound) {
+	m.sound = sound
+}
+
+// LoadBIOS maps a DMG or CGB boot ROM over the cartridge until a write to
+// FF50 unmaps it
 func (m *MemoryManagedUnit) LoadBIOS(data []byte) error {
-	if len(data) > len(m.bios) {
-		return nil // Error: BIOS data too large
+	if len(data) != DMG_BOOT_ROM_SIZE && len(data) != CGB_BOOT_ROM_SIZE {
+		return fmt.Errorf("boot ROM is %d bytes, expected %d for a DMG or %d for a CGB",
+			len(data), DMG_BOOT_ROM_SIZE, CGB_BOOT_ROM_SIZE)
 	}
 
-	copy(m.bios[:], data)
+	m.bios = append([]byte(nil), data...)
 	m.biosActive = true
 	return nil
 }
@@ -166,11 +209,49 @@ func (m *MemoryManagedUnit) DisableBIOS() {
 	m.biosActive = false
 }
 
+// BIOSActive returns whether the boot ROM is mapped
+func (m *MemoryManagedUnit) BIOSActive() bool {
+	return m.biosActive
+}
+
+// biosMapped returns whether addr reads the boot ROM. The CGB boot ROM
+// leaves a gap at 0x0100-0x01FF for the cartridge header.
+func (m *MemoryManagedUnit) biosMapped(addr uint16) bool {
+	return m.biosActive && int(addr) < len(m.bios) && (addr < 0x100 || addr >= 0x200)
+}
+
+// SetLYStub makes LY always read 0x90, the start of V-Blank. Trace tools
+// like Gameboy Doctor expect this so traces do not depend on PPU timing.
+func (m *MemoryManagedUnit) SetLYStub(enabled bool) {
+	m.stubLY = enabled
+}
+
 // Read a byte from memory
 func (m *MemoryManagedUnit) ReadByte(addr uint16) byte {
+	value := m.readByte(addr)
+	if m.watchKinds&AccessRead != 0 {
+		m.checkWatchpoints(AccessRead, addr, value, value)
+	}
+	return value
+}
+
+// Peek reads a byte without triggering watchpoints, for debuggers and other
+// tools inspecting memory
+func (m *MemoryManagedUnit) Peek(addr uint16) byte {
+	return m.readByte(addr)
+}
+
+// Poke writes a byte without triggering watchpoints, for the CPU updating
+// registers as the hardware does rather than for the program
+func (m *MemoryManagedUnit) Poke(addr uint16, value byte) {
+	m.writeByte(addr, value)
+}
+
+// readByte reads a byte without triggering watchpoints
+func (m *MemoryManagedUnit) readByte(addr uint16) byte {
 	switch {
-	case addr < 0x100 && m.biosActive:
-		// BIOS (if active)
+	case m.biosMapped(addr):
+		// Boot ROM (if active)
 		return m.bios[addr]
 	case addr < 0x8000:
 		// ROM banks
@@ -207,6 +288,17 @@ func (m *MemoryManagedUnit) ReadByte(addr uint16) byte {
 
 // Write a byte to memory
 func (m *MemoryManagedUnit) WriteByte(addr uint16, value byte) {
+	if m.watchKinds&AccessWrite != 0 {
+		old := m.readByte(addr)
+		m.writeByte(addr, value)
+		m.checkWatchpoints(AccessWrite, addr, value, old)
+		return
+	}
+	m.writeByte(addr, value)
+}
+
+// writeByte writes a byte without triggering watchpoints
+func (m *MemoryManagedUnit) writeByte(addr uint16, value byte) {
 	switch {
 	case addr < 0x8000:
 		// ROM banks - handled by cartridge
@@ -256,6 +348,11 @@ func (m *MemoryManagedUnit) WriteWord(addr uint16, value uint16) {
 
 // Special handling for I/O register reads
 func (m *MemoryManagedUnit) readIO(addr uint16) byte {
+	// Sound registers and wave RAM are handled by the sound component
+	if addr >= 0xFF10 && addr <= 0xFF3F && m.sound != nil {
+		return m.sound.ReadRegister(addr)
+	}
+
 	// Handle special I/O registers
 	switch addr {
 	case 0xFF00: // Joypad
@@ -263,6 +360,13 @@ func (m *MemoryManagedUnit) readIO(addr uint16) byte {
 			return m.controller.ReadJoypad()
 		}
 		return 0xFF
+	case 0xFF01, // SB - Serial transfer data
+		0xFF02: // SC - Serial transfer control
+		// These registers are handled by the serial component
+		if m.serial != nil {
+			return m.serial.ReadRegister(addr)
+		}
+		return m.io[addr-0xFF00]
 	case 0xFF04, // DIV - Divider register
 		0xFF05, // TIMA - Timer counter
 		0xFF06, // TMA - Timer modulo
@@ -272,6 +376,11 @@ func (m *MemoryManagedUnit) readIO(addr uint16) byte {
 			return m.timer.ReadRegister(addr)
 		}
 		return m.io[addr-0xFF00]
+	case 0xFF44: // LY - LCD Y-Coordinate
+		if m.stubLY {
+			return 0x90
+		}
+		return m.io[addr-0xFF00]
 	default:
 		return m.io[addr-0xFF00]
 	}
@@ -279,6 +388,12 @@ func (m *MemoryManagedUnit) readIO(addr uint16) byte {
 
 // Special handling for I/O register writes
 func (m *MemoryManagedUnit) writeIO(addr uint16, value byte) {
+	// Sound registers and wave RAM are handled by the sound component
+	if addr >= 0xFF10 && addr <= 0xFF3F && m.sound != nil {
+		m.sound.WriteRegister(addr, value)
+		return
+	}
+
 	// Handle special I/O registers
 	switch addr {
 	case 0xFF00: // Joypad
@@ -286,6 +401,14 @@ func (m *MemoryManagedUnit) writeIO(addr uint16, value byte) {
 			m.controller.WriteJoypad(value)
 		}
 		m.io[0] = value
+	case 0xFF01, // SB - Serial transfer data
+		0xFF02: // SC - Serial transfer control
+		// These registers are handled by the serial component
+		if m.serial != nil {
+			m.serial.WriteRegister(addr, value)
+		} else {
+			m.io[addr-0xFF00] = value
+		}
 	case 0xFF04, // DIV - Divider register
 		0xFF05, // TIMA - Timer counter
 		0xFF06, // TMA - Timer modulo
@@ -322,6 +445,12 @@ func (m *MemoryManagedUnit) writeIO(addr uint16, value byte) {
 		// STAT register is handled specially by the PPU via WriteIODirect
 	case 0xFF46: // DMA - OAM DMA transfer
 		m.doDMATransfer(value)
+	case 0xFF50: // Boot ROM disable
+		// Any nonzero write unmaps the boot ROM, it cannot be mapped again
+		if value != 0 {
+			m.biosActive = false
+		}
+		m.io[addr-0xFF00] = value
 	default:
 		m.io[addr-0xFF00] = value
 	}
@@ -340,8 +469,37 @@ func (m *MemoryManagedUnit) doDMATransfer(value byte) {
 	// DMA transfers 160 bytes from XX00-XX9F to FE00-FE9F
 	// where XX is the value written to FF46
 	baseAddr := uint16(value) << 8
+	m.inDMA = true
 	for i := uint16(0); i < 160; i++ {
 		m.oam[i] = m.ReadByte(baseAddr + i)
 	}
+	m.inDMA = false
 	m.io[0x46] = value
 }
+
+// SaveState writes all internal memory regions and the BIOS mapping flag.
+// Cartridge ROM/RAM is owned by the cartridge and saved separately.
+func (m *MemoryManagedUnit) SaveState(w *snapshot.StateWriter) {
+	w.Section("MMU ")
+	w.Bytes(m.vram[:])
+	w.Bytes(m.eram[:])
+	w.Bytes(m.wram[:])
+	w.Bytes(m.oam[:])
+	w.Bytes(m.io[:])
+	w.Bytes(m.hram[:])
+	w.Uint8(m.ie)
+	w.Bool(m.biosActive)
+}
+
+// LoadState restores the memory regions written by SaveState
+func (m *MemoryManagedUnit) LoadState(r *snapshot.StateReader) {
+	r.Section("MMU ")
+	r.BytesInto(m.vram[:])
+	r.BytesInto(m.eram[:])
+	r.BytesInto(m.wram[:])
+	r.BytesInto(m.oam[:])
+	r.BytesInto(m.io[:])
+	r.BytesInto(m.hram[:])
+	m.ie = r.Uint8()
+	m.biosActive = r.Bool()
+}
@@ -175,6 +175,109 @@ func TestMMUDMATransfer(t *testing.T) {
 	}
 }
 
+// TestBootROM tests mapping a boot ROM until FF50 is written
+func TestBootROM(t *testing.T) {
+	mmu := NewMMU()
+	cart := &MockCartridge{}
+	mmu.SetCartridge(cart)
+
+	if err := mmu.LoadBIOS(make([]byte, 0x200)); err == nil {
+		t.Error("Expected an error loading a boot ROM of the wrong size")
+	}
+
+	bios := make([]byte, DMG_BOOT_ROM_SIZE)
+	bios[0x00] = 0x31
+	bios[0xFF] = 0x50
+	if err := mmu.LoadBIOS(bios); err != nil {
+		t.Fatalf("LoadBIOS failed: %v", err)
+	}
+
+	if mmu.ReadByte(0x0000) != 0x31 || mmu.ReadByte(0x00FF) != 0x50 {
+		t.Errorf("Expected the boot ROM at 0x0000-0x00FF")
+	}
+	mmu.ReadByte(0x0100)
+	if cart.lastReadAddr != 0x0100 {
+		t.Errorf("Expected the cartridge at 0x0100, got a read of %04X", cart.lastReadAddr)
+	}
+
+	// Only nonzero writes unmap the boot ROM
+	mmu.WriteByte(0xFF50, 0x00)
+	if !mmu.BIOSActive() {
+		t.Error("Expected the boot ROM to stay mapped after writing 0 to FF50")
+	}
+	mmu.WriteByte(0xFF50, 0x01)
+	if mmu.BIOSActive() {
+		t.Error("Expected the boot ROM to be unmapped after writing 1 to FF50")
+	}
+	mmu.WriteByte(0xFF50, 0x00)
+	mmu.ReadByte(0x0000)
+	if mmu.BIOSActive() || cart.lastReadAddr != 0x0000 {
+		t.Error("Expected the cartridge at 0x0000 once the boot ROM is unmapped")
+	}
+}
+
+// TestCGBBootROM tests that the CGB boot ROM leaves the cartridge header
+// mapped
+func TestCGBBootROM(t *testing.T) {
+	mmu := NewMMU()
+	cart := &MockCartridge{}
+	mmu.SetCartridge(cart)
+
+	bios := make([]byte, CGB_BOOT_ROM_SIZE)
+	bios[0x0200] = 0xAA
+	bios[0x08FF] = 0xBB
+	if err := mmu.LoadBIOS(bios); err != nil {
+		t.Fatalf("LoadBIOS failed: %v", err)
+	}
+
+	if mmu.ReadByte(0x0200) != 0xAA || mmu.ReadByte(0x08FF) != 0xBB {
+		t.Errorf("Expected the boot ROM at 0x0200-0x08FF")
+	}
+	for _, addr := range []uint16{0x0104, 0x0900} {
+		mmu.ReadByte(addr)
+		if cart.lastReadAddr != addr {
+			t.Errorf("Expected the cartridge at %04X, got a read of %04X", addr, cart.lastReadAddr)
+		}
+	}
+}
+
+// TestSoundRegisterRouting tests that FF10-FF3F are handled by the sound component
+func TestSoundRegisterRouting(t *testing.T) {
+	mmu := NewMMU()
+	sound := &MockSound{registers: make(map[uint16]byte)}
+	mmu.SetSound(sound)
+
+	mmu.WriteByte(0xFF12, 0xF3) // NR12
+	mmu.WriteByte(0xFF3F, 0x5A) // Wave RAM
+
+	if sound.registers[0xFF12] != 0xF3 || sound.registers[0xFF3F] != 0x5A {
+		t.Errorf("Expected sound writes to reach the sound component, got %v", sound.registers)
+	}
+
+	if value := mmu.ReadByte(0xFF12); value != 0xF3 {
+		t.Errorf("Expected NR12 read from sound component to be F3, got %02X", value)
+	}
+
+	// Registers outside the sound range stay in the MMU
+	mmu.WriteByte(0xFF40, 0x91)
+	if _, ok := sound.registers[0xFF40]; ok {
+		t.Error("Expected LCDC write not to reach the sound component")
+	}
+}
+
+// MockSound is a mock implementation of the Sound interface for testing
+type MockSound struct {
+	registers map[uint16]byte
+}
+
+func (m *MockSound) ReadRegister(addr uint16) byte {
+	return m.registers[addr]
+}
+
+func (m *MockSound) WriteRegister(addr uint16, value byte) {
+	m.registers[addr] = value
+}
+
 // MockCartridge is a mock implementation of the Cartridge interface for testing
 type MockCartridge struct {
 	lastReadAddr   uint16
@@ -0,0 +1,174 @@
+package mmu
+
+// AccessKind is a kind of memory access, combined as a bit mask
+type AccessKind uint8
+
+const (
+	AccessRead    AccessKind = 1 << iota // Any read, including instruction fetches
+	AccessWrite                          // Any write
+	AccessExecute                        // An instruction starting at the address
+)
+
+func (k AccessKind) String() string {
+	s := ""
+	if k&AccessRead != 0 {
+		s += "r"
+	}
+	if k&AccessWrite != 0 {
+		s += "w"
+	}
+	if k&AccessExecute != 0 {
+		s += "x"
+	}
+	return s
+}
+
+// Access describes a memory access that triggered a watchpoint
+type Access struct {
+	Kind AccessKind
+	Addr uint16
+
+	// The value read, written or the opcode executed
+	Value byte
+
+	// The value before a write, the same as Value for other accesses
+	Old byte
+
+	// Set for reads made by OAM DMA rather than the CPU
+	DMA bool
+
+	// Set for reads and instructions from the boot ROM rather than the
+	// cartridge
+	BIOS bool
+}
+
+// Watchpoint calls Callback for accesses of the given kinds to the
+// addresses from Start to End inclusive
+type Watchpoint struct {
+	Start uint16
+	End   uint16
+	Kinds AccessKind
+
+	// When MatchValue is set, only accesses of Value trigger the callback
+	MatchValue bool
+	Value      byte
+
+	// When OnlyChanges is set, writes of the value already in memory are
+	// ignored
+	OnlyChanges bool
+
+	Callback func(Access)
+
+	id int
+}
+
+// ID returns the identifier to pass to RemoveWatchpoint
+func (w *Watchpoint) ID() int {
+	return w.id
+}
+
+// matches reports whether the access should trigger the watchpoint
+func (w *Watchpoint) matches(a Access) bool {
+	if w.Kinds&a.Kind == 0 || a.Addr < w.Start || a.Addr > w.End {
+		return false
+	}
+	if w.MatchValue && a.Value != w.Value {
+		return false
+	}
+	if w.OnlyChanges && a.Kind == AccessWrite && a.Value == a.Old {
+		return false
+	}
+	return true
+}
+
+// AddWatchpoint installs a watchpoint and returns its ID. Callbacks run
+// during the access; memory accesses they make do not trigger watchpoints.
+func (m *MemoryManagedUnit) AddWatchpoint(w Watchpoint) int {
+	m.nextWatchID++
+	w.id = m.nextWatchID
+	m.watchpoints = append(m.watchpoints, &w)
+	m.updateWatchKinds()
+	return w.id
+}
+
+// RemoveWatchpoint removes the watchpoint with the ID returned by
+// AddWatchpoint. It reports whether the watchpoint existed.
+func (m *MemoryManagedUnit) RemoveWatchpoint(id int) bool {
+	for i, w := range m.watchpoints {
+		if w.id == id {
+			// Copy instead of shifting in place, a callback may be removing
+			// its watchpoint while checkWatchpoints ranges over the slice
+			m.watchpoints = append(m.watchpoints[:i:i], m.watchpoints[i+1:]...)
+			m.updateWatchKinds()
+			return true
+		}
+	}
+	return false
+}
+
+// Watchpoints returns the installed watchpoints
+func (m *MemoryManagedUnit) Watchpoints() []Watchpoint {
+	watchpoints := make([]Watchpoint, len(m.watchpoints))
+	for i, w := range m.watchpoints {
+		watchpoints[i] = *w
+	}
+	return watchpoints
+}
+
+func (m *MemoryManagedUnit) updateWatchKinds() {
+	m.watchKinds = 0
+	for _, w := range m.watchpoints {
+		m.watchKinds |= w.Kinds
+	}
+}
+
+// Unwatched accesses the MMU without triggering watchpoints. The PPU, timer
+// and serial controller are given one, so watchpoints only see the accesses
+// made by the program and OAM DMA.
+type Unwatched struct {
+	m *MemoryManagedUnit
+}
+
+// Unwatched returns a view of the MMU whose accesses do not trigger
+// watchpoints
+func (m *MemoryManagedUnit) Unwatched() Unwatched {
+	return Unwatched{m: m}
+}
+
+func (u Unwatched) ReadByte(addr uint16) byte {
+	return u.m.readByte(addr)
+}
+
+func (u Unwatched) WriteByte(addr uint16, value byte) {
+	u.m.writeByte(addr, value)
+}
+
+func (u Unwatched) WriteIODirect(addr uint16, value byte) {
+	u.m.WriteIODirect(addr, value)
+}
+
+// WatchExecute is called by the CPU before executing the instruction at
+// addr, see cpu.ExecuteWatcher
+func (m *MemoryManagedUnit) WatchExecute(addr uint16) {
+	if m.watchKinds&AccessExecute != 0 {
+		opcode := m.readByte(addr)
+		m.checkWatchpoints(AccessExecute, addr, opcode, opcode)
+	}
+}
+
+// checkWatchpoints runs the callbacks of the watchpoints matching an access
+func (m *MemoryManagedUnit) checkWatchpoints(kind AccessKind, addr uint16, value, old byte) {
+	if m.inWatchpoint {
+		return
+	}
+	m.inWatchpoint = true
+	defer func() { m.inWatchpoint = false }()
+
+	access := Access{Kind: kind, Addr: addr, Value: value, Old: old, DMA: m.inDMA}
+	access.BIOS = kind != AccessWrite && m.biosMapped(addr)
+	for _, w := range m.watchpoints {
+		if w.matches(access) {
+			w.Callback(access)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package mmu
+
+import (
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// TestWatchpointReadWrite tests read and write watchpoints on an address range
+func TestWatchpointReadWrite(t *testing.T) {
+	mmu := NewMMU()
+
+	var accesses []Access
+	id := mmu.AddWatchpoint(Watchpoint{
+		Start:    0xC000,
+		End:      0xC00F,
+		Kinds:    AccessRead | AccessWrite,
+		Callback: func(a Access) { accesses = append(accesses, a) },
+	})
+
+	mmu.WriteByte(0xC000, 0x12)
+	mmu.WriteByte(0xC010, 0x34) // Outside the range
+	mmu.ReadByte(0xC000)
+
+	if len(accesses) != 2 {
+		t.Fatalf("Expected 2 accesses, got %d", len(accesses))
+	}
+	expected := Access{Kind: AccessWrite, Addr: 0xC000, Value: 0x12, Old: 0x00}
+	if accesses[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, accesses[0])
+	}
+	if accesses[1].Kind != AccessRead || accesses[1].Value != 0x12 {
+		t.Errorf("Expected a read of 0x12, got %+v", accesses[1])
+	}
+
+	// Removing the watchpoint stops the callbacks
+	if !mmu.RemoveWatchpoint(id) {
+		t.Error("Expected the watchpoint to be removed")
+	}
+	mmu.WriteByte(0xC000, 0x56)
+	if len(accesses) != 2 {
+		t.Errorf("Expected no more accesses, got %d", len(accesses))
+	}
+	if mmu.watchKinds != 0 {
+		t.Errorf("Expected no watched access kinds, got %v", mmu.watchKinds)
+	}
+}
+
+// TestWatchpointConditions tests value and change conditions
+func TestWatchpointConditions(t *testing.T) {
+	mmu := NewMMU()
+
+	matched, changed := 0, 0
+	mmu.AddWatchpoint(Watchpoint{
+		Start: 0xC100, End: 0xC100, Kinds: AccessWrite,
+		MatchValue: true, Value: 0x99,
+		Callback: func(a Access) { matched++ },
+	})
+	mmu.AddWatchpoint(Watchpoint{
+		Start: 0xC100, End: 0xC100, Kinds: AccessWrite,
+		OnlyChanges: true,
+		Callback:    func(a Access) { changed++ },
+	})
+
+	mmu.WriteByte(0xC100, 0x01)
+	mmu.WriteByte(0xC100, 0x01)
+	mmu.WriteByte(0xC100, 0x99)
+
+	if matched != 1 {
+		t.Errorf("Expected 1 write of 0x99, got %d", matched)
+	}
+	if changed != 2 {
+		t.Errorf("Expected 2 writes that changed the value, got %d", changed)
+	}
+}
+
+// TestWatchpointExecute tests that execute watchpoints fire from the CPU
+func TestWatchpointExecute(t *testing.T) {
+	mmu := NewMMU()
+	mmu.DisableBIOS()
+	mmu.SetCartridge(&MockCartridge{})
+	c, _ := cpu.NewCPU(mmu)
+
+	var executed []uint16
+	mmu.AddWatchpoint(Watchpoint{
+		Start: 0x0101, End: 0x0101, Kinds: AccessExecute,
+		Callback: func(a Access) { executed = append(executed, a.Addr) },
+	})
+
+	// The cartridge is all NOPs
+	c.Step()
+	c.Step()
+	c.Step()
+
+	if len(executed) != 1 || executed[0] != 0x0101 {
+		t.Errorf("Expected one execution at 0x0101, got %v", executed)
+	}
+}
+
+// TestWatchpointDMA tests that reads by OAM DMA are marked
+func TestWatchpointDMA(t *testing.T) {
+	mmu := NewMMU()
+
+	var reads []Access
+	mmu.AddWatchpoint(Watchpoint{
+		Start: 0xC000, End: 0xC0FF, Kinds: AccessRead,
+		Callback: func(a Access) { reads = append(reads, a) },
+	})
+
+	mmu.ReadByte(0xC000)
+	mmu.WriteByte(0xFF46, 0xC0)
+
+	if len(reads) != 161 {
+		t.Fatalf("Expected 161 reads, got %d", len(reads))
+	}
+	if reads[0].DMA || !reads[1].DMA || !reads[160].DMA {
+		t.Errorf("Expected only the reads by DMA to be marked")
+	}
+}
+
+// TestWatchpointCallbackAccess tests that callbacks can access memory
+// without triggering watchpoints again
+func TestWatchpointCallbackAccess(t *testing.T) {
+	mmu := NewMMU()
+
+	calls := 0
+	mmu.AddWatchpoint(Watchpoint{
+		Start: 0xC000, End: 0xC000, Kinds: AccessRead | AccessWrite,
+		Callback: func(a Access) {
+			calls++
+			mmu.WriteByte(0xC000, mmu.ReadByte(0xC000)+1)
+		},
+	})
+
+	mmu.WriteByte(0xC000, 0x10)
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	if value := mmu.readByte(0xC000); value != 0x11 {
+		t.Errorf("Expected the callback's write, got 0x%02X", value)
+	}
+}
@@ -1,6 +1,10 @@
 package ppu
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
+)
 
 // PPU modes
 const (
@@ -50,6 +54,9 @@ type PPU struct {
 
 	// Reference to MMU for memory access
 	mmu MMU
+
+	// Verbose per-step logging
+	debug bool
 }
 
 // MMU interface for PPU to access memory
@@ -99,18 +106,46 @@ func (ppu *PPU) Reset() {
 	ppu.mmu.WriteByte(0xFF44, 0)
 }
 
+// SetDebug enables verbose per-step PPU logging
+func (ppu *PPU) SetDebug(debug bool) {
+	ppu.debug = debug
+}
+
+// debugf prints a PPU trace message when debug logging is enabled
+func (ppu *PPU) debugf(format string, args ...interface{}) {
+	if ppu.debug {
+		fmt.Printf(format, args...)
+	}
+}
+
+// logRenderedPixels logs how many pixels of the current scanline are non-zero
+func (ppu *PPU) logRenderedPixels() {
+	nonZeroPixels := 0
+	startIdx := int(ppu.line) * SCREEN_WIDTH
+	endIdx := startIdx + SCREEN_WIDTH
+	if endIdx > len(ppu.screenBuffer) {
+		endIdx = len(ppu.screenBuffer)
+	}
+	for i := startIdx; i < endIdx; i++ {
+		if ppu.screenBuffer[i] != 0 {
+			nonZeroPixels++
+		}
+	}
+	ppu.debugf("[PPU] Scanline %d rendered %d non-zero pixels\n", ppu.line, nonZeroPixels)
+}
+
 // Step advances the PPU by the specified number of cycles
 func (ppu *PPU) Step(cycles int) {
-	// Debug: Log PPU steps (remove this later)
-	if cycles > 0 {
-		fmt.Printf("[PPU] Step called with %d cycles, modeClock=%d, mode=%d, line=%d\n", cycles, ppu.modeClock, ppu.mode, ppu.line)
+	// Debug: Log PPU steps
+	if ppu.debug && cycles > 0 {
+		ppu.debugf("[PPU] Step called with %d cycles, modeClock=%d, mode=%d, line=%d\n", cycles, ppu.modeClock, ppu.mode, ppu.line)
 	}
 
 	// Check if LCD is enabled
 	lcdc := ppu.mmu.ReadByte(0xFF40)
 	if (lcdc & LCDC_DISPLAY_ENABLE) == 0 {
 		// LCD is disabled
-		fmt.Printf("[PPU] LCD disabled, LCDC=0x%02X\n", lcdc)
+		ppu.debugf("[PPU] LCD disabled, LCDC=0x%02X\n", lcdc)
 		return
 	}
 
@@ -122,7 +157,7 @@ func (ppu *PPU) Step(cycles int) {
 	case MODE_OAM:
 		// OAM Search - 80 cycles
 		if ppu.modeClock >= 80 {
-			fmt.Printf("[PPU] OAM->VRAM transition, modeClock=%d\n", ppu.modeClock)
+			ppu.debugf("[PPU] OAM->VRAM transition, modeClock=%d\n", ppu.modeClock)
 			ppu.modeClock -= 80
 			ppu.mode = MODE_VRAM
 			ppu.updateSTAT()
@@ -131,41 +166,32 @@ func (ppu *PPU) Step(cycles int) {
 	case MODE_VRAM:
 		// Pixel Transfer - 172 cycles
 		if ppu.modeClock >= 172 {
-			fmt.Printf("[PPU] VRAM->HBLANK transition, modeClock=%d\n", ppu.modeClock)
+			ppu.debugf("[PPU] VRAM->HBLANK transition, modeClock=%d\n", ppu.modeClock)
 			ppu.modeClock -= 172
 			ppu.mode = MODE_HBLANK
 			ppu.updateSTAT()
 
 			// Render scanline
-			fmt.Printf("[PPU] Rendering scanline %d\n", ppu.line)
+			ppu.debugf("[PPU] Rendering scanline %d\n", ppu.line)
 			ppu.renderScanline()
 
 			// Debug: Check if anything was rendered
-			nonZeroPixels := 0
-			startIdx := int(ppu.line) * SCREEN_WIDTH
-			endIdx := startIdx + SCREEN_WIDTH
-			if endIdx > len(ppu.screenBuffer) {
-				endIdx = len(ppu.screenBuffer)
-			}
-			for i := startIdx; i < endIdx; i++ {
-				if ppu.screenBuffer[i] != 0 {
-					nonZeroPixels++
-				}
+			if ppu.debug {
+				ppu.logRenderedPixels()
 			}
-			fmt.Printf("[PPU] Scanline %d rendered %d non-zero pixels\n", ppu.line, nonZeroPixels)
 		}
 
 	case MODE_HBLANK:
 		// H-Blank - 204 cycles
 		if ppu.modeClock >= 204 {
-			fmt.Printf("[PPU] HBLANK line complete, line=%d->%d\n", ppu.line, ppu.line+1)
+			ppu.debugf("[PPU] HBLANK line complete, line=%d->%d\n", ppu.line, ppu.line+1)
 			ppu.modeClock -= 204
 			oldLine := ppu.line
 			ppu.line++
 
 			// Check if we've reached the bottom of the screen
 			if ppu.line == 144 {
-				fmt.Printf("[PPU] Entering VBLANK\n")
+				ppu.debugf("[PPU] Entering VBLANK\n")
 				ppu.mode = MODE_VBLANK
 				ppu.updateSTAT()
 
@@ -177,7 +203,7 @@ func (ppu *PPU) Step(cycles int) {
 			}
 
 			// Update LY register (use direct write to avoid reset handler)
-			fmt.Printf("[PPU] Writing LY register: %d (was %d)\n", ppu.line, oldLine)
+			ppu.debugf("[PPU] Writing LY register: %d (was %d)\n", ppu.line, oldLine)
 			if mmuWithDirect, ok := ppu.mmu.(interface{ WriteIODirect(uint16, byte) }); ok {
 				mmuWithDirect.WriteIODirect(0xFF44, ppu.line)
 			} else {
@@ -898,3 +924,21 @@ func (ppu *PPU) handleWXWrite(value byte) {
 	// Note: In real hardware, changing WX during a scanline can cause glitches
 	// For now, we'll allow it and handle it in the rendering logic
 }
+
+// SaveState writes the PPU mode, timing and screen buffer
+func (ppu *PPU) SaveState(w *snapshot.StateWriter) {
+	w.Section("PPU ")
+	w.Uint8(ppu.mode)
+	w.Int(ppu.modeClock)
+	w.Uint8(ppu.line)
+	w.Bytes(ppu.screenBuffer[:])
+}
+
+// LoadState restores the PPU state written by SaveState
+func (ppu *PPU) LoadState(r *snapshot.StateReader) {
+	r.Section("PPU ")
+	ppu.mode = r.Uint8()
+	ppu.modeClock = r.Int()
+	ppu.line = r.Uint8()
+	r.BytesInto(ppu.screenBuffer[:])
+}
@@ -0,0 +1,380 @@
+package printer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/serial"
+)
+
+// Packet layout (all sent by the Game Boy, which drives the clock)
+//
+//	0x88 0x33    magic
+//	command      1 byte
+//	compression  1 byte, 1 if the data is run-length encoded
+//	length       2 bytes, little endian
+//	data         length bytes
+//	checksum     2 bytes, little endian sum of command through data
+//	0x00 0x00    the printer answers 0x81 (alive) and then its status
+//
+// The printer replies 0x00 to every other byte.
+const (
+	CMD_INIT   = 0x01 // Clear the image buffer
+	CMD_PRINT  = 0x02 // Print the buffered image
+	CMD_DATA   = 0x04 // Append tile data to the image buffer
+	CMD_STATUS = 0x0F // Report status only
+)
+
+// Status byte bits
+const (
+	STATUS_CHECKSUM_ERROR = 0x01
+	STATUS_BUSY           = 0x02
+	STATUS_FULL           = 0x04
+	STATUS_UNPROCESSED    = 0x08
+	STATUS_PACKET_ERROR   = 0x10
+)
+
+const (
+	// Reply to the first byte after the checksum
+	aliveReply = 0x81
+
+	// A DATA packet holds at most two rows of 20 tiles
+	maxPacketData = 0x280
+
+	// The printer buffers at most 9 DATA packets (160x144 pixels)
+	maxImageData = maxPacketData * 9
+
+	// Width of the paper in pixels and tiles
+	paperWidth   = 160
+	tilesPerRow  = paperWidth / 8
+	bytesPerTile = 16
+
+	// Blank pixel rows fed per margin unit
+	marginRows = 8
+
+	// STATUS packets answered busy after a PRINT before the print finishes
+	printDuration = 3
+)
+
+// Packet parser states
+const (
+	stateMagic1 = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthLow
+	stateLengthHigh
+	stateData
+	stateChecksumLow
+	stateChecksumHigh
+	stateAlive
+	stateStatus
+)
+
+// Print shades from lightest to darkest
+var shades = [4]color.Gray{{0xFF}, {0xAA}, {0x55}, {0x00}}
+
+// Printer emulates the Game Boy Printer as a serial.SerialPeer. Printed
+// images are written as PNG files to the output directory.
+type Printer struct {
+	outputDir string
+
+	// Packet being received
+	state       int
+	command     byte
+	compressed  bool
+	length      int
+	data        []byte
+	checksum    uint16
+	rxChecksum  uint16
+	packetError bool
+
+	status byte
+
+	// Remaining STATUS packets before a print completes
+	printing int
+
+	// Decompressed tile data received since the last INIT
+	image []byte
+
+	// Rows printed since the paper was last fed out, written to a file when
+	// a print ends with a bottom margin
+	sheet []byte
+
+	printCount int
+}
+
+// NewPrinter creates a printer writing PNG files to outputDir
+func NewPrinter(outputDir string) *Printer {
+	return &Printer{
+		outputDir: outputDir,
+	}
+}
+
+// Exchange receives one byte of a packet and returns the printer's reply
+func (p *Printer) Exchange(out byte) byte {
+	switch p.state {
+	case stateMagic1:
+		if out == 0x88 {
+			p.state = stateMagic2
+		}
+	case stateMagic2:
+		switch out {
+		case 0x33:
+			p.state = stateCommand
+		case 0x88:
+			// Still in sync, wait for the second magic byte
+		default:
+			p.state = stateMagic1
+		}
+	case stateCommand:
+		p.command = out
+		p.checksum = uint16(out)
+		p.data = p.data[:0]
+		p.packetError = false
+		p.state = stateCompression
+	case stateCompression:
+		p.compressed = out&0x01 != 0
+		p.checksum += uint16(out)
+		p.state = stateLengthLow
+	case stateLengthLow:
+		p.length = int(out)
+		p.checksum += uint16(out)
+		p.state = stateLengthHigh
+	case stateLengthHigh:
+		p.length |= int(out) << 8
+		p.checksum += uint16(out)
+		if p.length > maxPacketData {
+			p.packetError = true
+		}
+		if p.length == 0 {
+			p.state = stateChecksumLow
+		} else {
+			p.state = stateData
+		}
+	case stateData:
+		if len(p.data) < maxPacketData {
+			p.data = append(p.data, out)
+		}
+		p.checksum += uint16(out)
+		p.length--
+		if p.length == 0 {
+			p.state = stateChecksumLow
+		}
+	case stateChecksumLow:
+		p.rxChecksum = uint16(out)
+		p.state = stateChecksumHigh
+	case stateChecksumHigh:
+		p.rxChecksum |= uint16(out) << 8
+		p.handlePacket()
+		p.state = stateAlive
+	case stateAlive:
+		p.state = stateStatus
+		return aliveReply
+	case stateStatus:
+		p.state = stateMagic1
+		return p.status
+	}
+
+	return 0x00
+}
+
+// Poll does nothing, the printer never drives the clock
+func (p *Printer) Poll(s *serial.Serial) {}
+
+// handlePacket runs the command of a fully received packet
+func (p *Printer) handlePacket() {
+	if p.rxChecksum != p.checksum {
+		p.status |= STATUS_CHECKSUM_ERROR
+		return
+	}
+	p.status &^= STATUS_CHECKSUM_ERROR | STATUS_PACKET_ERROR
+	if p.packetError {
+		p.status |= STATUS_PACKET_ERROR
+		return
+	}
+
+	switch p.command {
+	case CMD_INIT:
+		p.image = p.image[:0]
+		p.printing = 0
+		p.status = 0
+	case CMD_DATA:
+		p.receiveData()
+	case CMD_PRINT:
+		p.print()
+	case CMD_STATUS:
+		if p.printing > 0 {
+			p.printing--
+			if p.printing == 0 {
+				p.status &^= STATUS_BUSY | STATUS_FULL
+			}
+		}
+	default:
+		p.status |= STATUS_PACKET_ERROR
+	}
+}
+
+// receiveData appends the packet data to the image buffer
+func (p *Printer) receiveData() {
+	data := p.data
+	if p.compressed {
+		data = decompress(data)
+	}
+
+	// An empty DATA packet marks the end of the image
+	if len(data) == 0 {
+		p.status |= STATUS_FULL
+		return
+	}
+
+	room := maxImageData - len(p.image)
+	if len(data) > room {
+		data = data[:room]
+	}
+	p.image = append(p.image, data...)
+
+	p.status |= STATUS_UNPROCESSED
+	if len(p.image) >= maxImageData {
+		p.status |= STATUS_FULL
+	}
+}
+
+// decompress expands the printer's run-length encoding: a control byte with
+// bit 7 set repeats the next byte (c&0x7F)+2 times, otherwise the next
+// c+1 bytes are copied
+func decompress(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		c := data[i]
+		i++
+		if c&0x80 != 0 {
+			if i >= len(data) {
+				break
+			}
+			for n := int(c&0x7F) + 2; n > 0; n-- {
+				out = append(out, data[i])
+			}
+			i++
+		} else {
+			n := int(c) + 1
+			if i+n > len(data) {
+				n = len(data) - i
+			}
+			out = append(out, data[i:i+n]...)
+			i += n
+		}
+	}
+	return out
+}
+
+// print renders the image buffer with the palette and margins from the
+// PRINT packet
+func (p *Printer) print() {
+	if len(p.data) < 4 {
+		p.status |= STATUS_PACKET_ERROR
+		return
+	}
+
+	// data[0] is the number of sheets and data[3] the exposure, neither
+	// affects the output image
+	topMargin := int(p.data[1] >> 4)
+	bottomMargin := int(p.data[1] & 0x0F)
+	palette := p.data[2]
+	if palette == 0 {
+		// Games that never set a palette get the standard one
+		palette = 0xE4
+	}
+
+	p.feed(topMargin)
+	p.sheet = append(p.sheet, decodeTiles(p.image, palette)...)
+	p.feed(bottomMargin)
+
+	// The paper is only cut once a print feeds it out
+	if bottomMargin > 0 {
+		if err := p.Flush(); err != nil {
+			log.Printf("[Printer] Failed to save print: %v", err)
+		}
+	}
+
+	p.image = p.image[:0]
+	p.status = STATUS_BUSY | STATUS_FULL
+	p.printing = printDuration
+}
+
+// feed adds blank rows to the current sheet
+func (p *Printer) feed(units int) {
+	for i := 0; i < units*marginRows*paperWidth; i++ {
+		p.sheet = append(p.sheet, shades[0].Y)
+	}
+}
+
+// decodeTiles converts 2bpp tile data, 20 tiles per row, into grayscale
+// pixel rows using the given palette
+func decodeTiles(data []byte, palette byte) []byte {
+	rows := len(data) / (tilesPerRow * bytesPerTile)
+	pixels := make([]byte, rows*8*paperWidth)
+
+	for row := 0; row < rows; row++ {
+		for tile := 0; tile < tilesPerRow; tile++ {
+			base := (row*tilesPerRow + tile) * bytesPerTile
+			for y := 0; y < 8; y++ {
+				low := data[base+y*2]
+				high := data[base+y*2+1]
+				for x := 0; x < 8; x++ {
+					bit := 7 - x
+					colorIndex := (low>>bit)&0x01 | ((high>>bit)&0x01)<<1
+					shade := (palette >> (colorIndex * 2)) & 0x03
+					pixels[(row*8+y)*paperWidth+tile*8+x] = shades[shade].Y
+				}
+			}
+		}
+	}
+
+	return pixels
+}
+
+// Flush writes any printed rows that have not been fed out yet to a PNG file
+func (p *Printer) Flush() error {
+	if len(p.sheet) == 0 {
+		return nil
+	}
+
+	img := image.NewGray(image.Rect(0, 0, paperWidth, len(p.sheet)/paperWidth))
+	copy(img.Pix, p.sheet)
+	p.sheet = p.sheet[:0]
+
+	if err := os.MkdirAll(p.outputDir, 0755); err != nil {
+		return err
+	}
+
+	p.printCount++
+	name := fmt.Sprintf("print-%s-%03d.png", time.Now().Format("20060102-150405"), p.printCount)
+	path := filepath.Join(p.outputDir, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("[Printer] Saved print to %s", path)
+	return nil
+}
+
+// Close writes any unfinished print
+func (p *Printer) Close() error {
+	return p.Flush()
+}
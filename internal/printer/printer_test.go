@@ -0,0 +1,191 @@
+package printer
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sendPacket sends a complete packet and returns the printer's replies
+func sendPacket(p *Printer, command byte, compressed bool, data []byte) []byte {
+	var compression byte
+	if compressed {
+		compression = 1
+	}
+
+	packet := []byte{0x88, 0x33, command, compression, byte(len(data)), byte(len(data) >> 8)}
+	packet = append(packet, data...)
+
+	var checksum uint16
+	for _, b := range packet[2:] {
+		checksum += uint16(b)
+	}
+	packet = append(packet, byte(checksum), byte(checksum>>8), 0x00, 0x00)
+
+	replies := make([]byte, len(packet))
+	for i, b := range packet {
+		replies[i] = p.Exchange(b)
+	}
+	return replies
+}
+
+// TestPacketReplies tests the alive byte and status at the end of a packet
+func TestPacketReplies(t *testing.T) {
+	p := NewPrinter(t.TempDir())
+
+	replies := sendPacket(p, CMD_INIT, false, nil)
+	for i, b := range replies[:len(replies)-2] {
+		if b != 0x00 {
+			t.Errorf("Expected reply 0x00 at byte %d, got 0x%02X", i, b)
+		}
+	}
+	if replies[len(replies)-2] != 0x81 {
+		t.Errorf("Expected alive reply 0x81, got 0x%02X", replies[len(replies)-2])
+	}
+	if replies[len(replies)-1] != 0x00 {
+		t.Errorf("Expected status 0x00 after INIT, got 0x%02X", replies[len(replies)-1])
+	}
+
+	replies = sendPacket(p, CMD_DATA, false, make([]byte, maxPacketData))
+	if status := replies[len(replies)-1]; status != STATUS_UNPROCESSED {
+		t.Errorf("Expected status 0x%02X after DATA, got 0x%02X", STATUS_UNPROCESSED, status)
+	}
+}
+
+// TestChecksumError tests that a bad checksum is reported and the packet ignored
+func TestChecksumError(t *testing.T) {
+	p := NewPrinter(t.TempDir())
+
+	packet := []byte{0x88, 0x33, CMD_DATA, 0x00, 0x01, 0x00, 0xAA, 0x00, 0x00, 0x00, 0x00}
+	var status byte
+	for _, b := range packet {
+		status = p.Exchange(b)
+	}
+
+	if status&STATUS_CHECKSUM_ERROR == 0 {
+		t.Errorf("Expected checksum error status, got 0x%02X", status)
+	}
+	if len(p.image) != 0 {
+		t.Errorf("Expected data to be ignored, got %d bytes", len(p.image))
+	}
+
+	// The next good packet clears the error
+	replies := sendPacket(p, CMD_STATUS, false, nil)
+	if status := replies[len(replies)-1]; status&STATUS_CHECKSUM_ERROR != 0 {
+		t.Errorf("Expected checksum error to clear, got 0x%02X", status)
+	}
+}
+
+// TestDecompress tests the printer's run-length encoding
+func TestDecompress(t *testing.T) {
+	data := []byte{0x81, 0xAB, 0x01, 0x11, 0x22}
+	expected := []byte{0xAB, 0xAB, 0xAB, 0x11, 0x22}
+
+	got := decompress(data)
+	if string(got) != string(expected) {
+		t.Errorf("Expected % X, got % X", expected, got)
+	}
+}
+
+// TestPrint tests printing an image with a palette and margins to a PNG file
+func TestPrint(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPrinter(dir)
+
+	sendPacket(p, CMD_INIT, false, nil)
+
+	// Two tile rows where every pixel uses color 1
+	tiles := make([]byte, maxPacketData)
+	for i := 0; i < len(tiles); i += 2 {
+		tiles[i] = 0xFF
+	}
+	sendPacket(p, CMD_DATA, false, tiles)
+	sendPacket(p, CMD_DATA, false, nil)
+
+	// One unit of margin above and below, with color 1 printed black
+	replies := sendPacket(p, CMD_PRINT, false, []byte{0x01, 0x11, 0x0C, 0x40})
+	if status := replies[len(replies)-1]; status&STATUS_BUSY == 0 {
+		t.Errorf("Expected busy status after PRINT, got 0x%02X", status)
+	}
+
+	// The printer finishes after a few status requests
+	var status byte
+	for i := 0; i < printDuration; i++ {
+		replies = sendPacket(p, CMD_STATUS, false, nil)
+		status = replies[len(replies)-1]
+	}
+	if status != 0x00 {
+		t.Errorf("Expected status 0x00 after printing, got 0x%02X", status)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.png"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 PNG file, got %d", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Failed to open print: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode print: %v", err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != 160 || bounds.Dy() != 32 {
+		t.Fatalf("Expected 160x32 image, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	// Margin rows are white and image rows black
+	if r, _, _, _ := img.At(0, 0).RGBA(); r != 0xFFFF {
+		t.Errorf("Expected white margin, got 0x%04X", r)
+	}
+	if r, _, _, _ := img.At(80, 16).RGBA(); r != 0 {
+		t.Errorf("Expected black pixel, got 0x%04X", r)
+	}
+	if r, _, _, _ := img.At(0, 31).RGBA(); r != 0xFFFF {
+		t.Errorf("Expected white margin, got 0x%04X", r)
+	}
+}
+
+// TestPrintContinues tests that prints without a bottom margin are joined
+func TestPrintContinues(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPrinter(dir)
+
+	for i := 0; i < 2; i++ {
+		sendPacket(p, CMD_INIT, false, nil)
+		sendPacket(p, CMD_DATA, false, make([]byte, maxPacketData))
+		sendPacket(p, CMD_PRINT, false, []byte{0x01, 0x00, 0xE4, 0x40})
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.png"))
+	if len(files) != 0 {
+		t.Fatalf("Expected no PNG file before the paper is fed out, got %d", len(files))
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, _ = filepath.Glob(filepath.Join(dir, "*.png"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 PNG file, got %d", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Failed to open print: %v", err)
+	}
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("Failed to decode print: %v", err)
+	}
+	if cfg.Height != 32 {
+		t.Errorf("Expected joined height 32, got %d", cfg.Height)
+	}
+}
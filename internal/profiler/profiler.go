@@ -0,0 +1,326 @@
+// Package profiler attributes emulated CPU cycles to the guest code that
+// spent them and writes pprof profiles, so go tool pprof can show where a
+// ROM's frame budget goes
+package profiler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+	"github.com/briancain/gameboy-go/internal/timer"
+)
+
+// Function names for code outside any subroutine with a label
+const (
+	topLevelFunction = "[top level]"
+	haltedFunction   = "[halted]"
+)
+
+// Interrupt names by vector, for interrupt handlers without a label
+var interruptNames = map[uint16]string{
+	0x0040: "VBlank",
+	0x0048: "STAT",
+	0x0050: "Timer",
+	0x0058: "Serial",
+	0x0060: "Joypad",
+}
+
+// Profiler is a cpu.StepObserver that adds the cycles of every step to the
+// call stack it ran in. The CPU must track calls, see cpu.TrackCalls.
+//
+// Cycles spent waiting in HALT are attributed to a [halted] function called
+// from the HALT instruction, and interrupt dispatch to the handler it enters.
+type Profiler struct {
+	name    string
+	banker  symbols.ROMBanker
+	symbols *symbols.Table
+	start   time.Time
+
+	// The instruction about to run, set by BeforeInstruction for AfterStep
+	pc        uint16
+	frames    []cpu.CallFrame
+	executing bool
+
+	// Reused to look up samples without allocating
+	key []byte
+
+	samples      map[string]*sample
+	order        []*sample
+	cycles       uint64
+	instructions uint64
+}
+
+// sample is the cost of one call stack
+type sample struct {
+	// Innermost location first
+	stack        []location
+	instructions int64
+	cycles       int64
+}
+
+// location is an address in a function. bank is the ROM bank at addr, or
+// -1 outside ROM.
+type location struct {
+	bank     int
+	addr     uint16
+	function string
+}
+
+// New creates a profiler for the ROM called name. ROM addresses are looked
+// up in the bank banker has mapped, and named by the labels in table, which
+// may be nil.
+func New(name string, banker symbols.ROMBanker, table *symbols.Table) *Profiler {
+	return &Profiler{
+		name:    name,
+		banker:  banker,
+		symbols: table,
+		start:   time.Now(),
+		samples: make(map[string]*sample),
+	}
+}
+
+// BeforeInstruction records the call stack of the instruction at PC
+func (p *Profiler) BeforeInstruction(c *cpu.Z80) {
+	p.pc = c.GetRegisters().PC
+	p.frames = c.AppendCallStack(p.frames[:0])
+	p.executing = true
+}
+
+// AfterStep adds the cycles of the step to the stack it ran in
+func (p *Profiler) AfterStep(c *cpu.Z80, cycles int) {
+	p.cycles += uint64(cycles)
+
+	if p.executing {
+		p.executing = false
+		p.instructions++
+		p.add(p.pc, p.frames, false, 1, cycles)
+		return
+	}
+
+	// No instruction ran, the CPU was halted or entered an interrupt handler
+	p.frames = c.AppendCallStack(p.frames[:0])
+	p.add(c.GetRegisters().PC, p.frames, c.Halted(), 0, cycles)
+}
+
+// add adds to the sample at pc in frames
+func (p *Profiler) add(pc uint16, frames []cpu.CallFrame, halted bool, instructions, cycles int) {
+	key := p.key[:0]
+	key = appendLocation(key, halted, pc, symbols.BankAt(pc, p.banker))
+	for _, f := range frames {
+		key = appendLocation(key, f.Interrupt, f.CallSite, symbols.BankAt(f.CallSite, p.banker))
+		key = append(key, byte(f.Target), byte(f.Target>>8))
+	}
+	p.key = key
+
+	s, ok := p.samples[string(key)]
+	if !ok {
+		s = &sample{stack: p.stack(pc, frames, halted)}
+		p.samples[string(key)] = s
+		p.order = append(p.order, s)
+	}
+	s.instructions += int64(instructions)
+	s.cycles += int64(cycles)
+}
+
+// stack resolves the locations of a sample, innermost first. Each caller is
+// at its CALL, or at the instruction an interrupt preempted.
+func (p *Profiler) stack(pc uint16, frames []cpu.CallFrame, halted bool) []location {
+	var stack []location
+	if halted {
+		stack = append(stack, location{bank: symbols.BankAt(pc, p.banker), addr: pc, function: haltedFunction})
+	}
+
+	// frames[i] was entered from the function of frames[i-1]
+	addr := pc
+	for i := len(frames); i >= 0; i-- {
+		var entered *cpu.CallFrame
+		if i > 0 {
+			entered = &frames[i-1]
+		}
+		stack = append(stack, p.location(addr, entered))
+		if i > 0 {
+			addr = frames[i-1].CallSite
+		}
+	}
+	return stack
+}
+
+// location names addr by the closest label before it, without the local
+// part, or else by the frame it is running in
+func (p *Profiler) location(addr uint16, frame *cpu.CallFrame) location {
+	loc := location{bank: symbols.BankAt(addr, p.banker), addr: addr}
+
+	if s, _, ok := p.symbols.Nearest(loc.bank, addr); ok {
+		loc.function, _, _ = strings.Cut(s.Name, ".")
+		return loc
+	}
+
+	switch {
+	case frame == nil:
+		loc.function = topLevelFunction
+	case frame.Interrupt && interruptNames[frame.Target] != "":
+		loc.function = fmt.Sprintf("[%s interrupt]", interruptNames[frame.Target])
+	default:
+		loc.function = "sub_" + formatAddress(symbols.BankAt(frame.Target, p.banker), frame.Target)
+	}
+	return loc
+}
+
+// Cycles returns the number of cycles profiled
+func (p *Profiler) Cycles() uint64 {
+	return p.cycles
+}
+
+// Instructions returns the number of instructions profiled
+func (p *Profiler) Instructions() uint64 {
+	return p.instructions
+}
+
+// WriteProfile writes the profile as a gzipped profile.proto, the format
+// go tool pprof reads. It has two sample types, instructions and cycles,
+// with cycles shown by default.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	var b profileBuilder
+	b.strings = map[string]int64{"": 0}
+	b.stringTable = []string{""}
+	b.locations = make(map[location]uint64)
+	b.functions = make(map[string]uint64)
+
+	for _, sampleType := range []string{"instructions", "cycles"} {
+		var vt protoBuffer
+		vt.int64(valueTypeType, b.str(sampleType))
+		vt.int64(valueTypeUnit, b.str("count"))
+		b.out.message(profileSampleType, &vt)
+	}
+
+	for _, s := range p.order {
+		ids := make([]uint64, len(s.stack))
+		for i, loc := range s.stack {
+			ids[i] = b.location(loc)
+		}
+		var sb protoBuffer
+		sb.packed(sampleLocationID, ids)
+		sb.packed(sampleValue, []uint64{uint64(s.instructions), uint64(s.cycles)})
+		b.out.message(profileSample, &sb)
+	}
+
+	// All addresses are in the ROM's single mapping, see addressOf
+	var mapping protoBuffer
+	mapping.uint64(mappingID, 1)
+	mapping.uint64(mappingMemoryStart, 0)
+	mapping.uint64(mappingMemoryLimit, 1<<32)
+	mapping.int64(mappingFilename, b.str(p.name))
+	mapping.bool(mappingHasFunctions, true)
+	b.out.message(profileMapping, &mapping)
+
+	b.out.data = append(b.out.data, b.locationData.data...)
+	b.out.data = append(b.out.data, b.functionData.data...)
+
+	var period protoBuffer
+	period.int64(valueTypeType, b.str("cycles"))
+	period.int64(valueTypeUnit, b.str("count"))
+
+	// Every string has been numbered by now
+	for _, s := range b.stringTable {
+		b.out.string(profileStringTable, s)
+	}
+	b.out.int64(profileTimeNanos, p.start.UnixNano())
+	b.out.int64(profileDurationNanos, int64(float64(p.cycles)/timer.CPU_CLOCK*float64(time.Second)))
+	b.out.message(profilePeriodType, &period)
+	b.out.int64(profilePeriod, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.out.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// profileBuilder encodes a profile, numbering strings, locations and
+// functions as they are first used
+type profileBuilder struct {
+	out          protoBuffer
+	locationData protoBuffer
+	functionData protoBuffer
+
+	strings     map[string]int64
+	stringTable []string
+	locations   map[location]uint64
+	functions   map[string]uint64
+}
+
+func (b *profileBuilder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.stringTable))
+	b.strings[s] = i
+	b.stringTable = append(b.stringTable, s)
+	return i
+}
+
+func (b *profileBuilder) location(loc location) uint64 {
+	if id, ok := b.locations[loc]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[loc] = id
+
+	var line protoBuffer
+	line.uint64(lineFunctionID, b.function(loc.function))
+
+	var lb protoBuffer
+	lb.uint64(locationID, id)
+	lb.uint64(locationMappingID, 1)
+	lb.uint64(locationAddress, addressOf(loc))
+	lb.message(locationLine, &line)
+	b.locationData.message(profileLocation, &lb)
+	return id
+}
+
+func (b *profileBuilder) function(name string) uint64 {
+	if id, ok := b.functions[name]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[name] = id
+
+	var fb protoBuffer
+	fb.uint64(functionID, id)
+	fb.int64(functionName, b.str(name))
+	fb.int64(functionSystemName, b.str(name))
+	b.functionData.message(profileFunction, &fb)
+	return id
+}
+
+// addressOf returns the address of a location in the profile. Addresses in
+// switchable ROM banks have the bank above them, 0x014A2F for 01:4A2F, so
+// every bank's code has its own addresses.
+func addressOf(loc location) uint64 {
+	if loc.bank > 0 {
+		return uint64(loc.bank)<<16 | uint64(loc.addr)
+	}
+	return uint64(loc.addr)
+}
+
+// formatAddress formats addr with its ROM bank, or alone outside ROM
+func formatAddress(bank int, addr uint16) string {
+	if bank >= 0 {
+		return fmt.Sprintf("%02X:%04X", bank, addr)
+	}
+	return fmt.Sprintf("%04X", addr)
+}
+
+// appendLocation appends a location to a sample key
+func appendLocation(key []byte, flag bool, addr uint16, bank int) []byte {
+	var flagByte byte
+	if flag {
+		flagByte = 1
+	}
+	return append(key, flagByte, byte(addr), byte(addr>>8), byte(bank), byte(bank>>8))
+}
@@ -0,0 +1,244 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/symbols"
+)
+
+// program calls a subroutine and halts
+//
+//	0100: CALL $0110   24 cycles
+//	0103: HALT          4 cycles, then 4 per step halted
+//	0110: INC C         4 cycles
+//	0111: RET          16 cycles
+var program = map[uint16][]byte{
+	0x0100: {0xCD, 0x10, 0x01, 0x76},
+	0x0110: {0x0C, 0xC9},
+}
+
+// runProgram profiles program for steps steps
+func runProgram(t *testing.T, table *symbols.Table, steps int) *Profiler {
+	t.Helper()
+
+	mmu := &MockMMU{}
+	for addr, code := range program {
+		copy(mmu.memory[addr:], code)
+	}
+	c, _ := cpu.NewCPU(mmu)
+	c.TrackCalls(true)
+
+	p := New("TEST", nil, table)
+	c.AddObserver(p)
+	for i := 0; i < steps; i++ {
+		c.Step()
+	}
+	return p
+}
+
+// TestProfile tests attributing cycles to labelled functions and call stacks
+func TestProfile(t *testing.T) {
+	table, _ := symbols.Parse(strings.NewReader("00:0100 Main\n00:0110 Sub\n00:0111 Sub.done\n"))
+	p := runProgram(t, table, 7)
+
+	if p.Cycles() != 60 || p.Instructions() != 4 {
+		t.Errorf("Expected 4 instructions and 60 cycles, got %d and %d", p.Instructions(), p.Cycles())
+	}
+
+	prof := decodeProfile(t, p)
+	if leaf := prof.leafCycles(); leaf["Main"] != 28 || leaf["Sub"] != 20 || leaf["[halted]"] != 12 {
+		t.Errorf("Expected Main 28, Sub 20 and [halted] 12 cycles, got %v", leaf)
+	}
+	for _, s := range prof.samples {
+		if stack := prof.stack(s); stack[0] == "Sub" && strings.Join(stack, " ") != "Sub Main" {
+			t.Errorf("Expected Sub to be called from Main, got %v", stack)
+		}
+	}
+	if prof.sampleTypes != "instructions/count cycles/count" {
+		t.Errorf("Expected instructions and cycles sample types, got %q", prof.sampleTypes)
+	}
+}
+
+// TestProfileWithoutSymbols tests naming functions by their call frames
+func TestProfileWithoutSymbols(t *testing.T) {
+	p := runProgram(t, nil, 3)
+
+	leaf := decodeProfile(t, p).leafCycles()
+	if leaf["[top level]"] != 24 || leaf["sub_00:0110"] != 20 {
+		t.Errorf("Expected [top level] 24 and sub_00:0110 20 cycles, got %v", leaf)
+	}
+}
+
+// testProfile is the part of a decoded profile the tests check
+type testProfile struct {
+	strings     []string
+	sampleTypes string
+	samples     []testSample
+	locations   map[uint64]uint64 // location ID to function ID
+	functions   map[uint64]string
+}
+
+type testSample struct {
+	locations []uint64
+	values    []uint64
+}
+
+// leafCycles sums cycles by the function each sample is in
+func (p *testProfile) leafCycles() map[string]uint64 {
+	cycles := make(map[string]uint64)
+	for _, s := range p.samples {
+		cycles[p.stack(s)[0]] += s.values[1]
+	}
+	return cycles
+}
+
+// stack returns the function names of a sample, innermost first
+func (p *testProfile) stack(s testSample) []string {
+	var names []string
+	for _, id := range s.locations {
+		names = append(names, p.functions[p.locations[id]])
+	}
+	return names
+}
+
+// decodeProfile writes a profile and decodes it
+func decodeProfile(t *testing.T, p *Profiler) *testProfile {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := p.WriteProfile(&buf); err != nil {
+		t.Fatalf("WriteProfile failed: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected a gzipped profile: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	prof := &testProfile{locations: make(map[uint64]uint64), functions: make(map[uint64]string)}
+	var sampleTypes [][2]uint64
+	var functionNames = make(map[uint64]uint64)
+	forEachField(t, data, func(field int, value uint64, bytes []byte) {
+		switch field {
+		case profileSampleType:
+			var vt [2]uint64
+			forEachField(t, bytes, func(field int, value uint64, _ []byte) { vt[field-1] = value })
+			sampleTypes = append(sampleTypes, vt)
+		case profileSample:
+			var s testSample
+			forEachField(t, bytes, func(field int, _ uint64, packed []byte) {
+				values := decodePacked(t, packed)
+				if field == sampleLocationID {
+					s.locations = values
+				} else {
+					s.values = values
+				}
+			})
+			prof.samples = append(prof.samples, s)
+		case profileLocation:
+			var id, function uint64
+			forEachField(t, bytes, func(field int, value uint64, line []byte) {
+				switch field {
+				case locationID:
+					id = value
+				case locationLine:
+					forEachField(t, line, func(_ int, value uint64, _ []byte) { function = value })
+				}
+			})
+			prof.locations[id] = function
+		case profileFunction:
+			var id, name uint64
+			forEachField(t, bytes, func(field int, value uint64, _ []byte) {
+				switch field {
+				case functionID:
+					id = value
+				case functionName:
+					name = value
+				}
+			})
+			functionNames[id] = name
+		case profileStringTable:
+			prof.strings = append(prof.strings, string(bytes))
+		}
+	})
+
+	for id, name := range functionNames {
+		prof.functions[id] = prof.strings[name]
+	}
+	var types []string
+	for _, vt := range sampleTypes {
+		types = append(types, prof.strings[vt[0]]+"/"+prof.strings[vt[1]])
+	}
+	prof.sampleTypes = strings.Join(types, " ")
+	return prof
+}
+
+// forEachField calls f with each field of a message, with the value of
+// varints and the data of length delimited fields
+func forEachField(t *testing.T, data []byte, f func(field int, value uint64, bytes []byte)) {
+	t.Helper()
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("Invalid varint")
+		}
+		data = data[n:]
+
+		switch key & 7 {
+		case wireVarint:
+			f(int(key>>3), value, nil)
+		case wireBytes:
+			f(int(key>>3), 0, data[:value])
+			data = data[value:]
+		default:
+			t.Fatalf("Unexpected wire type %d", key&7)
+		}
+	}
+}
+
+func decodePacked(t *testing.T, data []byte) []uint64 {
+	t.Helper()
+	var values []uint64
+	for len(data) > 0 {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("Invalid packed varint")
+		}
+		values = append(values, value)
+		data = data[n:]
+	}
+	return values
+}
+
+// MockMMU is a flat 64KB memory for testing
+type MockMMU struct {
+	memory [0x10000]byte
+}
+
+func (m *MockMMU) ReadByte(addr uint16) byte {
+	return m.memory[addr]
+}
+
+func (m *MockMMU) WriteByte(addr uint16, value byte) {
+	m.memory[addr] = value
+}
+
+func (m *MockMMU) ReadWord(addr uint16) uint16 {
+	return uint16(m.memory[addr]) | (uint16(m.memory[addr+1]) << 8)
+}
+
+func (m *MockMMU) WriteWord(addr uint16, value uint16) {
+	m.memory[addr] = byte(value & 0xFF)
+	m.memory[addr+1] = byte(value >> 8)
+}
@@ -0,0 +1,111 @@
+package profiler
+
+// A minimal protocol buffer encoder for the messages of pprof's
+// profile.proto (https://github.com/google/pprof/blob/main/proto/profile.proto),
+// so writing profiles needs no dependencies
+
+// Field numbers of the Profile message and the messages it contains
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileMapping       = 3
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	mappingID           = 1
+	mappingMemoryStart  = 2
+	mappingMemoryLimit  = 3
+	mappingFilename     = 5
+	mappingHasFunctions = 7
+
+	locationID        = 1
+	locationMappingID = 2
+	locationAddress   = 3
+	locationLine      = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+// Wire types
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// protoBuffer accumulates an encoded message
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint64 writes a varint field, omitting the default of zero
+func (b *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protoBuffer) bool(field int, x bool) {
+	if x {
+		b.uint64(field, 1)
+	}
+}
+
+// string writes a length delimited string, even if it is empty, as string
+// table entries must be
+func (b *protoBuffer) string(field int, s string) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+// packed writes repeated varints as a packed field
+func (b *protoBuffer) packed(field int, values []uint64) {
+	if len(values) == 0 {
+		return
+	}
+	var inner protoBuffer
+	for _, x := range values {
+		inner.varint(x)
+	}
+	b.message(field, &inner)
+}
+
+// message writes an embedded message
+func (b *protoBuffer) message(field int, m *protoBuffer) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(m.data)))
+	b.data = append(b.data, m.data...)
+}
@@ -0,0 +1,232 @@
+package rewind
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrEmpty is returned when there is no earlier state to step back to
+var ErrEmpty = errors.New("rewind buffer is empty")
+
+// Buffer keeps a bounded history of emulator states for rewinding.
+//
+// Only the most recent state is stored in full. Every older state is stored
+// as a backward delta: the XOR of that state with the next newer one,
+// run-length encoded. Consecutive frames differ in very few bytes, so the
+// XOR is almost entirely zeros and each delta is a small fraction of the
+// full state size.
+//
+// When the ring is full, pushing a new state drops the oldest delta and that
+// state is no longer reachable.
+type Buffer struct {
+	// Most recent full state
+	latest []byte
+	valid  bool
+
+	// Ring of encoded backward deltas; head is the index of the oldest entry
+	deltas [][]byte
+	head   int
+	count  int
+
+	// Scratch space reused between calls
+	xor []byte
+}
+
+// NewBuffer creates a rewind buffer that can step back up to capacity states
+func NewBuffer(capacity int) *Buffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Buffer{
+		deltas: make([][]byte, capacity),
+	}
+}
+
+// Push records state as the newest entry. The buffer keeps its own copy.
+func (b *Buffer) Push(state []byte) {
+	if b.valid {
+		// Store how to get from the new state back to the current latest one
+		b.xor = xorInto(b.xor, state, b.latest)
+		slot := (b.head + b.count) % len(b.deltas)
+		if b.count == len(b.deltas) {
+			// Full: overwrite the oldest delta
+			slot = b.head
+			b.head = (b.head + 1) % len(b.deltas)
+		} else {
+			b.count++
+		}
+		b.deltas[slot] = encodeDelta(b.deltas[slot][:0], len(b.latest), b.xor)
+	}
+
+	b.latest = append(b.latest[:0], state...)
+	b.valid = true
+}
+
+// Pop discards the newest state and returns the one recorded before it. The
+// returned slice is owned by the buffer and is only valid until the next call.
+func (b *Buffer) Pop() ([]byte, error) {
+	if b.count == 0 {
+		return nil, ErrEmpty
+	}
+
+	slot := (b.head + b.count - 1) % len(b.deltas)
+	prevLen, xor, err := decodeDelta(b.xor[:0], b.deltas[slot])
+	if err != nil {
+		return nil, err
+	}
+	b.xor = xor
+	b.count--
+
+	// latest XOR delta gives the previous state
+	for len(b.latest) < len(xor) {
+		b.latest = append(b.latest, 0)
+	}
+	for i, v := range xor {
+		b.latest[i] ^= v
+	}
+	b.latest = b.latest[:prevLen]
+
+	return b.latest, nil
+}
+
+// Latest returns the newest state, or nil if nothing has been pushed
+func (b *Buffer) Latest() []byte {
+	if !b.valid {
+		return nil
+	}
+	return b.latest
+}
+
+// Len returns the number of earlier states that can be popped
+func (b *Buffer) Len() int {
+	return b.count
+}
+
+// Capacity returns the maximum number of earlier states kept
+func (b *Buffer) Capacity() int {
+	return len(b.deltas)
+}
+
+// Size returns the number of bytes used to hold the history
+func (b *Buffer) Size() int {
+	size := len(b.latest)
+	for i := 0; i < b.count; i++ {
+		size += len(b.deltas[(b.head+i)%len(b.deltas)])
+	}
+	return size
+}
+
+// Reset discards all recorded states
+func (b *Buffer) Reset() {
+	b.latest = b.latest[:0]
+	b.valid = false
+	b.head = 0
+	b.count = 0
+}
+
+// xorInto stores a XOR b into dst, treating the shorter slice as zero padded
+func xorInto(dst, a, b []byte) []byte {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	if cap(dst) < n {
+		dst = make([]byte, n)
+	}
+	dst = dst[:n]
+	for i := range dst {
+		var x, y byte
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		dst[i] = x ^ y
+	}
+	return dst
+}
+
+// Delta encoding
+//
+//	prevLen uvarint  length of the older state
+//	xorLen  uvarint  length of the XOR data
+//	runs    ...      repeated (zeros uvarint, literals uvarint, literal bytes)
+//
+// Each run is a span of zero bytes followed by a span of bytes copied as-is.
+func encodeDelta(dst []byte, prevLen int, xor []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(prevLen))
+	dst = binary.AppendUvarint(dst, uint64(len(xor)))
+
+	i := 0
+	for i < len(xor) {
+		start := i
+		for i < len(xor) && xor[i] == 0 {
+			i++
+		}
+		zeros := i - start
+
+		start = i
+		for i < len(xor) {
+			// End the literal span at the next run of at least 4 zeros,
+			// shorter zero runs are cheaper to copy than to encode
+			if xor[i] == 0 && i+3 < len(xor) && xor[i+1] == 0 && xor[i+2] == 0 && xor[i+3] == 0 {
+				break
+			}
+			i++
+		}
+
+		dst = binary.AppendUvarint(dst, uint64(zeros))
+		dst = binary.AppendUvarint(dst, uint64(i-start))
+		dst = append(dst, xor[start:i]...)
+	}
+
+	return dst
+}
+
+func decodeDelta(dst []byte, data []byte) (int, []byte, error) {
+	prevLen, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errors.New("corrupt rewind delta header")
+	}
+	data = data[n:]
+	xorLen, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errors.New("corrupt rewind delta header")
+	}
+	data = data[n:]
+
+	if uint64(cap(dst)) < xorLen {
+		dst = make([]byte, 0, xorLen)
+	}
+	dst = dst[:0]
+
+	for len(data) > 0 {
+		zeros, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, nil, errors.New("corrupt rewind delta run")
+		}
+		data = data[n:]
+		literals, n := binary.Uvarint(data)
+		if n <= 0 || literals > uint64(len(data)-n) {
+			return 0, nil, errors.New("corrupt rewind delta run")
+		}
+		data = data[n:]
+
+		if uint64(len(dst))+zeros+literals > xorLen {
+			return 0, nil, fmt.Errorf("rewind delta overflows %d bytes", xorLen)
+		}
+		for ; zeros > 0; zeros-- {
+			dst = append(dst, 0)
+		}
+		dst = append(dst, data[:literals]...)
+		data = data[literals:]
+	}
+
+	if uint64(len(dst)) != xorLen || prevLen > xorLen {
+		return 0, nil, fmt.Errorf("rewind delta size mismatch: decoded %d bytes, expected %d", len(dst), xorLen)
+	}
+
+	return int(prevLen), dst, nil
+}
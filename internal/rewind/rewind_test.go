@@ -0,0 +1,96 @@
+package rewind
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestBufferPushPop tests stepping back through pushed states in order
+func TestBufferPushPop(t *testing.T) {
+	b := NewBuffer(8)
+
+	states := make([][]byte, 5)
+	for i := range states {
+		state := make([]byte, 4096)
+		state[100] = byte(i)
+		state[2000+i] = 0xFF
+		states[i] = state
+		b.Push(state)
+	}
+
+	if b.Len() != 4 {
+		t.Errorf("Expected 4 earlier states, got %d", b.Len())
+	}
+	if !bytes.Equal(b.Latest(), states[4]) {
+		t.Errorf("Expected latest to match last pushed state")
+	}
+
+	for i := 3; i >= 0; i-- {
+		got, err := b.Pop()
+		if err != nil {
+			t.Fatalf("Pop failed: %v", err)
+		}
+		if !bytes.Equal(got, states[i]) {
+			t.Errorf("Expected state %d after pop", i)
+		}
+	}
+
+	if _, err := b.Pop(); err != ErrEmpty {
+		t.Errorf("Expected ErrEmpty, got %v", err)
+	}
+}
+
+// TestBufferCapacity tests that the oldest states are dropped when full
+func TestBufferCapacity(t *testing.T) {
+	b := NewBuffer(2)
+	for i := 0; i < 5; i++ {
+		b.Push([]byte{byte(i), 0, 0, 0})
+	}
+
+	if b.Len() != 2 {
+		t.Errorf("Expected 2 earlier states, got %d", b.Len())
+	}
+
+	b.Pop()
+	got, _ := b.Pop()
+	if got[0] != 2 {
+		t.Errorf("Expected oldest reachable state 2, got %d", got[0])
+	}
+}
+
+// TestBufferDeltaSize tests that small changes produce small deltas
+func TestBufferDeltaSize(t *testing.T) {
+	b := NewBuffer(10)
+	state := make([]byte, 64*1024)
+	for i := range state {
+		state[i] = byte(i * 7)
+	}
+	b.Push(state)
+
+	for i := 0; i < 10; i++ {
+		state[i*1000] ^= 0x55
+		b.Push(state)
+	}
+
+	// 10 deltas of a few changed bytes each on top of one full state
+	if b.Size() > len(state)+10*64 {
+		t.Errorf("Expected compact deltas, history uses %d bytes", b.Size())
+	}
+}
+
+// TestBufferSizeChange tests states of different lengths
+func TestBufferSizeChange(t *testing.T) {
+	b := NewBuffer(4)
+	short := []byte{1, 2, 3}
+	long := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	b.Push(short)
+	b.Push(long)
+
+	got, err := b.Pop()
+	if err != nil {
+		t.Fatalf("Pop failed: %v", err)
+	}
+	if !bytes.Equal(got, short) {
+		t.Errorf("Expected %v, got %v", short, got)
+	}
+}
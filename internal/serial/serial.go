@@ -0,0 +1,258 @@
+package serial
+
+import "github.com/briancain/gameboy-go/internal/snapshot"
+
+// Serial handles the Game Boy's link port
+// According to Pan Docs:
+//   - SB (FF01) holds the byte being shifted out; received bits shift in from
+//     the right as the outgoing bits shift out from the left
+//   - SC (FF02) bit 7 starts a transfer and stays set until it completes,
+//     bit 0 selects the internal clock (master) or external clock (slave)
+//   - With the internal clock, bits are shifted at 8192Hz
+//   - When all 8 bits have been shifted a serial interrupt is requested
+type Serial struct {
+	// Serial registers
+	sb byte // Serial transfer data (FF01)
+	sc byte // Serial transfer control (FF02)
+
+	// Transfer state for internal clock transfers
+	transferring bool
+	incoming     byte // Byte being received from the peer
+	bitsLeft     int  // Bits still to shift
+	bitCounter   int  // Cycles since the last bit was shifted
+
+	// Set while an AsyncPeer has not answered the transfer yet
+	awaitingReply bool
+
+	// Device attached to the link port, nil when the cable is unplugged
+	peer SerialPeer
+
+	// Reference to MMU for memory access
+	mmu MMU
+}
+
+// SC register bits
+const (
+	SC_TRANSFER_START = 0x80 // Bit 7 - Transfer Start Flag
+	SC_INTERNAL_CLOCK = 0x01 // Bit 0 - Shift Clock (0=External, 1=Internal)
+)
+
+// CPU cycles per transferred bit with the internal clock
+// 4194304Hz / 8192Hz = 512 cycles
+const CYCLES_PER_BIT = 512
+
+// MMU interface for Serial to access memory
+type MMU interface {
+	WriteByte(addr uint16, value byte)
+	ReadByte(addr uint16) byte
+}
+
+// SerialPeer is a device connected to the other end of the link cable
+type SerialPeer interface {
+	// Exchange is called when the Game Boy starts a transfer as the clock
+	// master. It receives the byte being sent and returns the byte the peer
+	// sends back, which is shifted into SB over the following 8 bit periods.
+	Exchange(out byte) byte
+
+	// Poll is called regularly from the emulation thread. Peers that act as
+	// the clock master use it to drive transfers with ExternalTransfer.
+	Poll(s *Serial)
+}
+
+// AsyncPeer is implemented by peers that answer a transfer some time after
+// it starts, such as another emulator over a network. Instead of Exchange,
+// the Game Boy calls StartExchange, which must not block, and the bits only
+// start shifting once the peer passes its byte to Reply from Poll.
+type AsyncPeer interface {
+	StartExchange(out byte)
+}
+
+// Initialize a new Serial controller
+func NewSerial(mmu MMU) *Serial {
+	return &Serial{
+		mmu: mmu,
+	}
+}
+
+// Reset the serial controller to its initial state
+func (s *Serial) Reset() {
+	s.sb = 0
+	s.sc = 0
+	s.transferring = false
+	s.incoming = 0
+	s.bitsLeft = 0
+	s.bitCounter = 0
+	s.awaitingReply = false
+}
+
+// SetPeer connects a device to the link port. A nil peer unplugs the cable.
+func (s *Serial) SetPeer(peer SerialPeer) {
+	s.peer = peer
+}
+
+// Peer returns the device connected to the link port
+func (s *Serial) Peer() SerialPeer {
+	return s.peer
+}
+
+// Step advances the serial controller by the specified number of cycles
+func (s *Serial) Step(cycles int) {
+	if s.peer != nil {
+		s.peer.Poll(s)
+	}
+
+	if !s.transferring || s.awaitingReply {
+		return
+	}
+
+	s.bitCounter += cycles
+	for s.transferring && s.bitCounter >= CYCLES_PER_BIT {
+		s.bitCounter -= CYCLES_PER_BIT
+		s.shiftBit()
+	}
+}
+
+// shiftBit shifts one bit out of SB and the next incoming bit in
+func (s *Serial) shiftBit() {
+	bit := (s.incoming >> 7) & 0x01
+	s.incoming <<= 1
+	s.sb = (s.sb << 1) | bit
+
+	s.bitsLeft--
+	if s.bitsLeft == 0 {
+		s.completeTransfer()
+	}
+}
+
+// completeTransfer ends the current transfer and requests a serial interrupt
+func (s *Serial) completeTransfer() {
+	s.transferring = false
+	s.sc &^= SC_TRANSFER_START
+	s.requestInterrupt()
+}
+
+// startTransfer begins an internal clock transfer
+func (s *Serial) startTransfer() {
+	// With nothing connected the data line floats high
+	s.incoming = 0xFF
+	if async, ok := s.peer.(AsyncPeer); ok {
+		async.StartExchange(s.sb)
+		s.awaitingReply = true
+	} else if s.peer != nil {
+		s.incoming = s.peer.Exchange(s.sb)
+	}
+
+	s.transferring = true
+	s.bitsLeft = 8
+	s.bitCounter = 0
+}
+
+// Reply passes an AsyncPeer's byte for the transfer it was started with,
+// which then shifts in over the following 8 bit periods. It is ignored if
+// the transfer was aborted.
+func (s *Serial) Reply(in byte) {
+	if !s.awaitingReply {
+		return
+	}
+
+	s.awaitingReply = false
+	s.incoming = in
+	s.bitCounter = 0
+}
+
+// AwaitingReply reports whether an internal clock transfer is waiting for
+// an AsyncPeer to answer
+func (s *Serial) AwaitingReply() bool {
+	return s.awaitingReply
+}
+
+// ExternalTransfer is called by a peer acting as the clock master to shift
+// a whole byte. If the Game Boy is waiting for an external clock transfer,
+// SB is swapped with in, the transfer completes and the outgoing byte is
+// returned with ok set. Otherwise nothing changes and ok is false.
+func (s *Serial) ExternalTransfer(in byte) (out byte, ok bool) {
+	if s.sc&SC_TRANSFER_START == 0 || s.sc&SC_INTERNAL_CLOCK != 0 {
+		return 0, false
+	}
+
+	out = s.sb
+	s.sb = in
+	s.completeTransfer()
+	return out, true
+}
+
+// WaitingForExternalClock reports whether a slave transfer is pending
+func (s *Serial) WaitingForExternalClock() bool {
+	return s.sc&SC_TRANSFER_START != 0 && s.sc&SC_INTERNAL_CLOCK == 0
+}
+
+// Request a serial interrupt
+func (s *Serial) requestInterrupt() {
+	// Set bit 3 of the IF register (0xFF0F)
+	interruptFlag := s.mmu.ReadByte(0xFF0F)
+	interruptFlag |= 0x08 // Serial interrupt (bit 3)
+	s.mmu.WriteByte(0xFF0F, interruptFlag)
+}
+
+// Read a serial register
+func (s *Serial) ReadRegister(addr uint16) byte {
+	switch addr {
+	case 0xFF01:
+		return s.sb
+	case 0xFF02:
+		// Bits 1-6 are unused and always return 1
+		return s.sc | 0x7E
+	default:
+		return 0xFF
+	}
+}
+
+// Write a serial register
+func (s *Serial) WriteRegister(addr uint16, value byte) {
+	switch addr {
+	case 0xFF01:
+		s.sb = value
+	case 0xFF02:
+		s.sc = value & (SC_TRANSFER_START | SC_INTERNAL_CLOCK)
+
+		switch {
+		case s.sc&SC_TRANSFER_START == 0:
+			// Clearing the start flag aborts a transfer in progress
+			s.transferring = false
+			s.awaitingReply = false
+		case s.sc&SC_INTERNAL_CLOCK != 0:
+			if !s.transferring {
+				s.startTransfer()
+			}
+		default:
+			// External clock: wait for the peer to drive the transfer
+			s.transferring = false
+			s.awaitingReply = false
+		}
+	}
+}
+
+// SaveState writes the serial registers and transfer state
+func (s *Serial) SaveState(w *snapshot.StateWriter) {
+	w.Section("SIO ")
+	w.Uint8(s.sb)
+	w.Uint8(s.sc)
+	w.Bool(s.transferring)
+	w.Uint8(s.incoming)
+	w.Int(s.bitsLeft)
+	w.Int(s.bitCounter)
+}
+
+// LoadState restores the serial state written by SaveState. A transfer that
+// was waiting for an AsyncPeer carries on receiving 0xFF, as the peer has
+// no record of it.
+func (s *Serial) LoadState(r *snapshot.StateReader) {
+	r.Section("SIO ")
+	s.sb = r.Uint8()
+	s.sc = r.Uint8()
+	s.transferring = r.Bool()
+	s.incoming = r.Uint8()
+	s.bitsLeft = r.Int()
+	s.bitCounter = r.Int()
+	s.awaitingReply = false
+}
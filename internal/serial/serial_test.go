@@ -0,0 +1,204 @@
+package serial
+
+import (
+	"testing"
+)
+
+// TestSerialInitialization verifies that a new Serial controller can be created
+func TestSerialInitialization(t *testing.T) {
+	serial := NewSerial(&MockMMU{})
+
+	if serial == nil {
+		t.Fatal("Expected Serial to be initialized, got nil")
+	}
+
+	// Unused SC bits read as 1
+	if value := serial.ReadRegister(0xFF02); value != 0x7E {
+		t.Errorf("Expected SC to be 0x7E, got %02X", value)
+	}
+}
+
+// TestInternalClockTransfer tests a transfer with no peer connected
+func TestInternalClockTransfer(t *testing.T) {
+	mmu := &MockMMU{}
+	serial := NewSerial(mmu)
+
+	serial.WriteRegister(0xFF01, 0x42)
+	serial.WriteRegister(0xFF02, 0x81)
+
+	// Not finished one cycle before the 8th bit
+	serial.Step(8*CYCLES_PER_BIT - 1)
+	if mmu.interruptFlag&0x08 != 0 {
+		t.Error("Expected no serial interrupt before the transfer completes")
+	}
+	if serial.ReadRegister(0xFF02)&0x80 == 0 {
+		t.Error("Expected SC bit 7 to stay set during the transfer")
+	}
+
+	serial.Step(1)
+	if mmu.interruptFlag&0x08 == 0 {
+		t.Error("Expected serial interrupt when the transfer completes")
+	}
+	if value := serial.ReadRegister(0xFF02); value != 0x7F {
+		t.Errorf("Expected SC to be 0x7F after the transfer, got %02X", value)
+	}
+
+	// A disconnected cable reads all ones
+	if value := serial.ReadRegister(0xFF01); value != 0xFF {
+		t.Errorf("Expected SB to be 0xFF, got %02X", value)
+	}
+}
+
+// TestTransferShiftsBits tests that SB shifts one bit per bit period
+func TestTransferShiftsBits(t *testing.T) {
+	serial := NewSerial(&MockMMU{})
+	peer := &MockPeer{reply: 0x00}
+	serial.SetPeer(peer)
+
+	serial.WriteRegister(0xFF01, 0xFF)
+	serial.WriteRegister(0xFF02, 0x81)
+	serial.Step(4 * CYCLES_PER_BIT)
+
+	if value := serial.ReadRegister(0xFF01); value != 0xF0 {
+		t.Errorf("Expected SB to be 0xF0 halfway through, got %02X", value)
+	}
+}
+
+// TestPeerExchange tests that the peer receives SB and its reply is shifted in
+func TestPeerExchange(t *testing.T) {
+	serial := NewSerial(&MockMMU{})
+	peer := &MockPeer{reply: 0xA5}
+	serial.SetPeer(peer)
+
+	serial.WriteRegister(0xFF01, 0x3C)
+	serial.WriteRegister(0xFF02, 0x81)
+	serial.Step(8 * CYCLES_PER_BIT)
+
+	if len(peer.received) != 1 || peer.received[0] != 0x3C {
+		t.Errorf("Expected peer to receive [3C], got %X", peer.received)
+	}
+	if value := serial.ReadRegister(0xFF01); value != 0xA5 {
+		t.Errorf("Expected SB to be 0xA5, got %02X", value)
+	}
+}
+
+// TestExternalClockTransfer tests slave mode driven by the peer
+func TestExternalClockTransfer(t *testing.T) {
+	mmu := &MockMMU{}
+	serial := NewSerial(mmu)
+
+	// Not waiting yet
+	if _, ok := serial.ExternalTransfer(0x11); ok {
+		t.Error("Expected external transfer to be rejected before SC is set")
+	}
+
+	serial.WriteRegister(0xFF01, 0x22)
+	serial.WriteRegister(0xFF02, 0x80)
+
+	// The internal clock does not advance an external transfer
+	serial.Step(16 * CYCLES_PER_BIT)
+	if !serial.WaitingForExternalClock() {
+		t.Fatal("Expected serial to wait for the external clock")
+	}
+
+	out, ok := serial.ExternalTransfer(0x11)
+	if !ok || out != 0x22 {
+		t.Errorf("Expected external transfer to return 22, got %02X (%v)", out, ok)
+	}
+	if value := serial.ReadRegister(0xFF01); value != 0x11 {
+		t.Errorf("Expected SB to be 0x11, got %02X", value)
+	}
+	if mmu.interruptFlag&0x08 == 0 {
+		t.Error("Expected serial interrupt after external transfer")
+	}
+}
+
+// TestAsyncPeerExchange tests that a transfer to an AsyncPeer waits for the
+// reply before shifting
+func TestAsyncPeerExchange(t *testing.T) {
+	mmu := &MockMMU{}
+	serial := NewSerial(mmu)
+	peer := &MockAsyncPeer{}
+	serial.SetPeer(peer)
+
+	serial.WriteRegister(0xFF01, 0x3C)
+	serial.WriteRegister(0xFF02, 0x81)
+	if len(peer.received) != 1 || peer.received[0] != 0x3C {
+		t.Errorf("Expected peer to receive [3C], got %X", peer.received)
+	}
+
+	// No bits shift until the peer replies
+	serial.Step(16 * CYCLES_PER_BIT)
+	if !serial.AwaitingReply() || serial.ReadRegister(0xFF01) != 0x3C {
+		t.Fatalf("Expected the transfer to wait for the reply, SB is %02X", serial.ReadRegister(0xFF01))
+	}
+
+	serial.Reply(0xA5)
+	serial.Step(8*CYCLES_PER_BIT - 1)
+	if mmu.interruptFlag&0x08 != 0 {
+		t.Error("Expected no serial interrupt before the transfer completes")
+	}
+	serial.Step(1)
+	if mmu.interruptFlag&0x08 == 0 {
+		t.Error("Expected serial interrupt when the transfer completes")
+	}
+	if value := serial.ReadRegister(0xFF01); value != 0xA5 {
+		t.Errorf("Expected SB to be 0xA5, got %02X", value)
+	}
+
+	// A reply to an aborted transfer is ignored
+	serial.WriteRegister(0xFF02, 0x81)
+	serial.WriteRegister(0xFF02, 0x00)
+	serial.Reply(0x00)
+	serial.Step(8 * CYCLES_PER_BIT)
+	if value := serial.ReadRegister(0xFF01); value != 0xA5 {
+		t.Errorf("Expected SB to stay 0xA5 after an aborted transfer, got %02X", value)
+	}
+}
+
+// MockMMU is a mock implementation of the MMU interface for testing
+type MockMMU struct {
+	interruptFlag byte
+}
+
+func (m *MockMMU) WriteByte(addr uint16, value byte) {
+	if addr == 0xFF0F {
+		m.interruptFlag = value
+	}
+}
+
+func (m *MockMMU) ReadByte(addr uint16) byte {
+	if addr == 0xFF0F {
+		return m.interruptFlag
+	}
+	return 0
+}
+
+// MockPeer is a mock implementation of the SerialPeer interface for testing
+type MockPeer struct {
+	reply    byte
+	received []byte
+}
+
+func (m *MockPeer) Exchange(out byte) byte {
+	m.received = append(m.received, out)
+	return m.reply
+}
+
+func (m *MockPeer) Poll(s *Serial) {}
+
+// MockAsyncPeer is a mock implementation of the AsyncPeer interface for
+// testing, it never replies by itself
+type MockAsyncPeer struct {
+	received []byte
+}
+
+func (m *MockAsyncPeer) Exchange(out byte) byte {
+	panic("Exchange called on an AsyncPeer")
+}
+
+func (m *MockAsyncPeer) StartExchange(out byte) {
+	m.received = append(m.received, out)
+}
+
+func (m *MockAsyncPeer) Poll(s *Serial) {}
@@ -0,0 +1,194 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+)
+
+// Emulator is an emulator that can both save and restore its state
+type Emulator interface {
+	Source
+	Target
+}
+
+// Manager records in-memory snapshots as a tree. Each new snapshot becomes a
+// child of the current node, so jumping back to an earlier node and taking
+// more snapshots starts a new branch instead of overwriting history.
+type Manager struct {
+	emulator Emulator
+
+	// All live snapshots by ID
+	nodes map[int]*Snapshot
+
+	// Snapshots without a parent, in creation order
+	roots []*Snapshot
+
+	// The node the emulator state was last taken from or restored to
+	current *Snapshot
+
+	nextID int
+}
+
+// NewManager creates a snapshot manager for the given emulator
+func NewManager(emulator Emulator) *Manager {
+	return &Manager{
+		emulator: emulator,
+		nodes:    make(map[int]*Snapshot),
+		nextID:   1,
+	}
+}
+
+// Take captures the emulator state as a new child of the current node and
+// makes it the current node
+func (m *Manager) Take(label string) (*Snapshot, error) {
+	s, err := TakeSnapshot(m.emulator, m.current)
+	if err != nil {
+		return nil, err
+	}
+
+	s.id = m.nextID
+	s.Label = label
+	m.nextID++
+
+	m.nodes[s.id] = s
+	if s.Parent != nil {
+		s.Parent.Children = append(s.Parent.Children, s)
+	} else {
+		m.roots = append(m.roots, s)
+	}
+	m.current = s
+
+	return s, nil
+}
+
+// Jump restores the snapshot with the given ID and makes it the current node.
+// The next Take will create a new branch from it.
+func (m *Manager) Jump(id int) error {
+	s, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Restore(m.emulator); err != nil {
+		return fmt.Errorf("restoring snapshot %d: %w", id, err)
+	}
+	m.current = s
+
+	return nil
+}
+
+// Branch jumps to the snapshot with the given ID and immediately records a
+// new child of it, returning the first node of the new branch
+func (m *Manager) Branch(id int, label string) (*Snapshot, error) {
+	if err := m.Jump(id); err != nil {
+		return nil, err
+	}
+	return m.Take(label)
+}
+
+// Prune removes the snapshot with the given ID and its whole subtree. If the
+// current node was inside the pruned subtree, its parent becomes current.
+func (m *Manager) Prune(id int) error {
+	s, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	// Detach from the parent (or the root list)
+	if s.Parent != nil {
+		s.Parent.Children = removeSnapshot(s.Parent.Children, s)
+	} else {
+		m.roots = removeSnapshot(m.roots, s)
+	}
+
+	// Move the current node out of the subtree before forgetting it
+	for n := m.current; n != nil; n = n.Parent {
+		if n == s {
+			m.current = s.Parent
+			break
+		}
+	}
+
+	var forget func(n *Snapshot)
+	forget = func(n *Snapshot) {
+		delete(m.nodes, n.id)
+		for _, child := range n.Children {
+			forget(child)
+		}
+	}
+	forget(s)
+
+	return nil
+}
+
+// Get returns the snapshot with the given ID
+func (m *Manager) Get(id int) (*Snapshot, bool) {
+	s, ok := m.nodes[id]
+	return s, ok
+}
+
+// Current returns the current node, or nil if no snapshot has been taken
+func (m *Manager) Current() *Snapshot {
+	return m.current
+}
+
+// Roots returns the snapshots that have no parent
+func (m *Manager) Roots() []*Snapshot {
+	return append([]*Snapshot(nil), m.roots...)
+}
+
+// Children returns the direct children of the snapshot with the given ID
+func (m *Manager) Children(id int) ([]*Snapshot, error) {
+	s, err := m.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*Snapshot(nil), s.Children...), nil
+}
+
+// Len returns the number of snapshots in the tree
+func (m *Manager) Len() int {
+	return len(m.nodes)
+}
+
+// Walk visits every snapshot depth-first in creation order, passing its depth
+// in the tree (0 for roots)
+func (m *Manager) Walk(fn func(s *Snapshot, depth int)) {
+	var walk func(n *Snapshot, depth int)
+	walk = func(n *Snapshot, depth int) {
+		fn(n, depth)
+		for _, child := range n.Children {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range m.roots {
+		walk(root, 0)
+	}
+}
+
+func (m *Manager) lookup(id int) (*Snapshot, error) {
+	s, ok := m.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("no snapshot with id %d", id)
+	}
+	return s, nil
+}
+
+// removeSnapshot returns list without s
+func removeSnapshot(list []*Snapshot, s *Snapshot) []*Snapshot {
+	for i, n := range list {
+		if n == s {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// String returns a one line description of the snapshot
+func (s *Snapshot) String() string {
+	label := s.Label
+	if label == "" {
+		label = "-"
+	}
+	return fmt.Sprintf("#%d %s %s (%d bytes)", s.id, s.Time.Format(time.TimeOnly), label, len(s.Data))
+}
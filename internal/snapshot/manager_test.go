@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"io"
+	"testing"
+)
+
+// counter is a minimal Emulator whose whole state is one value
+type counter struct {
+	value uint32
+}
+
+func (c *counter) SaveState(w io.Writer) error {
+	sw := NewStateWriter(w)
+	sw.Uint32(c.value)
+	return sw.Err()
+}
+
+func (c *counter) LoadState(r io.Reader) error {
+	sr := NewStateReader(r)
+	v := sr.Uint32()
+	if sr.Err() != nil {
+		return sr.Err()
+	}
+	c.value = v
+	return nil
+}
+
+// TestManagerTree tests that snapshots are linked into a tree
+func TestManagerTree(t *testing.T) {
+	emu := &counter{}
+	m := NewManager(emu)
+
+	root, _ := m.Take("start")
+	emu.value = 1
+	a, _ := m.Take("a")
+
+	if a.Parent != root {
+		t.Errorf("Expected snapshot %d to be child of %d", a.ID(), root.ID())
+	}
+	if root.ID() == a.ID() {
+		t.Errorf("Expected unique IDs, got %d twice", a.ID())
+	}
+	if m.Current() != a {
+		t.Errorf("Expected current snapshot %d, got %v", a.ID(), m.Current())
+	}
+	if a.Time.IsZero() {
+		t.Errorf("Expected snapshot timestamp to be set")
+	}
+}
+
+// TestManagerJumpAndBranch tests restoring a node and branching from it
+func TestManagerJumpAndBranch(t *testing.T) {
+	emu := &counter{}
+	m := NewManager(emu)
+
+	root, _ := m.Take("start")
+	emu.value = 1
+	a, _ := m.Take("a")
+	emu.value = 2
+
+	if err := m.Jump(root.ID()); err != nil {
+		t.Fatalf("Jump failed: %v", err)
+	}
+	if emu.value != 0 {
+		t.Errorf("Expected value 0 after jump, got %d", emu.value)
+	}
+
+	emu.value = 5
+	b, _ := m.Take("b")
+
+	children, err := m.Children(root.ID())
+	if err != nil {
+		t.Fatalf("Children failed: %v", err)
+	}
+	if len(children) != 2 || children[0] != a || children[1] != b {
+		t.Errorf("Expected children [%d %d], got %v", a.ID(), b.ID(), children)
+	}
+
+	c, err := m.Branch(a.ID(), "c")
+	if err != nil {
+		t.Fatalf("Branch failed: %v", err)
+	}
+	if c.Parent != a {
+		t.Errorf("Expected branch to start from %d, got %v", a.ID(), c.Parent)
+	}
+	if emu.value != 1 {
+		t.Errorf("Expected value 1 after branch, got %d", emu.value)
+	}
+
+	if err := m.Jump(99); err == nil {
+		t.Errorf("Expected error jumping to unknown snapshot")
+	}
+}
+
+// TestManagerPrune tests removing a subtree
+func TestManagerPrune(t *testing.T) {
+	emu := &counter{}
+	m := NewManager(emu)
+
+	root, _ := m.Take("start")
+	a, _ := m.Take("a")
+	a1, _ := m.Take("a1")
+	m.Jump(root.ID())
+	b, _ := m.Take("b")
+	m.Jump(a1.ID())
+
+	if err := m.Prune(a.ID()); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 snapshots after prune, got %d", m.Len())
+	}
+	if _, ok := m.Get(a1.ID()); ok {
+		t.Errorf("Expected descendant %d to be pruned", a1.ID())
+	}
+	if m.Current() != root {
+		t.Errorf("Expected current to move to %d, got %v", root.ID(), m.Current())
+	}
+	children, _ := m.Children(root.ID())
+	if len(children) != 1 || children[0] != b {
+		t.Errorf("Expected only child %d, got %v", b.ID(), children)
+	}
+
+	if err := m.Prune(root.ID()); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if m.Len() != 0 || len(m.Roots()) != 0 || m.Current() != nil {
+		t.Errorf("Expected empty tree, got %d snapshots", m.Len())
+	}
+}
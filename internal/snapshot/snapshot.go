@@ -1,15 +1,58 @@
 package snapshot
 
-import "time"
+import (
+	"bytes"
+	"io"
+	"time"
+)
 
+// Source is an emulator whose full state can be serialized
+type Source interface {
+	SaveState(w io.Writer) error
+}
+
+// Target is an emulator whose full state can be restored
+type Target interface {
+	LoadState(r io.Reader) error
+}
+
+// Snapshot is a point-in-time copy of the emulator state. Snapshots recorded
+// by a Manager form a tree through Parent and Children.
 type Snapshot struct {
 	id int
 
 	Time time.Time
 
-	Parent *Snapshot
+	// Optional user supplied description
+	Label string
+
+	Parent   *Snapshot
+	Children []*Snapshot
+
+	// Serialized emulator state in the binary state format
+	Data []byte
+}
+
+// TakeSnapshot captures the current state of src as a child of parent
+func TakeSnapshot(src Source, parent *Snapshot) (*Snapshot, error) {
+	var buf bytes.Buffer
+	if err := src.SaveState(&buf); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Time:   time.Now(),
+		Parent: parent,
+		Data:   buf.Bytes(),
+	}, nil
+}
+
+// ID returns the snapshot's identifier within its Manager (0 if unmanaged)
+func (s *Snapshot) ID() int {
+	return s.id
 }
 
-func (s *Snapshot) TakeSnapshot(parent *Snapshot) (*Snapshot, error) {
-	return nil, nil
+// Restore loads the state held by the snapshot into dst
+func (s *Snapshot) Restore(dst Target) error {
+	return dst.LoadState(bytes.NewReader(s.Data))
 }
@@ -0,0 +1,273 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// State file layout
+//
+// Every save state starts with a fixed header followed by one section per
+// emulator component, written in the order the core serializes them:
+//
+//	magic    [4]byte "GBGS"
+//	version  uint16  (StateVersion)
+//	sections ...
+//
+// All multi-byte values are little endian. Byte slices are prefixed with
+// their length as a uint32 so that mismatched regions (e.g. a state taken
+// from a cartridge with a different RAM size) are detected on load.
+const (
+	StateMagic   = "GBGS"
+	StateVersion = 5
+)
+
+// StateWriter serializes emulator component state into the binary state format
+type StateWriter struct {
+	w   io.Writer
+	err error
+	buf [8]byte
+}
+
+// NewStateWriter creates a new StateWriter writing to w
+func NewStateWriter(w io.Writer) *StateWriter {
+	return &StateWriter{w: w}
+}
+
+// WriteHeader writes the state magic and format version
+func (s *StateWriter) WriteHeader() {
+	s.write([]byte(StateMagic))
+	s.Uint16(StateVersion)
+}
+
+// Section writes a four character section tag
+func (s *StateWriter) Section(tag string) {
+	if len(tag) != 4 {
+		s.setErr(fmt.Errorf("invalid section tag %q", tag))
+		return
+	}
+	s.write([]byte(tag))
+}
+
+// Uint8 writes a single byte
+func (s *StateWriter) Uint8(v byte) {
+	s.buf[0] = v
+	s.write(s.buf[:1])
+}
+
+// Uint16 writes a 16-bit value
+func (s *StateWriter) Uint16(v uint16) {
+	binary.LittleEndian.PutUint16(s.buf[:2], v)
+	s.write(s.buf[:2])
+}
+
+// Uint32 writes a 32-bit value
+func (s *StateWriter) Uint32(v uint32) {
+	binary.LittleEndian.PutUint32(s.buf[:4], v)
+	s.write(s.buf[:4])
+}
+
+// Uint64 writes a 64-bit value
+func (s *StateWriter) Uint64(v uint64) {
+	binary.LittleEndian.PutUint64(s.buf[:8], v)
+	s.write(s.buf[:8])
+}
+
+// Int64 writes a signed 64-bit value
+func (s *StateWriter) Int64(v int64) {
+	s.Uint64(uint64(v))
+}
+
+// Int writes an int as a signed 64-bit value
+func (s *StateWriter) Int(v int) {
+	s.Int64(int64(v))
+}
+
+// Bool writes a boolean as a single byte
+func (s *StateWriter) Bool(v bool) {
+	if v {
+		s.Uint8(1)
+	} else {
+		s.Uint8(0)
+	}
+}
+
+// Bytes writes a length-prefixed byte slice
+func (s *StateWriter) Bytes(b []byte) {
+	s.Uint32(uint32(len(b)))
+	s.write(b)
+}
+
+// String writes a length-prefixed string
+func (s *StateWriter) String(v string) {
+	s.Bytes([]byte(v))
+}
+
+// Err returns the first error encountered while writing
+func (s *StateWriter) Err() error {
+	return s.err
+}
+
+func (s *StateWriter) write(b []byte) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.Write(b)
+}
+
+func (s *StateWriter) setErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// StateReader deserializes emulator component state written by a StateWriter.
+// Errors are sticky: once a read fails, all further reads return zero values
+// and Err reports the first failure.
+type StateReader struct {
+	r   io.Reader
+	err error
+	buf [8]byte
+}
+
+// NewStateReader creates a new StateReader reading from r
+func NewStateReader(r io.Reader) *StateReader {
+	return &StateReader{r: r}
+}
+
+// ReadHeader reads and validates the state magic and format version
+func (s *StateReader) ReadHeader() {
+	magic := make([]byte, len(StateMagic))
+	s.read(magic)
+	if s.err != nil {
+		return
+	}
+	if string(magic) != StateMagic {
+		s.Fail(fmt.Errorf("not a save state (bad magic %q)", magic))
+		return
+	}
+
+	version := s.Uint16()
+	if s.err == nil && version != StateVersion {
+		s.Fail(fmt.Errorf("unsupported save state version %d (expected %d)", version, StateVersion))
+	}
+}
+
+// Section reads a four character section tag and checks it matches tag
+func (s *StateReader) Section(tag string) {
+	got := make([]byte, 4)
+	s.read(got)
+	if s.err == nil && string(got) != tag {
+		s.Fail(fmt.Errorf("expected state section %q, found %q", tag, got))
+	}
+}
+
+// Uint8 reads a single byte
+func (s *StateReader) Uint8() byte {
+	s.read(s.buf[:1])
+	if s.err != nil {
+		return 0
+	}
+	return s.buf[0]
+}
+
+// Uint16 reads a 16-bit value
+func (s *StateReader) Uint16() uint16 {
+	s.read(s.buf[:2])
+	if s.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(s.buf[:2])
+}
+
+// Uint32 reads a 32-bit value
+func (s *StateReader) Uint32() uint32 {
+	s.read(s.buf[:4])
+	if s.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(s.buf[:4])
+}
+
+// Uint64 reads a 64-bit value
+func (s *StateReader) Uint64() uint64 {
+	s.read(s.buf[:8])
+	if s.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(s.buf[:8])
+}
+
+// Int64 reads a signed 64-bit value
+func (s *StateReader) Int64() int64 {
+	return int64(s.Uint64())
+}
+
+// Int reads an int stored as a signed 64-bit value
+func (s *StateReader) Int() int {
+	return int(s.Int64())
+}
+
+// Bool reads a boolean stored as a single byte
+func (s *StateReader) Bool() bool {
+	return s.Uint8() != 0
+}
+
+// BytesInto reads a length-prefixed byte slice into dst. The stored length
+// must match len(dst) exactly.
+func (s *StateReader) BytesInto(dst []byte) {
+	n := s.Uint32()
+	if s.err != nil {
+		return
+	}
+	if int(n) != len(dst) {
+		s.Fail(fmt.Errorf("state region size mismatch: stored %d bytes, expected %d", n, len(dst)))
+		return
+	}
+	s.read(dst)
+}
+
+// String reads a length-prefixed string
+func (s *StateReader) String() string {
+	n := s.Uint32()
+	if s.err != nil {
+		return ""
+	}
+	// Strings in the state are short identifiers (e.g. cartridge titles)
+	if n > 1024 {
+		s.Fail(fmt.Errorf("state string too long: %d bytes", n))
+		return ""
+	}
+	b := make([]byte, n)
+	s.read(b)
+	if s.err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Err returns the first error encountered while reading
+func (s *StateReader) Err() error {
+	return s.err
+}
+
+func (s *StateReader) read(b []byte) {
+	if s.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(s.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		s.err = fmt.Errorf("reading save state: %w", err)
+	}
+}
+
+// Fail records err as the reader error unless an earlier error is already set.
+// Components use it to reject states that don't match the loaded hardware.
+func (s *StateReader) Fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
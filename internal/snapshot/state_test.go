@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestStateRoundTrip verifies that values written by StateWriter are read back unchanged
+func TestStateRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewStateWriter(&buf)
+	w.WriteHeader()
+	w.Section("TEST")
+	w.Uint8(0x12)
+	w.Uint16(0x3456)
+	w.Uint32(0x789ABCDE)
+	w.Int64(-42)
+	w.Int(1234567)
+	w.Bool(true)
+	w.Bytes([]byte{1, 2, 3})
+	w.String("TETRIS")
+	if err := w.Err(); err != nil {
+		t.Fatalf("Expected no write error, got %v", err)
+	}
+
+	r := NewStateReader(&buf)
+	r.ReadHeader()
+	r.Section("TEST")
+	if v := r.Uint8(); v != 0x12 {
+		t.Errorf("Expected Uint8 0x12, got %02X", v)
+	}
+	if v := r.Uint16(); v != 0x3456 {
+		t.Errorf("Expected Uint16 0x3456, got %04X", v)
+	}
+	if v := r.Uint32(); v != 0x789ABCDE {
+		t.Errorf("Expected Uint32 0x789ABCDE, got %08X", v)
+	}
+	if v := r.Int64(); v != -42 {
+		t.Errorf("Expected Int64 -42, got %d", v)
+	}
+	if v := r.Int(); v != 1234567 {
+		t.Errorf("Expected Int 1234567, got %d", v)
+	}
+	if !r.Bool() {
+		t.Error("Expected Bool true")
+	}
+	data := make([]byte, 3)
+	r.BytesInto(data)
+	if !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("Expected bytes [1 2 3], got %v", data)
+	}
+	if v := r.String(); v != "TETRIS" {
+		t.Errorf("Expected string TETRIS, got %q", v)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Expected no read error, got %v", err)
+	}
+}
+
+// TestStateReaderErrors verifies that malformed states are rejected
+func TestStateReaderErrors(t *testing.T) {
+	// Bad magic
+	r := NewStateReader(strings.NewReader("NOPE\x01\x00"))
+	r.ReadHeader()
+	if r.Err() == nil {
+		t.Error("Expected error for bad magic")
+	}
+
+	// Unsupported version
+	r = NewStateReader(strings.NewReader(StateMagic + "\xFF\xFF"))
+	r.ReadHeader()
+	if r.Err() == nil {
+		t.Error("Expected error for unsupported version")
+	}
+
+	// Wrong section tag
+	var buf bytes.Buffer
+	w := NewStateWriter(&buf)
+	w.Section("AAAA")
+	r = NewStateReader(&buf)
+	r.Section("BBBB")
+	if r.Err() == nil {
+		t.Error("Expected error for mismatched section tag")
+	}
+
+	// Region size mismatch
+	buf.Reset()
+	w = NewStateWriter(&buf)
+	w.Bytes(make([]byte, 4))
+	r = NewStateReader(&buf)
+	r.BytesInto(make([]byte, 8))
+	if r.Err() == nil {
+		t.Error("Expected error for region size mismatch")
+	}
+
+	// Truncated data, errors are sticky
+	r = NewStateReader(bytes.NewReader([]byte{0x01}))
+	r.Uint16()
+	if r.Err() == nil {
+		t.Error("Expected error for truncated data")
+	}
+	if v := r.Uint8(); v != 0 {
+		t.Errorf("Expected reads after an error to return 0, got %02X", v)
+	}
+}
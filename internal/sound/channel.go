@@ -0,0 +1,261 @@
+package sound
+
+import (
+	"github.com/briancain/gameboy-go/internal/snapshot"
+)
+
+// Square wave duty patterns (12.5%, 25%, 50%, 75%), one bit per duty step
+var dutyPatterns = [4][8]byte{
+	{0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 0},
+}
+
+// Noise channel clock divisors in T-cycles, indexed by NR43 bits 0-2
+var noiseDivisors = [8]int{8, 16, 32, 48, 64, 80, 96, 112}
+
+// Channel represents a sound channel
+type Channel struct {
+	enabled bool
+
+	// Channel-specific registers (NRx0-NRx4) as last written
+	registers [5]byte
+
+	// Whether the channel DAC is powered (NRx2 bits 3-7, or NR30 bit 7)
+	dacEnabled bool
+
+	// Frequency timer
+	frequency uint16
+	timer     int
+
+	// Duty step (0-7) for square channels, sample index (0-31) for wave
+	position byte
+
+	// Length counter
+	lengthCounter int
+	lengthEnabled bool
+
+	// Volume envelope
+	volume        byte
+	envelopeTimer byte
+
+	// Frequency sweep (channel 1 only)
+	sweepEnabled    bool
+	sweepTimer      byte
+	shadowFrequency uint16
+	sweepNegated    bool
+
+	// Linear feedback shift register (channel 4 only)
+	lfsr uint16
+}
+
+// clockLength decrements the length counter, disabling the channel when it
+// expires
+func (c *Channel) clockLength() {
+	if c.lengthEnabled && c.lengthCounter > 0 {
+		c.lengthCounter--
+		if c.lengthCounter == 0 {
+			c.enabled = false
+		}
+	}
+}
+
+// clockEnvelope steps the volume envelope configured in NRx2
+func (c *Channel) clockEnvelope() {
+	period := c.registers[2] & 0x07
+	if period == 0 {
+		return
+	}
+
+	if c.envelopeTimer > 0 {
+		c.envelopeTimer--
+	}
+	if c.envelopeTimer != 0 {
+		return
+	}
+	c.envelopeTimer = period
+
+	if c.registers[2]&0x08 != 0 {
+		if c.volume < 15 {
+			c.volume++
+		}
+	} else if c.volume > 0 {
+		c.volume--
+	}
+}
+
+// resetEnvelope reloads the envelope from NRx2 on trigger
+func (c *Channel) resetEnvelope() {
+	c.volume = c.registers[2] >> 4
+	c.envelopeTimer = c.registers[2] & 0x07
+}
+
+// sweepCalculate computes the next sweep frequency and disables the channel
+// if it overflows
+func (c *Channel) sweepCalculate() uint16 {
+	shift := c.registers[0] & 0x07
+	delta := c.shadowFrequency >> shift
+
+	var freq uint16
+	if c.registers[0]&0x08 != 0 {
+		freq = c.shadowFrequency - delta
+		c.sweepNegated = true
+	} else {
+		freq = c.shadowFrequency + delta
+	}
+
+	if freq > 2047 {
+		c.enabled = false
+	}
+	return freq
+}
+
+// clockSweep steps the channel 1 frequency sweep configured in NR10
+func (c *Channel) clockSweep() {
+	if c.sweepTimer > 0 {
+		c.sweepTimer--
+	}
+	if c.sweepTimer != 0 {
+		return
+	}
+
+	period := (c.registers[0] >> 4) & 0x07
+	c.sweepTimer = period
+	if period == 0 {
+		c.sweepTimer = 8
+	}
+
+	if !c.sweepEnabled || period == 0 {
+		return
+	}
+
+	freq := c.sweepCalculate()
+	if freq <= 2047 && c.registers[0]&0x07 != 0 {
+		c.frequency = freq
+		c.shadowFrequency = freq
+		c.registers[3] = byte(freq)
+		c.registers[4] = c.registers[4]&0xF8 | byte(freq>>8)&0x07
+
+		// The new frequency is checked again but not applied
+		c.sweepCalculate()
+	}
+}
+
+// resetSweep reloads the sweep unit on trigger
+func (c *Channel) resetSweep() {
+	period := (c.registers[0] >> 4) & 0x07
+	shift := c.registers[0] & 0x07
+
+	c.shadowFrequency = c.frequency
+	c.sweepTimer = period
+	if period == 0 {
+		c.sweepTimer = 8
+	}
+	c.sweepEnabled = period != 0 || shift != 0
+	c.sweepNegated = false
+
+	if shift != 0 {
+		c.sweepCalculate()
+	}
+}
+
+// squarePeriod returns the number of cycles per duty step
+func (c *Channel) squarePeriod() int {
+	return (2048 - int(c.frequency)) * 4
+}
+
+// wavePeriod returns the number of cycles per wave sample
+func (c *Channel) wavePeriod() int {
+	return (2048 - int(c.frequency)) * 2
+}
+
+// noisePeriod returns the number of cycles per LFSR shift
+func (c *Channel) noisePeriod() int {
+	return noiseDivisors[c.registers[3]&0x07] << (c.registers[3] >> 4)
+}
+
+// stepSquare advances the duty generator by the given number of cycles
+func (c *Channel) stepSquare(cycles int) {
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.squarePeriod()
+		c.position = (c.position + 1) & 0x07
+	}
+}
+
+// stepWave advances the wave sample position by the given number of cycles
+func (c *Channel) stepWave(cycles int) {
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.wavePeriod()
+		c.position = (c.position + 1) & 0x1F
+	}
+}
+
+// stepNoise advances the LFSR by the given number of cycles
+func (c *Channel) stepNoise(cycles int) {
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.noisePeriod()
+
+		bit := (c.lfsr ^ (c.lfsr >> 1)) & 0x01
+		c.lfsr = (c.lfsr >> 1) | (bit << 14)
+		if c.registers[3]&0x08 != 0 {
+			// 7-bit mode also feeds the result into bit 6
+			c.lfsr = c.lfsr&^0x40 | bit<<6
+		}
+	}
+}
+
+// squareOutput returns the current digital output (0-15) of a square channel
+func (c *Channel) squareOutput() byte {
+	if !c.enabled {
+		return 0
+	}
+	return dutyPatterns[c.registers[1]>>6][c.position] * c.volume
+}
+
+// noiseOutput returns the current digital output (0-15) of the noise channel
+func (c *Channel) noiseOutput() byte {
+	if !c.enabled || c.lfsr&0x01 != 0 {
+		return 0
+	}
+	return c.volume
+}
+
+func (c *Channel) saveState(w *snapshot.StateWriter) {
+	w.Bool(c.enabled)
+	w.Bytes(c.registers[:])
+	w.Bool(c.dacEnabled)
+	w.Uint16(c.frequency)
+	w.Int(c.timer)
+	w.Uint8(c.position)
+	w.Int(c.lengthCounter)
+	w.Bool(c.lengthEnabled)
+	w.Uint8(c.volume)
+	w.Uint8(c.envelopeTimer)
+	w.Bool(c.sweepEnabled)
+	w.Uint8(c.sweepTimer)
+	w.Uint16(c.shadowFrequency)
+	w.Bool(c.sweepNegated)
+	w.Uint16(c.lfsr)
+}
+
+func (c *Channel) loadState(r *snapshot.StateReader) {
+	c.enabled = r.Bool()
+	r.BytesInto(c.registers[:])
+	c.dacEnabled = r.Bool()
+	c.frequency = r.Uint16()
+	c.timer = r.Int()
+	c.position = r.Uint8()
+	c.lengthCounter = r.Int()
+	c.lengthEnabled = r.Bool()
+	c.volume = r.Uint8()
+	c.envelopeTimer = r.Uint8()
+	c.sweepEnabled = r.Bool()
+	c.sweepTimer = r.Uint8()
+	c.shadowFrequency = r.Uint16()
+	c.sweepNegated = r.Bool()
+	c.lfsr = r.Uint16()
+}
@@ -0,0 +1,111 @@
+package sound
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+)
+
+// SampleRing is a lock-free single-producer, single-consumer ring buffer of
+// stereo samples. The emulator pushes samples through the SampleSink
+// interface and the audio player drains them through io.Reader as
+// interleaved 32-bit float little endian samples (left, right).
+//
+// If the producer gets ahead the newest samples are dropped, and if the
+// consumer runs dry the missing samples are filled with silence, so neither
+// side ever blocks the other.
+type SampleRing struct {
+	// Each slot holds the left sample bits in the low 32 bits and the right
+	// sample bits in the high 32 bits
+	slots []uint64
+	mask  uint64
+
+	// Total samples written and read. Only the producer stores head and only
+	// the consumer stores tail.
+	head atomic.Uint64
+	tail atomic.Uint64
+
+	// Counters for diagnosing audio glitches
+	dropped   atomic.Uint64
+	underruns atomic.Uint64
+}
+
+// Bytes per stereo frame in the Read output
+const sampleFrameSize = 8
+
+// NewSampleRing creates a ring that holds at least size stereo samples
+func NewSampleRing(size int) *SampleRing {
+	capacity := 1
+	for capacity < size {
+		capacity <<= 1
+	}
+
+	return &SampleRing{
+		slots: make([]uint64, capacity),
+		mask:  uint64(capacity - 1),
+	}
+}
+
+// PushSample adds a stereo sample, dropping it if the ring is full
+func (r *SampleRing) PushSample(left, right float32) {
+	head := r.head.Load()
+	if head-r.tail.Load() >= uint64(len(r.slots)) {
+		r.dropped.Add(1)
+		return
+	}
+
+	r.slots[head&r.mask] = uint64(math.Float32bits(left)) | uint64(math.Float32bits(right))<<32
+	r.head.Store(head + 1)
+}
+
+// Read fills p with as many whole stereo frames as fit, padding with silence
+// when not enough samples are buffered. It never blocks.
+func (r *SampleRing) Read(p []byte) (int, error) {
+	frames := len(p) / sampleFrameSize
+	if frames == 0 {
+		return 0, nil
+	}
+
+	tail := r.tail.Load()
+	available := r.head.Load() - tail
+
+	n := uint64(frames)
+	if available < n {
+		n = available
+		r.underruns.Add(1)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		slot := r.slots[(tail+i)&r.mask]
+		binary.LittleEndian.PutUint32(p[i*sampleFrameSize:], uint32(slot))
+		binary.LittleEndian.PutUint32(p[i*sampleFrameSize+4:], uint32(slot>>32))
+	}
+	r.tail.Store(tail + n)
+
+	silence := p[n*sampleFrameSize : frames*sampleFrameSize]
+	for i := range silence {
+		silence[i] = 0
+	}
+
+	return frames * sampleFrameSize, nil
+}
+
+// Len returns the number of buffered samples
+func (r *SampleRing) Len() int {
+	return int(r.head.Load() - r.tail.Load())
+}
+
+// Cap returns the number of samples the ring can hold
+func (r *SampleRing) Cap() int {
+	return len(r.slots)
+}
+
+// Dropped returns the number of samples discarded because the ring was full
+func (r *SampleRing) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Underruns returns the number of reads that had to be padded with silence
+func (r *SampleRing) Underruns() uint64 {
+	return r.underruns.Load()
+}
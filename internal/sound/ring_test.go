@@ -0,0 +1,98 @@
+package sound
+
+import (
+	"encoding/binary"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// TestSampleRingRead tests that samples are read back as interleaved float32
+func TestSampleRingRead(t *testing.T) {
+	ring := NewSampleRing(4)
+	ring.PushSample(0.5, -0.25)
+	ring.PushSample(1.0, 0.0)
+
+	p := make([]byte, 3*8)
+	n, err := ring.Read(p)
+	if err != nil || n != len(p) {
+		t.Fatalf("Expected full read of %d bytes, got %d (%v)", len(p), n, err)
+	}
+
+	expected := []float32{0.5, -0.25, 1.0, 0.0, 0, 0}
+	for i, want := range expected {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(p[i*4:]))
+		if got != want {
+			t.Errorf("Expected sample %d to be %f, got %f", i, want, got)
+		}
+	}
+
+	if ring.Underruns() != 1 {
+		t.Errorf("Expected 1 underrun, got %d", ring.Underruns())
+	}
+}
+
+// TestSampleRingFull tests that samples are dropped when the ring is full
+func TestSampleRingFull(t *testing.T) {
+	ring := NewSampleRing(3)
+	if ring.Cap() != 4 {
+		t.Errorf("Expected capacity rounded up to 4, got %d", ring.Cap())
+	}
+
+	for i := 0; i < 6; i++ {
+		ring.PushSample(float32(i), 0)
+	}
+
+	if ring.Len() != 4 || ring.Dropped() != 2 {
+		t.Errorf("Expected 4 buffered and 2 dropped, got %d and %d", ring.Len(), ring.Dropped())
+	}
+
+	// The oldest samples are kept
+	p := make([]byte, 8)
+	ring.Read(p)
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(p)); got != 0 {
+		t.Errorf("Expected first sample 0, got %f", got)
+	}
+}
+
+// TestSampleRingConcurrent tests one producer and one consumer running together
+func TestSampleRingConcurrent(t *testing.T) {
+	ring := NewSampleRing(64)
+	const total = 10000
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= total; {
+			if ring.Len() < ring.Cap() {
+				ring.PushSample(float32(i), float32(-i))
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	// Samples must arrive in order, with silence only while the ring is empty
+	next := float32(1)
+	p := make([]byte, 16*8)
+	for next <= total {
+		n, _ := ring.Read(p)
+		for i := 0; i < n; i += 8 {
+			left := math.Float32frombits(binary.LittleEndian.Uint32(p[i:]))
+			right := math.Float32frombits(binary.LittleEndian.Uint32(p[i+4:]))
+			if left == 0 {
+				continue
+			}
+			if left != next || right != -next {
+				t.Fatalf("Expected sample %f, got %f/%f", next, left, right)
+			}
+			next++
+		}
+		runtime.Gosched()
+	}
+
+	wg.Wait()
+}
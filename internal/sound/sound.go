@@ -1,9 +1,39 @@
 package sound
 
 import (
-	"log"
+	"fmt"
+	"math"
+
+	"github.com/briancain/gameboy-go/internal/snapshot"
+)
+
+const (
+	// CPU clock rate in T-cycles per second
+	CLOCK_RATE = 4194304
+
+	// Default output sample rate in Hz
+	DEFAULT_SAMPLE_RATE = 44100
+
+	// The frame sequencer runs at 512 Hz
+	frameSequencerPeriod = CLOCK_RATE / 512
 )
 
+// Bits that always read back as 1 for each register from NR10 (FF10) to
+// NR52 (FF26). Write-only bits and unused registers read as set.
+var readMasks = [0x17]byte{
+	0x80, 0x3F, 0x00, 0xFF, 0xBF, // NR10-NR14
+	0xFF, 0x3F, 0x00, 0xFF, 0xBF, // NR20-NR24
+	0x7F, 0xFF, 0x9F, 0xFF, 0xBF, // NR30-NR34
+	0xFF, 0xFF, 0x00, 0x00, 0xBF, // NR40-NR44
+	0x00, 0x00, 0x70, // NR50-NR52
+}
+
+// SampleSink receives the stereo samples mixed by the APU. Samples are in
+// the range -1.0 to 1.0 and are produced at the configured sample rate.
+type SampleSink interface {
+	PushSample(left, right float32)
+}
+
 // Sound handles the Game Boy's audio system
 type Sound struct {
 	// Sound channels
@@ -12,44 +42,49 @@ type Sound struct {
 	channel3 Channel // Wave Output
 	channel4 Channel // Noise
 
+	// NR52 bit 7, powers the whole APU
 	enabled bool
-}
 
-// Channel represents a sound channel
-type Channel struct {
-	enabled bool
+	// NR50 master volume and NR51 panning
+	masterVolume byte
+	panning      byte
+
+	// Wave pattern RAM (FF30-FF3F), 32 4-bit samples
+	waveRAM [16]byte
 
-	// Channel-specific registers
-	registers [5]byte
+	// Frame sequencer: next step (0-7) and cycles until it runs
+	frameStep    byte
+	frameCounter int
 
-	// Sound generation state
-	frequency    uint16
-	lengthTimer  byte
-	volumeTimer  byte
-	sweepTimer   byte
-	envelopeStep byte
+	// Output
+	sink       SampleSink
+	sampleRate int
 
-	// Output state
-	outputVolume byte
-	outputValue  byte
+	// Sample clock, advanced by sampleRate per cycle and wrapped at CLOCK_RATE
+	sampleClock int
+
+	// Running sums of the mixed output since the last sample, for averaging
+	accLeft   float64
+	accRight  float64
+	accCycles int
+
+	// High-pass filter state, removes the DC offset like the capacitor on
+	// the real hardware output
+	capLeft      float64
+	capRight     float64
+	chargeFactor float64
 }
 
 // Initialize a new Sound system
 func NewSound() *Sound {
-	sound := &Sound{
-		enabled: true,
-	}
-
-	// Initialize channels
-	sound.channel1 = Channel{enabled: false}
-	sound.channel2 = Channel{enabled: false}
-	sound.channel3 = Channel{enabled: false}
-	sound.channel4 = Channel{enabled: false}
+	sound := &Sound{}
+	sound.SetSampleRate(DEFAULT_SAMPLE_RATE)
+	sound.Reset()
 
 	return sound
 }
 
-// Reset the sound system
+// Reset the sound system to the state left by the boot ROM
 func (s *Sound) Reset() {
 	s.enabled = true
 
@@ -57,42 +92,244 @@ func (s *Sound) Reset() {
 	s.channel1 = Channel{enabled: false}
 	s.channel2 = Channel{enabled: false}
 	s.channel3 = Channel{enabled: false}
-	s.channel4 = Channel{enabled: false}
+	s.channel4 = Channel{enabled: false, lfsr: 0x7FFF}
+
+	s.channel1.registers = [5]byte{0x80, 0xBF, 0xF3, 0xFF, 0xBF}
+	s.channel2.registers = [5]byte{0xFF, 0x3F, 0x00, 0xFF, 0xBF}
+	s.channel3.registers = [5]byte{0x7F, 0xFF, 0x9F, 0xFF, 0xBF}
+	s.channel4.registers = [5]byte{0xFF, 0xFF, 0x00, 0x00, 0xBF}
+	s.channel1.dacEnabled = true
+
+	s.masterVolume = 0x77
+	s.panning = 0xF3
+
+	s.frameStep = 0
+	s.frameCounter = frameSequencerPeriod
+}
+
+// SetSampleSink sets where mixed samples are sent. A nil sink disables
+// mixing entirely.
+func (s *Sound) SetSampleSink(sink SampleSink) {
+	s.sink = sink
+}
+
+// SetSampleRate sets the output sample rate in Hz
+func (s *Sound) SetSampleRate(rate int) {
+	if rate <= 0 {
+		rate = DEFAULT_SAMPLE_RATE
+	}
+	s.sampleRate = rate
+	s.sampleClock = 0
+
+	// The hardware capacitor discharges by a factor of 0.999958 per cycle
+	s.chargeFactor = math.Pow(0.999958, float64(CLOCK_RATE)/float64(rate))
+}
+
+// SampleRate returns the output sample rate in Hz
+func (s *Sound) SampleRate() int {
+	return s.sampleRate
 }
 
 // Step advances the sound system by the specified number of cycles
 func (s *Sound) Step(cycles int) {
-	// TODO: Implement sound generation
-	// This is a placeholder for now
+	for cycles > 0 {
+		n := cycles
+		if s.enabled && n > s.frameCounter {
+			n = s.frameCounter
+		}
+
+		if s.enabled {
+			s.channel1.stepSquare(n)
+			s.channel2.stepSquare(n)
+			s.channel3.stepWave(n)
+			s.channel4.stepNoise(n)
+
+			s.frameCounter -= n
+			if s.frameCounter == 0 {
+				s.frameCounter = frameSequencerPeriod
+				s.clockFrameSequencer()
+			}
+		}
+
+		if s.sink != nil {
+			s.mix(n)
+		}
+
+		cycles -= n
+	}
+}
+
+// clockFrameSequencer runs one 512 Hz frame sequencer step
+//
+//	Step   Length   Sweep   Envelope
+//	0      Clock    -       -
+//	1      -        -       -
+//	2      Clock    Clock   -
+//	3      -        -       -
+//	4      Clock    -       -
+//	5      -        -       -
+//	6      Clock    Clock   -
+//	7      -        -       Clock
+func (s *Sound) clockFrameSequencer() {
+	switch s.frameStep {
+	case 0, 4:
+		s.clockLengths()
+	case 2, 6:
+		s.clockLengths()
+		s.channel1.clockSweep()
+	case 7:
+		s.channel1.clockEnvelope()
+		s.channel2.clockEnvelope()
+		s.channel4.clockEnvelope()
+	}
+
+	s.frameStep = (s.frameStep + 1) & 0x07
+}
+
+func (s *Sound) clockLengths() {
+	s.channel1.clockLength()
+	s.channel2.clockLength()
+	s.channel3.clockLength()
+	s.channel4.clockLength()
+}
+
+// waveOutput returns the current digital output (0-15) of the wave channel
+func (s *Sound) waveOutput() byte {
+	c := &s.channel3
+	if !c.enabled {
+		return 0
+	}
+
+	sample := s.waveRAM[c.position>>1]
+	if c.position&0x01 == 0 {
+		sample >>= 4
+	}
+	sample &= 0x0F
+
+	// NR32 bits 5-6: 0 = mute, 1 = 100%, 2 = 50%, 3 = 25%
+	code := (c.registers[2] >> 5) & 0x03
+	if code == 0 {
+		return 0
+	}
+	return sample >> (code - 1)
+}
+
+// mix adds the current channel outputs to the sample accumulators for the
+// given number of cycles and emits any samples that are due
+func (s *Sound) mix(cycles int) {
+	var left, right float64
+
+	if s.enabled {
+		outputs := [4]struct {
+			dac     bool
+			digital byte
+		}{
+			{s.channel1.dacEnabled, s.channel1.squareOutput()},
+			{s.channel2.dacEnabled, s.channel2.squareOutput()},
+			{s.channel3.dacEnabled, s.waveOutput()},
+			{s.channel4.dacEnabled, s.channel4.noiseOutput()},
+		}
+
+		for i, out := range outputs {
+			if !out.dac {
+				continue
+			}
+			// The DAC maps 0-15 to an analog level between -1 and 1
+			analog := float64(out.digital)/7.5 - 1.0
+
+			// NR51: bits 4-7 send channels 1-4 to the left, bits 0-3 to the right
+			if s.panning&(0x10<<i) != 0 {
+				left += analog
+			}
+			if s.panning&(0x01<<i) != 0 {
+				right += analog
+			}
+		}
+
+		// NR50: bits 4-6 are the left volume, bits 0-2 the right volume
+		left *= float64((s.masterVolume>>4)&0x07+1) / 8 / 4
+		right *= float64(s.masterVolume&0x07+1) / 8 / 4
+	}
+
+	for cycles > 0 {
+		// Split at the next sample boundary
+		n := (CLOCK_RATE - s.sampleClock + s.sampleRate - 1) / s.sampleRate
+		if n > cycles {
+			n = cycles
+		}
+
+		s.accLeft += left * float64(n)
+		s.accRight += right * float64(n)
+		s.accCycles += n
+
+		s.sampleClock += n * s.sampleRate
+		if s.sampleClock >= CLOCK_RATE {
+			s.sampleClock -= CLOCK_RATE
+			s.emitSample()
+		}
+
+		cycles -= n
+	}
+}
+
+// emitSample averages the accumulated output, filters it and sends it to the sink
+func (s *Sound) emitSample() {
+	if s.accCycles == 0 {
+		return
+	}
+
+	left := s.accLeft / float64(s.accCycles)
+	right := s.accRight / float64(s.accCycles)
+	s.accLeft, s.accRight, s.accCycles = 0, 0, 0
+
+	outLeft := left - s.capLeft
+	s.capLeft = left - outLeft*s.chargeFactor
+	outRight := right - s.capRight
+	s.capRight = right - outRight*s.chargeFactor
+
+	s.sink.PushSample(float32(outLeft), float32(outRight))
 }
 
 // Read a sound register
 func (s *Sound) ReadRegister(addr uint16) byte {
-	// Sound registers are from 0xFF10 to 0xFF3F
+	switch {
+	case addr >= 0xFF30 && addr <= 0xFF3F:
+		// Wave Pattern RAM, while the wave channel is playing reads return
+		// the byte it is currently playing
+		if s.channel3.enabled {
+			return s.waveRAM[s.channel3.position>>1]
+		}
+		return s.waveRAM[addr-0xFF30]
+	case addr < 0xFF10 || addr > 0xFF26:
+		return 0xFF
+	}
+
+	// Sound registers are from 0xFF10 to 0xFF26
 	regAddr := addr - 0xFF10
+	mask := readMasks[regAddr]
 
 	switch {
 	case regAddr < 0x05:
 		// Channel 1 - Tone & Sweep
-		return s.channel1.registers[regAddr]
+		return s.channel1.registers[regAddr] | mask
 	case regAddr < 0x0A:
 		// Channel 2 - Tone
-		return s.channel2.registers[regAddr-0x05]
+		return s.channel2.registers[regAddr-0x05] | mask
 	case regAddr < 0x0F:
 		// Channel 3 - Wave Output
-		return s.channel3.registers[regAddr-0x0A]
+		return s.channel3.registers[regAddr-0x0A] | mask
 	case regAddr < 0x14:
 		// Channel 4 - Noise
-		return s.channel4.registers[regAddr-0x0F]
+		return s.channel4.registers[regAddr-0x0F] | mask
 	case regAddr == 0x14:
 		// NR50 - Channel control / ON-OFF / Volume (R/W)
-		return 0x77 // Default value
+		return s.masterVolume | mask
 	case regAddr == 0x15:
 		// NR51 - Selection of Sound output terminal (R/W)
-		return 0xF3 // Default value
-	case regAddr == 0x16:
+		return s.panning | mask
+	default:
 		// NR52 - Sound on/off
-		value := byte(0x70) // Bits 4-6 are unused and always return 1
+		value := mask // Bits 4-6 are unused and always return 1
 		if s.enabled {
 			value |= 0x80
 		}
@@ -109,167 +346,274 @@ func (s *Sound) ReadRegister(addr uint16) byte {
 			value |= 0x08
 		}
 		return value
-	case regAddr >= 0x30 && regAddr < 0x40:
-		// Wave Pattern RAM
-		return 0xFF // Default value
-	default:
-		return 0xFF
 	}
 }
 
 // Write a sound register
 func (s *Sound) WriteRegister(addr uint16, value byte) {
-	// Sound registers are from 0xFF10 to 0xFF3F
-	regAddr := addr - 0xFF10
+	switch {
+	case addr >= 0xFF30 && addr <= 0xFF3F:
+		// Wave Pattern RAM is accessible even while the APU is off
+		if s.channel3.enabled {
+			s.waveRAM[s.channel3.position>>1] = value
+		} else {
+			s.waveRAM[addr-0xFF30] = value
+		}
+		return
+	case addr < 0xFF10 || addr > 0xFF26:
+		return
+	case addr == 0xFF26:
+		// NR52 - Sound on/off
+		on := (value & 0x80) != 0
+		if s.enabled && !on {
+			s.powerOff()
+		} else if !s.enabled && on {
+			s.powerOn()
+		}
+		return
+	}
 
-	// If sound is disabled, only NR52 can be written
-	if !s.enabled && regAddr != 0x16 {
+	// If sound is disabled, only NR52, wave RAM and (on the DMG) the length
+	// counters can be written
+	if !s.enabled {
+		switch addr {
+		case 0xFF11:
+			s.channel1.lengthCounter = 64 - int(value&0x3F)
+		case 0xFF16:
+			s.channel2.lengthCounter = 64 - int(value&0x3F)
+		case 0xFF1B:
+			s.channel3.lengthCounter = 256 - int(value)
+		case 0xFF20:
+			s.channel4.lengthCounter = 64 - int(value&0x3F)
+		}
 		return
 	}
 
+	// Sound registers are from 0xFF10 to 0xFF26
+	regAddr := addr - 0xFF10
+
 	switch {
 	case regAddr < 0x05:
 		// Channel 1 - Tone & Sweep
-		s.channel1.registers[regAddr] = value
-		s.updateChannel1()
+		s.writeChannel1(int(regAddr), value)
 	case regAddr < 0x0A:
 		// Channel 2 - Tone
-		s.channel2.registers[regAddr-0x05] = value
-		s.updateChannel2()
+		s.writeSquare(&s.channel2, int(regAddr-0x05), value)
 	case regAddr < 0x0F:
 		// Channel 3 - Wave Output
-		s.channel3.registers[regAddr-0x0A] = value
-		s.updateChannel3()
+		s.writeChannel3(int(regAddr-0x0A), value)
 	case regAddr < 0x14:
 		// Channel 4 - Noise
-		s.channel4.registers[regAddr-0x0F] = value
-		s.updateChannel4()
+		s.writeChannel4(int(regAddr-0x0F), value)
 	case regAddr == 0x14:
 		// NR50 - Channel control / ON-OFF / Volume (R/W)
-		// TODO: Implement volume control
+		s.masterVolume = value
 	case regAddr == 0x15:
 		// NR51 - Selection of Sound output terminal (R/W)
-		// TODO: Implement panning
-	case regAddr == 0x16:
-		// NR52 - Sound on/off
-		s.enabled = (value & 0x80) != 0
-		if !s.enabled {
-			// Disable all sound channels when sound is turned off
-			s.Reset()
-		}
-	case regAddr >= 0x30 && regAddr < 0x40:
-		// Wave Pattern RAM
-		// TODO: Implement wave pattern RAM
+		s.panning = value
 	}
 }
 
-// Update Channel 1 (Tone & Sweep)
-func (s *Sound) updateChannel1() {
-	// Check if channel was triggered
-	if (s.channel1.registers[4] & 0x80) != 0 {
-		s.channel1.enabled = true
+// powerOff clears every sound register and silences all channels
+func (s *Sound) powerOff() {
+	s.enabled = false
 
-		// Reset length timer if needed
-		if s.channel1.lengthTimer == 0 {
-			s.channel1.lengthTimer = 64
-		}
+	// Length counters are not affected by power on the DMG
+	lengths := [4]int{
+		s.channel1.lengthCounter,
+		s.channel2.lengthCounter,
+		s.channel3.lengthCounter,
+		s.channel4.lengthCounter,
+	}
 
-		// Set frequency
-		s.channel1.frequency = uint16(s.channel1.registers[3]&0x07)<<8 | uint16(s.channel1.registers[2])
+	s.channel1 = Channel{lengthCounter: lengths[0]}
+	s.channel2 = Channel{lengthCounter: lengths[1]}
+	s.channel3 = Channel{lengthCounter: lengths[2]}
+	s.channel4 = Channel{lengthCounter: lengths[3], lfsr: 0x7FFF}
+	s.masterVolume = 0
+	s.panning = 0
+}
 
-		// Set volume
-		s.channel1.outputVolume = (s.channel1.registers[2] >> 4) & 0x0F
+// powerOn restarts the frame sequencer after the APU is switched back on
+func (s *Sound) powerOn() {
+	s.enabled = true
+	s.frameStep = 0
+	s.frameCounter = frameSequencerPeriod
+	s.channel1.position = 0
+	s.channel2.position = 0
+	s.channel3.position = 0
+}
 
-		// Reset envelope
-		s.channel1.envelopeStep = 0
+// lengthClockedNext reports whether the next frame sequencer step clocks
+// the length counters
+func (s *Sound) lengthClockedNext() bool {
+	return s.frameStep&0x01 == 0
+}
 
-		// Reset sweep
-		s.channel1.sweepTimer = (s.channel1.registers[0] >> 4) & 0x07
-		if s.channel1.sweepTimer == 0 {
-			s.channel1.sweepTimer = 8
+// writeLengthEnable handles the NRx4 length enable bit and trigger, including
+// the extra length clock that happens when length is enabled during a frame
+// sequencer step that doesn't clock it. It returns whether the channel was
+// triggered.
+func (s *Sound) writeLengthEnable(c *Channel, value byte, maxLength int) bool {
+	wasEnabled := c.lengthEnabled
+	c.lengthEnabled = value&0x40 != 0
+	trigger := value&0x80 != 0
+	extraClock := !s.lengthClockedNext()
+
+	if extraClock && !wasEnabled && c.lengthEnabled && c.lengthCounter > 0 {
+		c.lengthCounter--
+		if c.lengthCounter == 0 && !trigger {
+			c.enabled = false
 		}
-
-		log.Printf("[Sound] Channel 1 triggered, freq=%d, vol=%d", s.channel1.frequency, s.channel1.outputVolume)
 	}
-}
-
-// Update Channel 2 (Tone)
-func (s *Sound) updateChannel2() {
-	// Check if channel was triggered
-	if (s.channel2.registers[4] & 0x80) != 0 {
-		s.channel2.enabled = true
 
-		// Reset length timer if needed
-		if s.channel2.lengthTimer == 0 {
-			s.channel2.lengthTimer = 64
+	if trigger && c.lengthCounter == 0 {
+		c.lengthCounter = maxLength
+		if c.lengthEnabled && extraClock {
+			c.lengthCounter--
 		}
+	}
 
-		// Set frequency
-		s.channel2.frequency = uint16(s.channel2.registers[3]&0x07)<<8 | uint16(s.channel2.registers[2])
+	return trigger
+}
 
-		// Set volume
-		s.channel2.outputVolume = (s.channel2.registers[2] >> 4) & 0x0F
+// writeChannel1 writes NR10-NR14
+func (s *Sound) writeChannel1(reg int, value byte) {
+	c := &s.channel1
+	if reg != 0 {
+		s.writeSquare(c, reg, value)
+		return
+	}
 
-		// Reset envelope
-		s.channel2.envelopeStep = 0
+	c.registers[0] = value
 
-		log.Printf("[Sound] Channel 2 triggered, freq=%d, vol=%d", s.channel2.frequency, s.channel2.outputVolume)
+	// Clearing negate after a negated calculation disables the channel
+	if c.sweepNegated && value&0x08 == 0 {
+		c.enabled = false
 	}
 }
 
-// Update Channel 3 (Wave Output)
-func (s *Sound) updateChannel3() {
-	// Check if channel was triggered
-	if (s.channel3.registers[4] & 0x80) != 0 {
-		s.channel3.enabled = true
-
-		// Check if channel is enabled
-		if (s.channel3.registers[0] & 0x80) == 0 {
-			s.channel3.enabled = false
+// writeSquare writes NRx1-NRx4 of a square channel
+func (s *Sound) writeSquare(c *Channel, reg int, value byte) {
+	c.registers[reg] = value
+
+	switch reg {
+	case 1:
+		// Duty and length load
+		c.lengthCounter = 64 - int(value&0x3F)
+	case 2:
+		// Volume envelope, the DAC is off when the top 5 bits are clear
+		c.dacEnabled = value&0xF8 != 0
+		if !c.dacEnabled {
+			c.enabled = false
 		}
-
-		// Reset length timer if needed
-		if s.channel3.lengthTimer == 0 {
-			s.channel3.lengthTimer = 255 // Max value for byte
+	case 3:
+		// Frequency low
+		c.frequency = c.frequency&0x700 | uint16(value)
+	case 4:
+		// Frequency high, length enable and trigger
+		c.frequency = c.frequency&0xFF | uint16(value&0x07)<<8
+		if s.writeLengthEnable(c, value, 64) {
+			s.triggerSquare(c)
 		}
+	}
+}
 
-		// Set frequency
-		s.channel3.frequency = uint16(s.channel3.registers[3]&0x07)<<8 | uint16(s.channel3.registers[2])
-
-		// Set volume
-		volumeCode := (s.channel3.registers[2] >> 5) & 0x03
-		switch volumeCode {
-		case 0:
-			s.channel3.outputVolume = 0
-		case 1:
-			s.channel3.outputVolume = 15
-		case 2:
-			s.channel3.outputVolume = 7
-		case 3:
-			s.channel3.outputVolume = 3
-		}
+// triggerSquare restarts a square channel
+func (s *Sound) triggerSquare(c *Channel) {
+	c.enabled = c.dacEnabled
+	c.timer = c.squarePeriod()
+	c.resetEnvelope()
 
-		log.Printf("[Sound] Channel 3 triggered, freq=%d, vol=%d", s.channel3.frequency, s.channel3.outputVolume)
+	if c == &s.channel1 {
+		c.resetSweep()
 	}
 }
 
-// Update Channel 4 (Noise)
-func (s *Sound) updateChannel4() {
-	// Check if channel was triggered
-	if (s.channel4.registers[4] & 0x80) != 0 {
-		s.channel4.enabled = true
-
-		// Reset length timer if needed
-		if s.channel4.lengthTimer == 0 {
-			s.channel4.lengthTimer = 64
+// writeChannel3 writes NR30-NR34
+func (s *Sound) writeChannel3(reg int, value byte) {
+	c := &s.channel3
+	c.registers[reg] = value
+
+	switch reg {
+	case 0:
+		// DAC power
+		c.dacEnabled = value&0x80 != 0
+		if !c.dacEnabled {
+			c.enabled = false
+		}
+	case 1:
+		// Length load
+		c.lengthCounter = 256 - int(value)
+	case 3:
+		// Frequency low
+		c.frequency = c.frequency&0x700 | uint16(value)
+	case 4:
+		// Frequency high, length enable and trigger
+		c.frequency = c.frequency&0xFF | uint16(value&0x07)<<8
+		if s.writeLengthEnable(c, value, 256) {
+			c.enabled = c.dacEnabled
+			c.timer = c.wavePeriod()
+			c.position = 0
 		}
+	}
+}
 
-		// Set volume
-		s.channel4.outputVolume = (s.channel4.registers[2] >> 4) & 0x0F
+// writeChannel4 writes NR41-NR44 (FF1F is unused)
+func (s *Sound) writeChannel4(reg int, value byte) {
+	c := &s.channel4
+	c.registers[reg] = value
+
+	switch reg {
+	case 1:
+		// Length load
+		c.lengthCounter = 64 - int(value&0x3F)
+	case 2:
+		// Volume envelope
+		c.dacEnabled = value&0xF8 != 0
+		if !c.dacEnabled {
+			c.enabled = false
+		}
+	case 4:
+		// Length enable and trigger
+		if s.writeLengthEnable(c, value, 64) {
+			c.enabled = c.dacEnabled
+			c.timer = c.noisePeriod()
+			c.lfsr = 0x7FFF
+			c.resetEnvelope()
+		}
+	}
+}
 
-		// Reset envelope
-		s.channel4.envelopeStep = 0
+// SaveState writes the sound system and channel state
+func (s *Sound) SaveState(w *snapshot.StateWriter) {
+	w.Section("APU ")
+	w.Bool(s.enabled)
+	w.Uint8(s.masterVolume)
+	w.Uint8(s.panning)
+	w.Bytes(s.waveRAM[:])
+	w.Uint8(s.frameStep)
+	w.Int(s.frameCounter)
+	s.channel1.saveState(w)
+	s.channel2.saveState(w)
+	s.channel3.saveState(w)
+	s.channel4.saveState(w)
+}
 
-		log.Printf("[Sound] Channel 4 triggered, vol=%d", s.channel4.outputVolume)
+// LoadState restores the sound state written by SaveState
+func (s *Sound) LoadState(r *snapshot.StateReader) {
+	r.Section("APU ")
+	s.enabled = r.Bool()
+	s.masterVolume = r.Uint8()
+	s.panning = r.Uint8()
+	r.BytesInto(s.waveRAM[:])
+	s.frameStep = r.Uint8()
+	s.frameCounter = r.Int()
+	if s.frameCounter <= 0 || s.frameCounter > frameSequencerPeriod {
+		r.Fail(fmt.Errorf("invalid frame sequencer counter %d", s.frameCounter))
 	}
+	s.channel1.loadState(r)
+	s.channel2.loadState(r)
+	s.channel3.loadState(r)
+	s.channel4.loadState(r)
 }
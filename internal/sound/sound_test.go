@@ -113,8 +113,8 @@ func TestChannelTrigger(t *testing.T) {
 	sound := NewSound()
 
 	// Trigger channel 1
-	sound.channel1.registers[4] = 0x80 // Set trigger bit
-	sound.updateChannel1()
+	sound.WriteRegister(0xFF12, 0xF0) // Volume 15, DAC on
+	sound.WriteRegister(0xFF14, 0x80) // Set trigger bit
 
 	// Check that channel 1 was enabled
 	if !sound.channel1.enabled {
@@ -122,8 +122,8 @@ func TestChannelTrigger(t *testing.T) {
 	}
 
 	// Trigger channel 2
-	sound.channel2.registers[4] = 0x80 // Set trigger bit
-	sound.updateChannel2()
+	sound.WriteRegister(0xFF17, 0xF0) // Volume 15, DAC on
+	sound.WriteRegister(0xFF19, 0x80) // Set trigger bit
 
 	// Check that channel 2 was enabled
 	if !sound.channel2.enabled {
@@ -131,9 +131,8 @@ func TestChannelTrigger(t *testing.T) {
 	}
 
 	// Trigger channel 3
-	sound.channel3.registers[0] = 0x80 // Enable channel 3
-	sound.channel3.registers[4] = 0x80 // Set trigger bit
-	sound.updateChannel3()
+	sound.WriteRegister(0xFF1A, 0x80) // Enable channel 3
+	sound.WriteRegister(0xFF1E, 0x80) // Set trigger bit
 
 	// Check that channel 3 was enabled
 	if !sound.channel3.enabled {
@@ -141,11 +140,225 @@ func TestChannelTrigger(t *testing.T) {
 	}
 
 	// Trigger channel 4
-	sound.channel4.registers[4] = 0x80 // Set trigger bit
-	sound.updateChannel4()
+	sound.WriteRegister(0xFF21, 0xF0) // Volume 15, DAC on
+	sound.WriteRegister(0xFF23, 0x80) // Set trigger bit
 
 	// Check that channel 4 was enabled
 	if !sound.channel4.enabled {
 		t.Error("Expected channel 4 to be enabled after trigger")
 	}
+
+	// NR52 reports all four channels as active
+	if value := sound.ReadRegister(0xFF26); value != 0xFF {
+		t.Errorf("Expected NR52 to be 0xFF, got %02X", value)
+	}
+}
+
+// TestChannelTriggerDACOff tests that a channel with its DAC off stays silent
+func TestChannelTriggerDACOff(t *testing.T) {
+	sound := NewSound()
+
+	sound.WriteRegister(0xFF17, 0x00) // DAC off
+	sound.WriteRegister(0xFF19, 0x80) // Trigger
+
+	if sound.channel2.enabled {
+		t.Error("Expected channel 2 to stay disabled with its DAC off")
+	}
+
+	// Turning the DAC off disables a playing channel
+	sound.WriteRegister(0xFF17, 0xF0)
+	sound.WriteRegister(0xFF19, 0x80)
+	sound.WriteRegister(0xFF17, 0x00)
+	if sound.channel2.enabled {
+		t.Error("Expected channel 2 to be disabled when its DAC is turned off")
+	}
+}
+
+// TestRegisterReadMasks tests that registers read back with unused bits set
+func TestRegisterReadMasks(t *testing.T) {
+	sound := NewSound()
+
+	tests := []struct {
+		addr     uint16
+		write    byte
+		expected byte
+	}{
+		{0xFF10, 0x00, 0x80}, // NR10
+		{0xFF11, 0x80, 0xBF}, // NR11, only duty is readable
+		{0xFF12, 0xF3, 0xF3}, // NR12
+		{0xFF13, 0x12, 0xFF}, // NR13 is write-only
+		{0xFF14, 0x40, 0xFF}, // NR14, only length enable is readable
+		{0xFF15, 0x00, 0xFF}, // Unused
+		{0xFF1A, 0x00, 0x7F}, // NR30
+		{0xFF1C, 0x20, 0xBF}, // NR32
+		{0xFF22, 0x5A, 0x5A}, // NR43
+		{0xFF24, 0x35, 0x35}, // NR50
+		{0xFF25, 0x9C, 0x9C}, // NR51
+		{0xFF27, 0x00, 0xFF}, // Unused
+	}
+
+	for _, test := range tests {
+		sound.WriteRegister(test.addr, test.write)
+		if value := sound.ReadRegister(test.addr); value != test.expected {
+			t.Errorf("Expected %04X to read %02X after writing %02X, got %02X",
+				test.addr, test.expected, test.write, value)
+		}
+	}
+}
+
+// TestPowerOff tests that clearing NR52 resets the registers and ignores writes
+func TestPowerOff(t *testing.T) {
+	sound := NewSound()
+	sound.WriteRegister(0xFF30, 0xAB)
+	sound.WriteRegister(0xFF12, 0xF0)
+	sound.WriteRegister(0xFF14, 0x80)
+
+	sound.WriteRegister(0xFF26, 0x00)
+
+	if value := sound.ReadRegister(0xFF26); value != 0x70 {
+		t.Errorf("Expected NR52 to be 0x70 when off, got %02X", value)
+	}
+	if value := sound.ReadRegister(0xFF12); value != 0x00 {
+		t.Errorf("Expected NR12 to be cleared, got %02X", value)
+	}
+
+	// Writes are ignored while off, except wave RAM
+	sound.WriteRegister(0xFF24, 0x77)
+	if value := sound.ReadRegister(0xFF24); value != 0x00 {
+		t.Errorf("Expected NR50 write to be ignored while off, got %02X", value)
+	}
+	if value := sound.ReadRegister(0xFF30); value != 0xAB {
+		t.Errorf("Expected wave RAM to survive power off, got %02X", value)
+	}
+
+	sound.WriteRegister(0xFF26, 0x80)
+	sound.WriteRegister(0xFF24, 0x77)
+	if value := sound.ReadRegister(0xFF24); value != 0x77 {
+		t.Errorf("Expected NR50 to be writable after power on, got %02X", value)
+	}
+}
+
+// TestLengthCounter tests that the length counter silences a channel
+func TestLengthCounter(t *testing.T) {
+	sound := NewSound()
+
+	sound.WriteRegister(0xFF17, 0xF0)
+	sound.WriteRegister(0xFF16, 0x3E) // Length 2
+	sound.WriteRegister(0xFF19, 0xC0) // Trigger with length enabled
+
+	// Two length clocks happen within 4 frame sequencer steps
+	sound.Step(frameSequencerPeriod * 4)
+
+	if sound.channel2.enabled {
+		t.Error("Expected channel 2 to be disabled when its length expired")
+	}
+}
+
+// TestEnvelope tests that the volume envelope fades a channel out
+func TestEnvelope(t *testing.T) {
+	sound := NewSound()
+
+	sound.WriteRegister(0xFF17, 0x21) // Volume 2, decrease, period 1
+	sound.WriteRegister(0xFF19, 0x80)
+
+	// The envelope is clocked once every 8 frame sequencer steps
+	sound.Step(frameSequencerPeriod * 8)
+	if sound.channel2.volume != 1 {
+		t.Errorf("Expected volume 1 after one envelope clock, got %d", sound.channel2.volume)
+	}
+	sound.Step(frameSequencerPeriod * 16)
+	if sound.channel2.volume != 0 {
+		t.Errorf("Expected volume 0 after fading out, got %d", sound.channel2.volume)
+	}
+}
+
+// TestSweepOverflow tests that a sweep past 2047 disables channel 1
+func TestSweepOverflow(t *testing.T) {
+	sound := NewSound()
+
+	sound.WriteRegister(0xFF10, 0x11) // Period 1, increase, shift 1
+	sound.WriteRegister(0xFF12, 0xF0)
+	sound.WriteRegister(0xFF13, 0x00)
+	sound.WriteRegister(0xFF14, 0x85) // Trigger, frequency 0x500
+
+	if !sound.channel1.enabled {
+		t.Fatal("Expected channel 1 to be enabled after trigger")
+	}
+
+	// 0x500 sweeps to 0x780, and the check of the next step (0xB40) overflows
+	sound.Step(frameSequencerPeriod * 4)
+	if sound.channel1.enabled {
+		t.Error("Expected channel 1 to be disabled by sweep overflow")
+	}
+
+	// An overflow on trigger disables the channel immediately
+	sound.WriteRegister(0xFF13, 0x00)
+	sound.WriteRegister(0xFF14, 0x86) // Frequency 0x600 + 0x300
+	if sound.channel1.enabled {
+		t.Error("Expected channel 1 to be disabled by overflow on trigger")
+	}
+}
+
+// TestNoiseLFSR tests the noise channel shift register
+func TestNoiseLFSR(t *testing.T) {
+	sound := NewSound()
+
+	sound.WriteRegister(0xFF21, 0xF0)
+	sound.WriteRegister(0xFF22, 0x00) // Divisor 8, shift 0
+	sound.WriteRegister(0xFF23, 0x80)
+
+	sound.Step(8)
+
+	// 0x7FFF shifts right with a 0 fed back (bit0 XOR bit1 = 0)
+	if sound.channel4.lfsr != 0x3FFF {
+		t.Errorf("Expected LFSR 0x3FFF, got %04X", sound.channel4.lfsr)
+	}
+}
+
+// sampleRecorder collects mixed samples for tests
+type sampleRecorder struct {
+	left, right []float32
+}
+
+func (r *sampleRecorder) PushSample(left, right float32) {
+	r.left = append(r.left, left)
+	r.right = append(r.right, right)
+}
+
+// TestMixing tests sample output rate and stereo panning
+func TestMixing(t *testing.T) {
+	sound := NewSound()
+	recorder := &sampleRecorder{}
+	sound.SetSampleSink(recorder)
+
+	// Channel 2 at full volume, panned left only
+	sound.WriteRegister(0xFF25, 0x20)
+	sound.WriteRegister(0xFF24, 0x77)
+	sound.WriteRegister(0xFF16, 0x80) // 50% duty
+	sound.WriteRegister(0xFF17, 0xF0)
+	sound.WriteRegister(0xFF18, 0x00)
+	sound.WriteRegister(0xFF19, 0x87) // Trigger, ~1 kHz
+
+	// One second of emulation
+	sound.Step(CLOCK_RATE)
+
+	if len(recorder.left) != DEFAULT_SAMPLE_RATE {
+		t.Errorf("Expected %d samples, got %d", DEFAULT_SAMPLE_RATE, len(recorder.left))
+	}
+
+	var peakLeft, peakRight float32
+	for i := range recorder.left {
+		if v := recorder.left[i]; v > peakLeft {
+			peakLeft = v
+		}
+		if v := recorder.right[i]; v > peakRight {
+			peakRight = v
+		}
+	}
+	if peakLeft < 0.1 {
+		t.Errorf("Expected audible output on the left, peak %f", peakLeft)
+	}
+	if peakRight != 0 {
+		t.Errorf("Expected silence on the right, peak %f", peakRight)
+	}
 }
@@ -0,0 +1,162 @@
+package sound
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// Size of the canonical 44 byte PCM WAV header
+	wavHeaderSize = 44
+
+	// RIFF sizes are 32-bit, which limits the number of 4 byte frames
+	wavMaxFrames = (0xFFFFFFFF - (wavHeaderSize - 8)) / 4
+)
+
+// WAVWriter is a SampleSink that records samples to a 16-bit stereo PCM WAV
+// stream. The RIFF and data chunk sizes are patched in by Close, so the
+// destination must be seekable.
+type WAVWriter struct {
+	dst        io.WriteSeeker
+	buf        *bufio.Writer
+	sampleRate int
+	frames     uint32
+	err        error
+	closed     bool
+}
+
+// NewWAVWriter writes a WAV header to dst and returns a writer for samples
+// at the given sample rate
+func NewWAVWriter(dst io.WriteSeeker, sampleRate int) (*WAVWriter, error) {
+	w := &WAVWriter{
+		dst:        dst,
+		buf:        bufio.NewWriter(dst),
+		sampleRate: sampleRate,
+	}
+
+	// Sizes are placeholders until Close
+	w.writeHeader(0)
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w, nil
+}
+
+// PushSample appends a stereo sample, clamped to the 16-bit range
+func (w *WAVWriter) PushSample(left, right float32) {
+	if w.err != nil || w.closed {
+		return
+	}
+	if w.frames >= wavMaxFrames {
+		w.err = errors.New("WAV recording exceeds 4GB")
+		return
+	}
+
+	var frame [4]byte
+	binary.LittleEndian.PutUint16(frame[0:], uint16(toPCM16(left)))
+	binary.LittleEndian.PutUint16(frame[2:], uint16(toPCM16(right)))
+	if _, err := w.buf.Write(frame[:]); err != nil {
+		w.err = err
+		return
+	}
+	w.frames++
+}
+
+// Frames returns the number of stereo samples written so far
+func (w *WAVWriter) Frames() uint32 {
+	return w.frames
+}
+
+// Err returns the first error encountered while writing
+func (w *WAVWriter) Err() error {
+	return w.err
+}
+
+// Close flushes buffered samples and fills in the header sizes. It does not
+// close the underlying destination.
+func (w *WAVWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := w.dst.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.writeHeader(w.frames * 4)
+	if w.err != nil {
+		return w.err
+	}
+	_, err := w.dst.Seek(0, io.SeekEnd)
+	return err
+}
+
+// writeHeader writes the RIFF/WAVE header for dataSize bytes of samples
+func (w *WAVWriter) writeHeader(dataSize uint32) {
+	const (
+		channels      = 2
+		bitsPerSample = 16
+		blockAlign    = channels * bitsPerSample / 8
+	)
+
+	var h [wavHeaderSize]byte
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], wavHeaderSize-8+dataSize)
+	copy(h[8:], "WAVE")
+
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(h[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(h[22:], channels)
+	binary.LittleEndian.PutUint32(h[24:], uint32(w.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(w.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], blockAlign)
+	binary.LittleEndian.PutUint16(h[34:], bitsPerSample)
+
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], dataSize)
+
+	// The header goes straight to dst, samples go through the buffer
+	if _, err := w.dst.Write(h[:]); err != nil {
+		w.err = err
+	}
+}
+
+// toPCM16 converts a sample in the range -1.0 to 1.0 to a signed 16-bit value
+func toPCM16(v float32) int16 {
+	if v > 1 {
+		v = 1
+	} else if v < -1 {
+		v = -1
+	}
+	return int16(v * 32767)
+}
+
+// MultiSink returns a SampleSink that forwards every sample to all sinks,
+// similar to io.MultiWriter
+func MultiSink(sinks ...SampleSink) SampleSink {
+	all := make(multiSink, 0, len(sinks))
+	for _, sink := range sinks {
+		if sink != nil {
+			all = append(all, sink)
+		}
+	}
+	return all
+}
+
+type multiSink []SampleSink
+
+func (m multiSink) PushSample(left, right float32) {
+	for _, sink := range m {
+		sink.PushSample(left, right)
+	}
+}
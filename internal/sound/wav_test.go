@@ -0,0 +1,94 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// memFile is an in-memory io.WriteSeeker for tests
+type memFile struct {
+	data []byte
+	pos  int
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if end := f.pos + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	copy(f.data[f.pos:], p)
+	f.pos += len(p)
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		f.pos = int(offset)
+	case io.SeekCurrent:
+		f.pos += int(offset)
+	case io.SeekEnd:
+		f.pos = len(f.data) + int(offset)
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	return int64(f.pos), nil
+}
+
+// TestWAVWriter tests the header and sample encoding of a WAV recording
+func TestWAVWriter(t *testing.T) {
+	f := &memFile{}
+	w, err := NewWAVWriter(f, 22050)
+	if err != nil {
+		t.Fatalf("NewWAVWriter failed: %v", err)
+	}
+
+	w.PushSample(1.0, -1.0)
+	w.PushSample(0.0, 2.0) // Clamped
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(f.data) != 44+8 {
+		t.Fatalf("Expected 52 bytes, got %d", len(f.data))
+	}
+	if !bytes.Equal(f.data[0:4], []byte("RIFF")) || !bytes.Equal(f.data[8:12], []byte("WAVE")) {
+		t.Errorf("Expected RIFF/WAVE header, got %q", f.data[:12])
+	}
+
+	le := binary.LittleEndian
+	if size := le.Uint32(f.data[4:]); size != 44 {
+		t.Errorf("Expected RIFF size 44, got %d", size)
+	}
+	if rate := le.Uint32(f.data[24:]); rate != 22050 {
+		t.Errorf("Expected sample rate 22050, got %d", rate)
+	}
+	if channels := le.Uint16(f.data[22:]); channels != 2 {
+		t.Errorf("Expected 2 channels, got %d", channels)
+	}
+	if size := le.Uint32(f.data[40:]); size != 8 {
+		t.Errorf("Expected data size 8, got %d", size)
+	}
+
+	samples := []int16{32767, -32767, 0, 32767}
+	for i, want := range samples {
+		if got := int16(le.Uint16(f.data[44+i*2:])); got != want {
+			t.Errorf("Expected sample %d to be %d, got %d", i, want, got)
+		}
+	}
+}
+
+// TestMultiSink tests that samples reach every sink
+func TestMultiSink(t *testing.T) {
+	a := &sampleRecorder{}
+	b := &sampleRecorder{}
+	sink := MultiSink(a, nil, b)
+
+	sink.PushSample(0.5, 0.25)
+
+	if len(a.left) != 1 || len(b.left) != 1 {
+		t.Errorf("Expected both sinks to receive the sample, got %d and %d", len(a.left), len(b.left))
+	}
+}
@@ -0,0 +1,147 @@
+package symbols
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ROMBanker reports the ROM bank the MBC has mapped at 0x4000-0x7FFF
+type ROMBanker interface {
+	CurrentROMBank() int
+}
+
+// Start addresses of the memory regions. A label never covers addresses in
+// a different region from its own.
+var regions = []uint16{0x0000, 0x4000, 0x8000, 0xA000, 0xC000, 0xD000, 0xE000, 0xFE00, 0xFF00, 0xFF80}
+
+func region(addr uint16) int {
+	return sort.Search(len(regions), func(i int) bool { return regions[i] > addr }) - 1
+}
+
+// BankAt returns the ROM bank visible at addr with banker's bank switched
+// in, or -1 outside ROM. A nil banker has bank 1 switched in.
+func BankAt(addr uint16, banker ROMBanker) int {
+	switch {
+	case addr < 0x4000:
+		return 0
+	case addr < 0x8000:
+		if banker == nil {
+			return 1
+		}
+		return banker.CurrentROMBank()
+	default:
+		return -1
+	}
+}
+
+// ROMBank returns the ROM bank the symbol is visible in, see BankAt, or -1
+// outside ROM. Symbols only distinguish ROM banks, there is nothing to tell
+// which RAM bank is mapped.
+func (s Symbol) ROMBank() int {
+	switch {
+	case s.Addr >= 0x8000:
+		return -1
+	case s.Addr >= 0x4000 && s.Bank == 0:
+		// Linking a 32KB ROM without an MBC (rgblink -t) puts 0x4000-0x7FFF
+		// in bank 0, but it is visible as bank 1
+		return 1
+	default:
+		return s.Bank
+	}
+}
+
+// index returns the symbols ordered by bank and address, keeping file order
+// for symbols at the same address
+func (t *Table) index() []Symbol {
+	if t.sorted == nil && len(t.symbols) > 0 {
+		t.sorted = append([]Symbol(nil), t.symbols...)
+		sort.SliceStable(t.sorted, func(i, j int) bool {
+			a, b := t.sorted[i], t.sorted[j]
+			if a.ROMBank() != b.ROMBank() {
+				return a.ROMBank() < b.ROMBank()
+			}
+			return a.Addr < b.Addr
+		})
+	}
+	return t.sorted
+}
+
+// Nearest returns the closest symbol at or before addr in the same memory
+// region, and how far past it addr is. bank is the ROM bank at addr, see
+// BankAt, and is ignored outside ROM.
+func (t *Table) Nearest(bank int, addr uint16) (Symbol, uint16, bool) {
+	if t == nil {
+		return Symbol{}, 0, false
+	}
+	if addr >= 0x8000 {
+		bank = -1
+	}
+
+	sorted := t.index()
+	i := sort.Search(len(sorted), func(i int) bool {
+		key := sorted[i].ROMBank()
+		return key > bank || key == bank && sorted[i].Addr > addr
+	}) - 1
+	if i < 0 || sorted[i].ROMBank() != bank || region(sorted[i].Addr) != region(addr) {
+		return Symbol{}, 0, false
+	}
+
+	// Prefer the first label defined at an address
+	for i > 0 && sorted[i-1].ROMBank() == bank && sorted[i-1].Addr == sorted[i].Addr {
+		i--
+	}
+	return sorted[i], addr - sorted[i].Addr, true
+}
+
+// At returns the symbol defined exactly at addr, see Nearest
+func (t *Table) At(bank int, addr uint16) (Symbol, bool) {
+	s, offset, ok := t.Nearest(bank, addr)
+	return s, ok && offset == 0
+}
+
+// Label names addr as label or label+$offset, or returns "" if no label
+// precedes it. bank is the ROM bank at addr, see BankAt.
+func (t *Table) Label(bank int, addr uint16) string {
+	s, offset, ok := t.Nearest(bank, addr)
+	switch {
+	case !ok:
+		return ""
+	case offset == 0:
+		return s.Name
+	default:
+		return fmt.Sprintf("%s+$%X", s.Name, offset)
+	}
+}
+
+// LabelAt names addr as Label does, in the ROM bank banker has mapped there
+func (t *Table) LabelAt(addr uint16, banker ROMBanker) string {
+	return t.Label(BankAt(addr, banker), addr)
+}
+
+// Resolve looks up a label with an optional offset, label+$offset in hex or
+// label+offset in decimal, and returns the symbol moved by the offset
+func (t *Table) Resolve(expr string) (Symbol, bool) {
+	name, offsetText, hasOffset := strings.Cut(expr, "+")
+	s, ok := t.Lookup(strings.TrimSpace(name))
+	if !ok || !hasOffset {
+		return s, ok
+	}
+
+	offsetText = strings.TrimSpace(offsetText)
+	base := 10
+	if hex, found := strings.CutPrefix(offsetText, "$"); found {
+		offsetText, base = hex, 16
+	} else if hex, found := strings.CutPrefix(strings.ToLower(offsetText), "0x"); found {
+		offsetText, base = hex, 16
+	}
+	offset, err := strconv.ParseUint(offsetText, base, 16)
+	if err != nil {
+		return Symbol{}, false
+	}
+
+	s.Name = expr
+	s.Addr += uint16(offset)
+	return s, true
+}
@@ -0,0 +1,143 @@
+// Package symbols loads RGBDS and no$gmb symbol files, which label ROM and
+// RAM addresses
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Symbol is a labelled address
+type Symbol struct {
+	Name string
+	Bank int
+	Addr uint16
+}
+
+func (s Symbol) String() string {
+	return fmt.Sprintf("%02X:%04X %s", s.Bank, s.Addr, s.Name)
+}
+
+// Table holds the symbols of a ROM. A nil Table has no symbols.
+type Table struct {
+	symbols []Symbol
+	byName  map[string]Symbol
+
+	// Symbols ordered for reverse lookups, built on first use
+	sorted []Symbol
+}
+
+// Load reads a symbol file
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return table, nil
+}
+
+// LoadForROM reads the symbol file next to a ROM, game.sym for game.gb, as
+// RGBDS and other assemblers name it. It returns nil if there is no symbol
+// file.
+func LoadForROM(romPath string) (*Table, error) {
+	path := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sym"
+	table, err := Load(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return table, err
+}
+
+// Parse reads symbols in the "bank:addr label" format, one per line, with
+// both numbers in hexadecimal. Blank lines and ; comments are skipped.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{byName: make(map[string]Symbol)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected bank:addr label", line)
+		}
+
+		bankText, addrText, ok := strings.Cut(fields[0], ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected bank:addr, got %q", line, fields[0])
+		}
+		bank, err := strconv.ParseUint(bankText, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid bank %q", line, bankText)
+		}
+		addr, err := strconv.ParseUint(addrText, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", line, addrText)
+		}
+
+		t.Add(Symbol{Name: fields[1], Bank: int(bank), Addr: uint16(addr)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Index now so lookups from several goroutines only read the table
+	t.index()
+	return t, nil
+}
+
+// Add adds a symbol, replacing any symbol with the same name
+func (t *Table) Add(s Symbol) {
+	if t.byName == nil {
+		t.byName = make(map[string]Symbol)
+	}
+	if _, exists := t.byName[s.Name]; exists {
+		for i := range t.symbols {
+			if t.symbols[i].Name == s.Name {
+				t.symbols[i] = s
+			}
+		}
+	} else {
+		t.symbols = append(t.symbols, s)
+	}
+	t.byName[s.Name] = s
+	t.sorted = nil
+}
+
+// Lookup returns the symbol with the given name
+func (t *Table) Lookup(name string) (Symbol, bool) {
+	if t == nil {
+		return Symbol{}, false
+	}
+	s, ok := t.byName[name]
+	return s, ok
+}
+
+// Symbols returns all symbols in file order
+func (t *Table) Symbols() []Symbol {
+	if t == nil {
+		return nil
+	}
+	return t.symbols
+}
+
+// Len returns the number of symbols
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.symbols)
+}
@@ -0,0 +1,144 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParse tests parsing a symbol file
+func TestParse(t *testing.T) {
+	input := `; File generated by rgblink
+00:0150 Main
+00:0158 Main.loop
+01:4000 Bank1Routine
+
+00:C000 wPlayerX ; comment
+`
+	table, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if table.Len() != 4 {
+		t.Errorf("Expected 4 symbols, got %d", table.Len())
+	}
+	expected := Symbol{Name: "Bank1Routine", Bank: 1, Addr: 0x4000}
+	if s, ok := table.Lookup("Bank1Routine"); !ok || s != expected {
+		t.Errorf("Expected %v, got %v %v", expected, s, ok)
+	}
+	if s, ok := table.Lookup("Main.loop"); !ok || s.Addr != 0x0158 {
+		t.Errorf("Expected Main.loop at 0x0158, got %v %v", s, ok)
+	}
+	if _, ok := table.Lookup("Missing"); ok {
+		t.Error("Expected no symbol for an unknown name")
+	}
+}
+
+// TestParseErrors tests that malformed lines are reported
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"0150 Main",
+		"00:XYZ Main",
+		"00:0150",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+// TestNearest tests naming addresses by the closest preceding label
+func TestNearest(t *testing.T) {
+	input := `00:0150 Main
+00:0158 Main.loop
+00:0158 Main.alias
+01:4000 Bank1Routine
+02:4000 Bank2Routine
+02:4010 Bank2Routine.end
+00:C000 wPlayerX
+00:FF80 hTemp
+`
+	table, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	for _, test := range []struct {
+		bank     int
+		addr     uint16
+		expected string
+	}{
+		{0, 0x0150, "Main"},
+		{0, 0x0153, "Main+$3"},
+		{0, 0x0158, "Main.loop"},
+		{0, 0x0100, ""},
+		{1, 0x4005, "Bank1Routine+$5"},
+		{2, 0x4005, "Bank2Routine+$5"},
+		{2, 0x4012, "Bank2Routine.end+$2"},
+		{3, 0x4005, ""},
+		{-1, 0xC123, "wPlayerX+$123"},
+		// Labels do not extend into the next memory region
+		{0, 0x4000, ""},
+		{-1, 0xE000, ""},
+		{-1, 0xFF80, "hTemp"},
+	} {
+		if label := table.Label(test.bank, test.addr); label != test.expected {
+			t.Errorf("Expected %02X:%04X to be %q, got %q", test.bank, test.addr, test.expected, label)
+		}
+	}
+
+	if label := table.LabelAt(0x4010, MockBanker(2)); label != "Bank2Routine.end" {
+		t.Errorf("Expected Bank2Routine.end with bank 2 mapped, got %q", label)
+	}
+	if label := (*Table)(nil).LabelAt(0x0150, nil); label != "" {
+		t.Errorf("Expected no label without symbols, got %q", label)
+	}
+}
+
+// TestResolve tests looking up labels with offsets
+func TestResolve(t *testing.T) {
+	table, _ := Parse(strings.NewReader("00:0150 Main\n"))
+
+	for expr, addr := range map[string]uint16{
+		"Main":      0x0150,
+		"Main+$10":  0x0160,
+		"Main+0x10": 0x0160,
+		"Main+10":   0x015A,
+	} {
+		if s, ok := table.Resolve(expr); !ok || s.Addr != addr {
+			t.Errorf("Expected %s at 0x%04X, got %v %v", expr, addr, s, ok)
+		}
+	}
+	for _, expr := range []string{"Missing", "Main+", "Main+$zz"} {
+		if _, ok := table.Resolve(expr); ok {
+			t.Errorf("Expected %q not to resolve", expr)
+		}
+	}
+}
+
+// TestLoadForROM tests finding the symbol file next to a ROM
+func TestLoadForROM(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "game.sym"), []byte("00:0150 Main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := LoadForROM(filepath.Join(dir, "game.gb"))
+	if err != nil || table.Len() != 1 {
+		t.Errorf("Expected 1 symbol from game.sym, got %d %v", table.Len(), err)
+	}
+
+	table, err = LoadForROM(filepath.Join(dir, "other.gb"))
+	if err != nil || table != nil {
+		t.Errorf("Expected no table without a symbol file, got %v %v", table, err)
+	}
+}
+
+// MockBanker has a fixed ROM bank mapped
+type MockBanker int
+
+func (b MockBanker) CurrentROMBank() int {
+	return int(b)
+}
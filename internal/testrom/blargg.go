@@ -0,0 +1,73 @@
+package testrom
+
+import (
+	"strings"
+	"time"
+)
+
+// Blargg's test ROMs print their results as text over the serial port and
+// finish with "Passed" or "Failed"
+const (
+	blarggPassed = "Passed"
+	blarggFailed = "Failed"
+)
+
+// Frames to keep running after a result so the details that follow it,
+// like the number of failed tests, are captured too
+const blarggSettleFrames = 30
+
+// DefaultBlarggTimeout is enough emulated time for the slowest single test
+// ROMs; the combined cpu_instrs ROM needs about a minute
+const DefaultBlarggTimeout = 2 * time.Minute
+
+// BlarggResult is the outcome of running a Blargg test ROM
+type BlarggResult struct {
+	Status Status
+
+	// Text the ROM printed over the serial port
+	Output string
+
+	// Emulated frames until the result was reported
+	Frames int
+}
+
+// RunBlargg runs a Blargg test ROM until it prints a result or the timeout,
+// measured in emulated time, runs out
+func RunBlargg(romPath string, timeout time.Duration) (*BlarggResult, error) {
+	gb, cleanup, err := newCore(romPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	capture := &SerialCapture{}
+	gb.Serial.SetPeer(capture)
+
+	result := &BlarggResult{}
+	maxFrames := int(timeout.Seconds() * framesPerSecond)
+	for result.Frames < maxFrames {
+		if err := gb.Step(); err != nil {
+			return nil, err
+		}
+		result.Frames++
+
+		output := capture.String()
+		if strings.Contains(output, blarggPassed) {
+			result.Status = StatusPassed
+			break
+		}
+		if strings.Contains(output, blarggFailed) {
+			result.Status = StatusFailed
+			break
+		}
+	}
+
+	for i := 0; i < blarggSettleFrames && result.Status != StatusTimeout; i++ {
+		if err := gb.Step(); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Output = capture.String()
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package testrom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test ROMs are not distributed with the repository, drop them in here to
+// have them run as part of go test
+var testdataDir = filepath.Join("..", "..", "test", "testdata")
+
+// printProgram prints the zero terminated string at 0x0150 over the serial
+// port and then loops forever
+var printProgram = []byte{
+	0x21, 0x50, 0x01, // LD HL,$0150
+	0x2A,       // loop: LD A,(HL+)
+	0xB7,       // OR A
+	0x28, 0x0E, // JR Z,done
+	0xE0, 0x01, // LDH ($01),A
+	0x3E, 0x81, // LD A,$81
+	0xE0, 0x02, // LDH ($02),A
+	0xF0, 0x02, // wait: LDH A,($02)
+	0xCB, 0x7F, // BIT 7,A
+	0x20, 0xFA, // JR NZ,wait
+	0x18, 0xEE, // JR loop
+	0x18, 0xFE, // done: JR done
+}
+
+// writeROM writes a 32KB ROM-only cartridge running program with message at
+// 0x0150
+func writeROM(t *testing.T, program []byte, message string) string {
+	t.Helper()
+
+	rom := make([]byte, 32*1024)
+	copy(rom[0x100:], program)
+	copy(rom[0x134:0x143], "TESTROM")
+	copy(rom[0x150:], message)
+
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatalf("Failed to write test ROM: %v", err)
+	}
+	return path
+}
+
+// findROMs returns the ROMs in test/testdata matching the glob patterns,
+// skipping the test if there are none
+func findROMs(t *testing.T, patterns ...string) []string {
+	t.Helper()
+
+	var roms []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(testdataDir, pattern))
+		roms = append(roms, matches...)
+	}
+
+	if len(roms) == 0 {
+		t.Skipf("No test ROMs found in %s, see the README for how to download them", testdataDir)
+	}
+	return roms
+}
+
+// TestSerialCapture tests that bytes sent by the Game Boy are recorded
+func TestSerialCapture(t *testing.T) {
+	capture := &SerialCapture{}
+
+	for _, b := range []byte("Hi") {
+		if reply := capture.Exchange(b); reply != 0xFF {
+			t.Errorf("Expected reply 0xFF, got 0x%02X", reply)
+		}
+	}
+
+	if capture.String() != "Hi" {
+		t.Errorf("Expected output %q, got %q", "Hi", capture.String())
+	}
+}
+
+// TestRunBlargg tests the harness with ROMs that report each result
+func TestRunBlargg(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		status  Status
+	}{
+		{"passed", "cpu_test\n\nPassed\n", StatusPassed},
+		{"failed", "cpu_test\n\nFailed #2\n", StatusFailed},
+		{"timeout", "cpu_test\n", StatusTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunBlargg(writeROM(t, printProgram, tt.message), time.Second)
+			if err != nil {
+				t.Fatalf("RunBlargg failed: %v", err)
+			}
+
+			if result.Status != tt.status {
+				t.Errorf("Expected status %v, got %v", tt.status, result.Status)
+			}
+			if result.Output != tt.message {
+				t.Errorf("Expected output %q, got %q", tt.message, result.Output)
+			}
+		})
+	}
+}
+
+// Blargg test suites that report over the serial port, laid out as in
+// https://github.com/retrio/gb-test-roms
+var blarggROMs = []string{
+	"gb-test-roms/cpu_instrs/individual/*.gb",
+	"gb-test-roms/instr_timing/*.gb",
+	"gb-test-roms/mem_timing/individual/*.gb",
+}
+
+// TestBlarggROMs runs the Blargg test ROMs found in test/testdata
+func TestBlarggROMs(t *testing.T) {
+	for _, rom := range findROMs(t, blarggROMs...) {
+		t.Run(filepath.Base(rom), func(t *testing.T) {
+			t.Parallel()
+
+			result, err := RunBlargg(rom, DefaultBlarggTimeout)
+			if err != nil {
+				t.Fatalf("Failed to run %s: %v", rom, err)
+			}
+			if result.Status != StatusPassed {
+				t.Errorf("Test ROM %s after %d frames:\n%s", result.Status, result.Frames, result.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package testrom
+
+import (
+	"time"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+	"github.com/briancain/gameboy-go/internal/timer"
+)
+
+// Mooneye test ROMs execute LD B,B as a software breakpoint when they finish
+const mooneyeBreakpoint = 0x40
+
+// Register values in B, C, D, E, H and L when a Mooneye test passes. Failing
+// tests set them all to 0x42.
+var mooneyePassSignature = [6]byte{3, 5, 8, 13, 21, 34}
+
+// DefaultMooneyeTimeout is enough emulated time for any acceptance test
+const DefaultMooneyeTimeout = 20 * time.Second
+
+// MooneyeResult is the outcome of running a Mooneye test ROM
+type MooneyeResult struct {
+	Status Status
+
+	// CPU registers at the breakpoint, or when the timeout ran out
+	Registers cpu.Registers
+
+	// Emulated cycles until the breakpoint
+	Cycles int
+}
+
+// RunMooneye runs a Mooneye test ROM until it reaches the LD B,B breakpoint
+// or the timeout, measured in emulated time, runs out. A test that stops
+// with anything other than the pass signature in its registers has failed.
+func RunMooneye(romPath string, timeout time.Duration) (*MooneyeResult, error) {
+	gb, cleanup, err := newCore(romPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result := &MooneyeResult{}
+	maxCycles := int(timeout.Seconds() * timer.CPU_CLOCK)
+	for result.Cycles < maxCycles {
+		regs := gb.Cpu.GetRegisters()
+		if gb.Mmu.ReadByte(regs.PC) == mooneyeBreakpoint {
+			result.Registers = regs
+			if mooneyeSignature(regs) == mooneyePassSignature {
+				result.Status = StatusPassed
+			} else {
+				result.Status = StatusFailed
+			}
+			return result, nil
+		}
+
+		cycles, err := gb.StepInstruction()
+		if err != nil {
+			return nil, err
+		}
+		result.Cycles += cycles
+	}
+
+	result.Registers = gb.Cpu.GetRegisters()
+	return result, nil
+}
+
+// mooneyeSignature returns the registers Mooneye tests report results in
+func mooneyeSignature(regs cpu.Registers) [6]byte {
+	return [6]byte{regs.B, regs.C, regs.D, regs.E, regs.H, regs.L}
+}
@@ -0,0 +1,124 @@
+package testrom
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Mooneye acceptance tests, from a mooneye-test-suite release extracted to
+// test/testdata/mooneye
+var mooneyeROMs = []string{
+	"mooneye/acceptance/*.gb",
+	"mooneye/acceptance/*/*.gb",
+}
+
+// runsOnDMG reports whether a Mooneye test targets the original Game Boy.
+// Tests for specific models have a suffix naming them, e.g. boot_regs-dmgABC
+// or di_timing-GS, where G is the DMG and MGB family.
+func runsOnDMG(romPath string) bool {
+	name := strings.TrimSuffix(filepath.Base(romPath), filepath.Ext(romPath))
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return true
+	}
+
+	models := name[i+1:]
+	if strings.Contains(models, "dmgABC") {
+		return true
+	}
+	return strings.ToUpper(models) == models && strings.Contains(models, "G")
+}
+
+// TestRunMooneye tests the harness with ROMs that stop at the breakpoint
+func TestRunMooneye(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		status  Status
+	}{
+		{
+			"passed",
+			[]byte{
+				0x06, 3, 0x0E, 5, 0x16, 8, 0x1E, 13, 0x26, 21, 0x2E, 34, // LD B..L with the pass signature
+				0x40,       // LD B,B
+				0x18, 0xFE, // JR -2
+			},
+			StatusPassed,
+		},
+		{
+			"failed",
+			[]byte{
+				0x06, 0x42, 0x0E, 0x42, 0x16, 0x42, 0x1E, 0x42, 0x26, 0x42, 0x2E, 0x42,
+				0x40,       // LD B,B
+				0x18, 0xFE, // JR -2
+			},
+			StatusFailed,
+		},
+		{
+			"timeout",
+			[]byte{0x18, 0xFE}, // JR -2
+			StatusTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunMooneye(writeROM(t, tt.program, ""), time.Second/10)
+			if err != nil {
+				t.Fatalf("RunMooneye failed: %v", err)
+			}
+
+			if result.Status != tt.status {
+				t.Errorf("Expected status %v, got %v", tt.status, result.Status)
+			}
+		})
+	}
+}
+
+// TestRunsOnDMG tests the model suffix filter
+func TestRunsOnDMG(t *testing.T) {
+	tests := map[string]bool{
+		"acceptance/ei_timing.gb":                        true,
+		"acceptance/boot_regs-dmgABC.gb":                 true,
+		"acceptance/boot_hwio-dmgABCmgb.gb":              true,
+		"acceptance/di_timing-GS.gb":                     true,
+		"acceptance/boot_regs-dmg0.gb":                   false,
+		"acceptance/boot_div-S.gb":                       false,
+		"acceptance/boot_regs-mgb.gb":                    false,
+		"acceptance/boot_hwio-C.gb":                      false,
+		"acceptance/timer/rapid_toggle.gb":               true,
+		"acceptance/serial/boot_sclk_align-dmgABCmgb.gb": true,
+	}
+
+	for rom, expected := range tests {
+		if got := runsOnDMG(rom); got != expected {
+			t.Errorf("Expected runsOnDMG(%q) to be %v, got %v", rom, expected, got)
+		}
+	}
+}
+
+// TestMooneyeROMs runs the Mooneye acceptance tests found in test/testdata
+func TestMooneyeROMs(t *testing.T) {
+	for _, rom := range findROMs(t, mooneyeROMs...) {
+		if !runsOnDMG(rom) {
+			continue
+		}
+
+		name, _ := filepath.Rel(filepath.Join(testdataDir, "mooneye"), rom)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			t.Parallel()
+
+			result, err := RunMooneye(rom, DefaultMooneyeTimeout)
+			if err != nil {
+				t.Fatalf("Failed to run %s: %v", rom, err)
+			}
+			if result.Status != StatusPassed {
+				regs := result.Registers
+				t.Errorf("Test ROM %s at PC=0x%04X: B=%d C=%d D=%d E=%d H=%d L=%d",
+					result.Status, regs.PC, regs.B, regs.C, regs.D, regs.E, regs.H, regs.L)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package testrom runs hardware test ROMs headlessly and reports whether
+// they passed
+package testrom
+
+import (
+	"os"
+
+	"github.com/briancain/gameboy-go/internal/core"
+	"github.com/briancain/gameboy-go/internal/serial"
+)
+
+// Emulated frames per second, used to convert timeouts to frame counts
+const framesPerSecond = 60
+
+// Status is the outcome of a test ROM run
+type Status int
+
+const (
+	StatusTimeout Status = iota // The ROM never reported a result
+	StatusPassed
+	StatusFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPassed:
+		return "passed"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "timed out"
+	}
+}
+
+// SerialCapture is a serial.SerialPeer that records every byte the Game Boy
+// sends over the link port, like a terminal on the other end of the cable
+type SerialCapture struct {
+	data []byte
+}
+
+// Exchange records the byte and replies as if nothing were connected
+func (c *SerialCapture) Exchange(out byte) byte {
+	c.data = append(c.data, out)
+	return 0xFF
+}
+
+// Poll does nothing, the capture never drives the clock
+func (c *SerialCapture) Poll(s *serial.Serial) {}
+
+// Bytes returns everything received so far
+func (c *SerialCapture) Bytes() []byte {
+	return c.data
+}
+
+// String returns everything received so far as text
+func (c *SerialCapture) String() string {
+	return string(c.data)
+}
+
+// newCore loads a ROM into an emulator without a display. Battery saves go
+// to a temporary directory so test runs never touch real save files.
+func newCore(romPath string) (*core.GameBoyCore, func(), error) {
+	saveDir, err := os.MkdirTemp("", "gameboy-go-testrom")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(saveDir) }
+
+	gb, err := core.NewGameBoyCore(false)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	gb.SetSaveDirectory(saveDir)
+
+	if err := gb.Init(romPath); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return gb, cleanup, nil
+}
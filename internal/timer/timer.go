@@ -1,5 +1,7 @@
 package timer
 
+import "github.com/briancain/gameboy-go/internal/snapshot"
+
 // Timer handles the Game Boy's timing system
 // According to the Game Boy CPU manual section 2.10:
 // - DIV register increments at 16384Hz
@@ -71,6 +73,12 @@ func (t *Timer) Reset() {
 	t.prevTimerOn = false
 }
 
+// SetDivider sets DIV, for starting in the state the boot ROM leaves it in.
+// Writing FF04 can only reset it.
+func (t *Timer) SetDivider(value byte) {
+	t.div = value
+}
+
 // Step advances the timer by the specified number of cycles
 func (t *Timer) Step(cycles int) {
 	// Update DIV register (increments at 16384Hz)
@@ -168,3 +176,27 @@ func (t *Timer) WriteRegister(addr uint16, value byte) {
 		}
 	}
 }
+
+// SaveState writes the timer registers and internal counters
+func (t *Timer) SaveState(w *snapshot.StateWriter) {
+	w.Section("TIMR")
+	w.Uint8(t.div)
+	w.Uint8(t.tima)
+	w.Uint8(t.tma)
+	w.Uint8(t.tac)
+	w.Int(t.divCounter)
+	w.Int(t.timaCounter)
+	w.Bool(t.prevTimerOn)
+}
+
+// LoadState restores the timer state written by SaveState
+func (t *Timer) LoadState(r *snapshot.StateReader) {
+	r.Section("TIMR")
+	t.div = r.Uint8()
+	t.tima = r.Uint8()
+	t.tma = r.Uint8()
+	t.tac = r.Uint8()
+	t.divCounter = r.Int()
+	t.timaCounter = r.Int()
+	t.prevTimerOn = r.Bool()
+}
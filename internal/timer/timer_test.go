@@ -129,6 +129,13 @@ func TestTimerDIV(t *testing.T) {
 	if timer.divCounter != 0 {
 		t.Errorf("Expected divCounter to be reset to 0, got %d", timer.divCounter)
 	}
+
+	// Setting DIV directly keeps counting from the value
+	timer.SetDivider(0xAB)
+	timer.Step(256)
+	if timer.ReadRegister(0xFF04) != 0xAC {
+		t.Errorf("Expected DIV to count up from AB to AC, got %02X", timer.ReadRegister(0xFF04))
+	}
 }
 
 // TestTimerTIMA tests the TIMA register
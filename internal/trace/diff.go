@@ -0,0 +1,412 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// DefaultContext is the number of lines shown before and after a divergence
+const DefaultContext = 5
+
+// Flags in the F register, most significant bit first
+var flagNames = []struct {
+	name string
+	mask byte
+}{
+	{"Z", cpu.FLAG_Z},
+	{"N", cpu.FLAG_N},
+	{"H", cpu.FLAG_H},
+	{"C", cpu.FLAG_C},
+}
+
+// FieldDiff is a field of a trace line with different values
+type FieldDiff struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (f FieldDiff) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", f.Name, f.Expected, f.Actual)
+}
+
+// Divergence describes the first line where a trace differs from the
+// reference
+type Divergence struct {
+	// Line number, starting at 1
+	Line int
+
+	// Label of the diverging instruction, if known
+	Label string
+
+	// The differing lines. An empty line means that trace ended first.
+	Expected string
+	Actual   string
+
+	// Fields that differ, empty if either trace ended
+	Fields []FieldDiff
+
+	// Matching lines before the divergence
+	Before []string
+
+	// Lines following the divergence in each trace
+	ExpectedAfter []string
+	ActualAfter   []string
+}
+
+// String formats the divergence as a report
+func (d *Divergence) String() string {
+	var b strings.Builder
+
+	if d.Label != "" {
+		fmt.Fprintf(&b, "First divergence at line %d in %s\n\n", d.Line, d.Label)
+	} else {
+		fmt.Fprintf(&b, "First divergence at line %d\n\n", d.Line)
+	}
+	for i, line := range d.Before {
+		fmt.Fprintf(&b, "  %8d  %s\n", d.Line-len(d.Before)+i, line)
+	}
+	fmt.Fprintf(&b, "- %8d  %s\n", d.Line, orEnd(d.Expected))
+	fmt.Fprintf(&b, "+ %8d  %s\n", d.Line, orEnd(d.Actual))
+
+	for i := 0; i < max(len(d.ExpectedAfter), len(d.ActualAfter)); i++ {
+		if i < len(d.ExpectedAfter) {
+			fmt.Fprintf(&b, "- %8d  %s\n", d.Line+i+1, d.ExpectedAfter[i])
+		}
+		if i < len(d.ActualAfter) {
+			fmt.Fprintf(&b, "+ %8d  %s\n", d.Line+i+1, d.ActualAfter[i])
+		}
+	}
+
+	if len(d.Fields) > 0 {
+		b.WriteString("\n")
+		for _, field := range d.Fields {
+			fmt.Fprintf(&b, "%s\n", field)
+		}
+	}
+
+	return b.String()
+}
+
+func orEnd(line string) string {
+	if line == "" {
+		return "(end of trace)"
+	}
+	return line
+}
+
+// DiffLine returns the fields that differ between two trace lines. A
+// difference in F is broken down into the individual flags.
+func DiffLine(expected, actual string) []FieldDiff {
+	expectedFields := parseLine(expected)
+	actualFields := parseLine(actual)
+
+	var diffs []FieldDiff
+	for _, field := range expectedFields {
+		actualValue := lookup(actualFields, field.name)
+		if actualValue == field.value {
+			continue
+		}
+		diffs = append(diffs, FieldDiff{Name: field.name, Expected: field.value, Actual: actualValue})
+
+		if field.name == "F" {
+			diffs = append(diffs, diffFlags(field.value, actualValue)...)
+		}
+	}
+
+	// Fields only present in the actual line
+	for _, field := range actualFields {
+		if lookup(expectedFields, field.name) == "" {
+			diffs = append(diffs, FieldDiff{Name: field.name, Actual: field.value})
+		}
+	}
+
+	return diffs
+}
+
+// diffFlags compares the flags in two hex F register values
+func diffFlags(expected, actual string) []FieldDiff {
+	e, err1 := strconv.ParseUint(expected, 16, 8)
+	a, err2 := strconv.ParseUint(actual, 16, 8)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+
+	var diffs []FieldDiff
+	for _, flag := range flagNames {
+		eSet := byte(e)&flag.mask != 0
+		aSet := byte(a)&flag.mask != 0
+		if eSet != aSet {
+			diffs = append(diffs, FieldDiff{
+				Name:     "flag " + flag.name,
+				Expected: flagState(eSet),
+				Actual:   flagState(aSet),
+			})
+		}
+	}
+	return diffs
+}
+
+func flagState(set bool) string {
+	if set {
+		return "set"
+	}
+	return "clear"
+}
+
+type lineField struct {
+	name  string
+	value string
+}
+
+// splitComment separates a trace line from a trailing ; comment, see
+// DoctorWriter.SetLabels
+func splitComment(line string) (string, string) {
+	fields, comment, _ := strings.Cut(line, ";")
+	return strings.TrimSpace(fields), strings.TrimSpace(comment)
+}
+
+// parseLine splits a trace line into its NAME:VALUE fields
+func parseLine(line string) []lineField {
+	var fields []lineField
+	line, _ = splitComment(line)
+	for _, token := range strings.Fields(line) {
+		name, value, _ := strings.Cut(token, ":")
+		fields = append(fields, lineField{name, value})
+	}
+	return fields
+}
+
+func lookup(fields []lineField, name string) string {
+	for _, field := range fields {
+		if field.name == name {
+			return field.value
+		}
+	}
+	return ""
+}
+
+// Comparer checks a trace line by line against a reference trace as it is
+// produced, keeping only the context lines in memory
+type Comparer struct {
+	reference *bufio.Scanner
+	context   int
+
+	// A trace running past the end of the reference is a divergence
+	strict bool
+
+	line   int
+	before []string
+
+	// Names the PC of a diverging line without a label comment
+	labels func(addr uint16) string
+
+	divergence     *Divergence
+	afterWanted    int
+	referenceEnded bool
+	err            error
+}
+
+// NewComparer creates a comparer against the reference trace, keeping
+// context lines before and after the divergence. A trace may continue past
+// the end of the reference, which makes it possible to check only the start
+// of a long run.
+func NewComparer(reference io.Reader, context int) *Comparer {
+	scanner := bufio.NewScanner(reference)
+	scanner.Buffer(nil, 1024*1024)
+
+	return &Comparer{
+		reference: scanner,
+		context:   context,
+	}
+}
+
+// SetLabels names the diverging instruction with labels when the lines do
+// not carry a label comment
+func (c *Comparer) SetLabels(labels func(addr uint16) string) {
+	c.labels = labels
+}
+
+// Add compares the next line of the trace. It returns false once the
+// comparison is finished: the context after a divergence has been collected,
+// the reference ended or reading it failed.
+func (c *Comparer) Add(actual string) bool {
+	if c.Done() {
+		return false
+	}
+
+	// Collecting the lines after the divergence
+	if d := c.divergence; d != nil {
+		d.ActualAfter = append(d.ActualAfter, actual)
+		return !c.Done()
+	}
+
+	expected, ok := c.next()
+	if !ok {
+		if c.err != nil {
+			return false
+		}
+		if !c.strict {
+			c.referenceEnded = true
+			return false
+		}
+	}
+	c.line++
+
+	expectedFields, _ := splitComment(expected)
+	actualFields, _ := splitComment(actual)
+	if expectedFields == actualFields {
+		c.before = append(c.before, actual)
+		if len(c.before) > c.context {
+			c.before = c.before[1:]
+		}
+		return true
+	}
+
+	c.diverge(expected, actual)
+	return !c.Done()
+}
+
+// Finish is called when the trace ends. If the reference continues, that is
+// reported as a divergence.
+func (c *Comparer) Finish() {
+	if c.divergence != nil || c.referenceEnded || c.err != nil {
+		return
+	}
+
+	if expected, ok := c.next(); ok {
+		c.line++
+		c.diverge(expected, "")
+	}
+}
+
+// diverge records the divergence and reads ahead the reference context
+func (c *Comparer) diverge(expected, actual string) {
+	d := &Divergence{
+		Line:     c.line,
+		Expected: expected,
+		Actual:   actual,
+		Before:   c.before,
+	}
+	if expected != "" && actual != "" {
+		d.Fields = DiffLine(expected, actual)
+	}
+	d.Label = c.label(actual, expected)
+
+	if expected != "" {
+		for len(d.ExpectedAfter) < c.context {
+			line, ok := c.next()
+			if !ok {
+				break
+			}
+			d.ExpectedAfter = append(d.ExpectedAfter, line)
+		}
+	}
+
+	// Once the trace has ended there is nothing after it to collect
+	if actual != "" {
+		c.afterWanted = c.context
+	}
+	c.divergence = d
+}
+
+// label names the instruction of the first line that has one, from its
+// label comment or by its PC
+func (c *Comparer) label(lines ...string) string {
+	for _, line := range lines {
+		if _, comment := splitComment(line); comment != "" {
+			return comment
+		}
+	}
+	if c.labels == nil {
+		return ""
+	}
+	for _, line := range lines {
+		if pc, err := strconv.ParseUint(lookup(parseLine(line), "PC"), 16, 16); err == nil {
+			return c.labels(uint16(pc))
+		}
+	}
+	return ""
+}
+
+// next reads the next reference line
+func (c *Comparer) next() (string, bool) {
+	if c.reference.Scan() {
+		return strings.TrimRight(c.reference.Text(), "\r"), true
+	}
+	c.err = c.reference.Err()
+	return "", false
+}
+
+// Done reports whether the comparison is finished
+func (c *Comparer) Done() bool {
+	if c.err != nil || c.referenceEnded {
+		return true
+	}
+	return c.divergence != nil && len(c.divergence.ActualAfter) >= c.afterWanted
+}
+
+// Lines returns the number of lines compared
+func (c *Comparer) Lines() int {
+	return c.line
+}
+
+// ReferenceEnded reports whether the trace matched every line of the
+// reference
+func (c *Comparer) ReferenceEnded() bool {
+	return c.referenceEnded
+}
+
+// Result returns the first divergence, or nil if none was found
+func (c *Comparer) Result() (*Divergence, error) {
+	return c.divergence, c.err
+}
+
+// Diff compares two traces line by line and returns the first divergence,
+// or nil if they are identical, along with the number of lines compared
+func Diff(actual, reference io.Reader, context int) (*Divergence, int, error) {
+	c := NewComparer(reference, context)
+	c.strict = true
+
+	scanner := bufio.NewScanner(actual)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if !c.Add(strings.TrimRight(scanner.Text(), "\r")) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, c.Lines(), err
+	}
+
+	c.Finish()
+	d, err := c.Result()
+	return d, c.Lines(), err
+}
+
+// LiveDiff is a cpu.InstructionObserver that compares execution against a
+// reference trace as it runs, without writing the trace to disk
+type LiveDiff struct {
+	*Comparer
+	mem Memory
+}
+
+// NewLiveDiff creates an observer comparing against the reference trace
+func NewLiveDiff(reference io.Reader, mem Memory, context int) *LiveDiff {
+	return &LiveDiff{
+		Comparer: NewComparer(reference, context),
+		mem:      mem,
+	}
+}
+
+// BeforeInstruction compares the CPU state with the next reference line
+func (l *LiveDiff) BeforeInstruction(c *cpu.Z80) {
+	if !l.Done() {
+		l.Add(DoctorLine(c, l.mem))
+	}
+}
@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+const (
+	line1 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02"
+	line2 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:C3,13,02,00"
+	line3 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0213 PCMEM:3E,00,00,00"
+	line4 = "A:00 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0215 PCMEM:00,00,00,00"
+)
+
+func lines(l ...string) string {
+	return strings.Join(l, "\n") + "\n"
+}
+
+// TestDiffIdentical tests that identical traces have no divergence
+func TestDiffIdentical(t *testing.T) {
+	trace := lines(line1, line2, line3)
+	d, _, err := Diff(strings.NewReader(trace), strings.NewReader(trace), DefaultContext)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d != nil {
+		t.Errorf("Expected no divergence, got:\n%s", d)
+	}
+}
+
+// TestDiffDivergence tests the first differing line and its context
+func TestDiffDivergence(t *testing.T) {
+	bad := strings.Replace(line3, "F:B0", "F:90", 1)
+	actual := lines(line1, line2, bad, line4)
+	reference := lines(line1, line2, line3, line4)
+
+	d, _, err := Diff(strings.NewReader(actual), strings.NewReader(reference), 1)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d == nil {
+		t.Fatal("Expected a divergence")
+	}
+
+	if d.Line != 3 {
+		t.Errorf("Expected divergence at line 3, got %d", d.Line)
+	}
+	if len(d.Before) != 1 || d.Before[0] != line2 {
+		t.Errorf("Expected the line before as context, got %q", d.Before)
+	}
+	if len(d.ExpectedAfter) != 1 || len(d.ActualAfter) != 1 {
+		t.Errorf("Expected one line after in each trace, got %d and %d", len(d.ExpectedAfter), len(d.ActualAfter))
+	}
+
+	expected := []FieldDiff{
+		{Name: "F", Expected: "B0", Actual: "90"},
+		{Name: "flag H", Expected: "set", Actual: "clear"},
+	}
+	if len(d.Fields) != len(expected) {
+		t.Fatalf("Expected fields %v, got %v", expected, d.Fields)
+	}
+	for i := range expected {
+		if d.Fields[i] != expected[i] {
+			t.Errorf("Expected field %v, got %v", expected[i], d.Fields[i])
+		}
+	}
+}
+
+// TestDiffLength tests traces of different lengths
+func TestDiffLength(t *testing.T) {
+	short := lines(line1, line2)
+	long := lines(line1, line2, line3)
+
+	d, _, _ := Diff(strings.NewReader(short), strings.NewReader(long), DefaultContext)
+	if d == nil || d.Line != 3 || d.Actual != "" {
+		t.Errorf("Expected the trace to end at line 3, got %+v", d)
+	}
+
+	d, _, _ = Diff(strings.NewReader(long), strings.NewReader(short), DefaultContext)
+	if d == nil || d.Line != 3 || d.Expected != "" {
+		t.Errorf("Expected the reference to end at line 3, got %+v", d)
+	}
+}
+
+// TestDiffLabels tests that label comments are ignored when comparing and
+// name the divergence
+func TestDiffLabels(t *testing.T) {
+	bad := strings.Replace(line3, "F:B0", "F:90", 1)
+	actual := lines(line1+" ; Entry", line2+" ; Entry+$1", bad+" ; Start")
+	reference := lines(line1, line2, line3)
+
+	d, _, err := Diff(strings.NewReader(actual), strings.NewReader(reference), 1)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d == nil || d.Line != 3 {
+		t.Fatalf("Expected divergence at line 3, got %+v", d)
+	}
+	if d.Label != "Start" || !strings.Contains(d.String(), "line 3 in Start") {
+		t.Errorf("Expected the divergence in Start, got:\n%s", d)
+	}
+	if len(d.Fields) != 2 || d.Fields[0].Name != "F" {
+		t.Errorf("Expected F to differ, got %v", d.Fields)
+	}
+}
+
+// TestLiveDiff tests comparing a running CPU against a reference trace
+func TestLiveDiff(t *testing.T) {
+	mmu := &MockMMU{}
+	copy(mmu.memory[0x0100:], []byte{0x00, 0xC3, 0x13, 0x02})
+	copy(mmu.memory[0x0213:], []byte{0x3E, 0x00})
+	c, _ := cpu.NewCPU(mmu)
+
+	// Matches the first three instructions, then LD A,$00 loads the wrong value
+	reference := lines(line1, line2, line3, strings.Replace(line4, "A:00", "A:FF", 1))
+	live := NewLiveDiff(strings.NewReader(reference), mmu, DefaultContext)
+	live.SetLabels(func(addr uint16) string { return fmt.Sprintf("L%04X", addr) })
+	c.AddObserver(live)
+
+	for i := 0; i < 10 && !live.Done(); i++ {
+		c.Step()
+	}
+
+	d, err := live.Result()
+	if err != nil {
+		t.Fatalf("LiveDiff failed: %v", err)
+	}
+	if d == nil || d.Line != 4 {
+		t.Fatalf("Expected divergence at line 4, got %+v", d)
+	}
+	if len(d.Fields) != 1 || d.Fields[0].Name != "A" {
+		t.Errorf("Expected A to differ, got %v", d.Fields)
+	}
+	if d.Label != "L0215" {
+		t.Errorf("Expected the divergence to be labelled L0215, got %q", d.Label)
+	}
+}
@@ -0,0 +1,96 @@
+// Package trace writes CPU execution traces for comparing against other
+// emulators
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// Memory is read to log the bytes at PC. Peek must not trigger watchpoints,
+// the bytes after the instruction are not read by it.
+type Memory interface {
+	Peek(addr uint16) byte
+}
+
+// DoctorWriter is a cpu.InstructionObserver that writes one line per
+// instruction in the Gameboy Doctor format
+// (https://github.com/robert/gameboy-doctor):
+//
+//	A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02
+//
+// Gameboy Doctor's reference logs are taken with LY reading 0x90, see
+// mmu.SetLYStub.
+type DoctorWriter struct {
+	w      *bufio.Writer
+	mem    Memory
+	labels func(addr uint16) string
+	lines  uint64
+	err    error
+}
+
+// NewDoctorWriter creates a trace writer to w that reads the bytes at PC
+// from mem
+func NewDoctorWriter(w io.Writer, mem Memory) *DoctorWriter {
+	return &DoctorWriter{
+		w:   bufio.NewWriter(w),
+		mem: mem,
+	}
+}
+
+// SetLabels names PC on each line with labels, in a comment after the
+// Gameboy Doctor fields:
+//
+//	A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0150 PCMEM:F3,31,FE,FF ; Main
+//
+// Gameboy Doctor itself does not accept the comments, the comparisons in
+// this package ignore them.
+func (d *DoctorWriter) SetLabels(labels func(addr uint16) string) {
+	d.labels = labels
+}
+
+// BeforeInstruction logs the CPU state before the instruction at PC
+func (d *DoctorWriter) BeforeInstruction(c *cpu.Z80) {
+	if d.err != nil {
+		return
+	}
+
+	line := DoctorLine(c, d.mem)
+	if d.labels != nil {
+		if label := d.labels(c.GetRegisters().PC); label != "" {
+			line += " ; " + label
+		}
+	}
+	if _, err := fmt.Fprintln(d.w, line); err != nil {
+		d.err = err
+		return
+	}
+	d.lines++
+}
+
+// DoctorLine formats the CPU state before the instruction at PC as a
+// Gameboy Doctor log line, without the newline
+func DoctorLine(c *cpu.Z80, mem Memory) string {
+	reg := c.GetRegisters()
+	pc := reg.PC
+	return fmt.Sprintf(
+		"A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X PCMEM:%02X,%02X,%02X,%02X",
+		reg.A, reg.F, reg.B, reg.C, reg.D, reg.E, reg.H, reg.L, reg.SP, pc,
+		mem.Peek(pc), mem.Peek(pc+1), mem.Peek(pc+2), mem.Peek(pc+3))
+}
+
+// Lines returns the number of instructions logged
+func (d *DoctorWriter) Lines() uint64 {
+	return d.lines
+}
+
+// Flush writes any buffered lines and returns the first error encountered
+func (d *DoctorWriter) Flush() error {
+	if d.err != nil {
+		return d.err
+	}
+	return d.w.Flush()
+}
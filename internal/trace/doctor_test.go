@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/briancain/gameboy-go/internal/cpu"
+)
+
+// TestDoctorWriter tests the Gameboy Doctor line format
+func TestDoctorWriter(t *testing.T) {
+	mmu := &MockMMU{}
+	copy(mmu.memory[0x0100:], []byte{0x00, 0xC3, 0x13, 0x02})
+	c, _ := cpu.NewCPU(mmu)
+
+	var buf bytes.Buffer
+	writer := NewDoctorWriter(&buf, mmu)
+	c.AddObserver(writer)
+
+	c.Step() // NOP
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	// Stops logging once removed
+	c.RemoveObserver(writer)
+	c.Step()
+	writer.Flush()
+	if writer.Lines() != 1 {
+		t.Errorf("Expected 1 line, got %d", writer.Lines())
+	}
+}
+
+// TestDoctorWriterLabels tests naming PC in a comment
+func TestDoctorWriterLabels(t *testing.T) {
+	mmu := &MockMMU{}
+	c, _ := cpu.NewCPU(mmu)
+
+	var buf bytes.Buffer
+	writer := NewDoctorWriter(&buf, mmu)
+	writer.SetLabels(func(addr uint16) string {
+		if addr == 0x0100 {
+			return "Entry"
+		}
+		return ""
+	})
+	c.AddObserver(writer)
+
+	c.Step()
+	c.Step()
+	writer.Flush()
+
+	expected := "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,00,00,00 ; Entry\n" +
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:00,00,00,00\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+// MockMMU is a flat 64KB memory for testing
+type MockMMU struct {
+	memory [0x10000]byte
+}
+
+func (m *MockMMU) ReadByte(addr uint16) byte {
+	return m.memory[addr]
+}
+
+func (m *MockMMU) Peek(addr uint16) byte {
+	return m.memory[addr]
+}
+
+func (m *MockMMU) WriteByte(addr uint16, value byte) {
+	m.memory[addr] = value
+}
+
+func (m *MockMMU) ReadWord(addr uint16) uint16 {
+	return uint16(m.memory[addr]) | (uint16(m.memory[addr+1]) << 8)
+}
+
+func (m *MockMMU) WriteWord(addr uint16, value uint16) {
+	m.memory[addr] = byte(value & 0xFF)
+	m.memory[addr+1] = byte(value >> 8)
+}